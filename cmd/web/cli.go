@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/database"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/service"
+)
+
+// runCommand despacha os subcomandos administrativos:
+//
+//	go run ./cmd/web create-admin -email admin@loja.com -name "Admin"
+//	go run ./cmd/web migrate [-status]
+func runCommand(args []string, store *database.MongoStore, authService *service.AuthService) error {
+	switch args[0] {
+	case "create-admin":
+		return createAdminCommand(args[1:], authService)
+	case "migrate":
+		return migrateCommand(args[1:], store)
+	default:
+		return fmt.Errorf("comando desconhecido: %s", args[0])
+	}
+}
+
+// createAdminCommand cria o primeiro admin ou promove um usuário existente.
+// Se -password não for informado, a senha é lida da entrada padrão.
+func createAdminCommand(args []string, authService *service.AuthService) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "e-mail do admin")
+	name := fs.String("name", "Administrador", "nome exibido")
+	password := fs.String("password", "", "senha (se vazio, lê da entrada padrão)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *email == "" {
+		return errors.New("informe -email")
+	}
+
+	if *password == "" {
+		fmt.Print("Senha: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return err
+		}
+		*password = strings.TrimSpace(line)
+	}
+
+	if err := authService.BootstrapAdmin(context.Background(), *name, *email, *password); err != nil {
+		return err
+	}
+
+	log.Printf("Admin %s pronto", *email)
+	return nil
+}
+
+// migrateCommand aplica as migrações pendentes ou, com -status, só lista
+func migrateCommand(args []string, store *database.MongoStore) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	status := fs.Bool("status", false, "só mostra o que já foi aplicado")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if *status {
+		migrator, err := database.NewMigrator(store.DB, database.Migrations)
+		if err != nil {
+			return err
+		}
+		states, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range states {
+			applied := "pendente"
+			if s.Applied != nil {
+				applied = "aplicada em " + s.Applied.AppliedAt.Local().Format("02/01/2006 15:04")
+			}
+			fmt.Printf("%3d  %-60s  %s\n", s.Version, s.Description, applied)
+		}
+		return nil
+	}
+
+	ran, err := store.Migrate(ctx)
+	for _, m := range ran {
+		log.Printf("Migração %d aplicada: %s", m.Version, m.Description)
+	}
+	if err != nil {
+		return err
+	}
+	if len(ran) == 0 {
+		log.Println("Banco já está na última versão")
+	}
+	return nil
+}
@@ -5,10 +5,11 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/joho/godotenv"
-
+	"github.com/MarcosAndradeV/go-ecommerce/internal/config"
 	"github.com/MarcosAndradeV/go-ecommerce/internal/database"
 	"github.com/MarcosAndradeV/go-ecommerce/internal/handlers"
 	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
@@ -17,29 +18,27 @@ import (
 )
 
 func main() {
-	// 1. Carregar variáveis
-	if err := godotenv.Load(); err != nil {
-		log.Println("Aviso: Arquivo .env não encontrado, usando vars de ambiente")
+	// 1. Configuração (.env, CONFIG_FILE e variáveis de ambiente)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("ERRO: configuração inválida:\n%v", err)
 	}
-
-	port := os.Getenv("PORT")
-	if port == "" { port = "8080" }
-	mongoURI := os.Getenv("MONGO_URI")
-	if mongoURI == "" { log.Fatal("ERRO: MONGO_URI não definido") }
+	log.Printf("Configuração efetiva:\n%s", cfg.Redacted())
 
 	// 2. Banco de Dados (Com Timeout para não travar)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	store := database.NewMongoStore("ecommerce_go")
-	if err := store.Connect(ctx, mongoURI); err != nil {
+	store := database.NewMongoStore(cfg.Mongo.DBName)
+	if err := store.Connect(ctx, cfg.Mongo.URI); err != nil {
 		log.Fatalf("FATAL: Falha ao conectar no MongoDB: %v", err)
 	}
-	defer func() {
+	// Chamado explicitamente no fim: os.Exit não roda defers
+	disconnect := func() {
 		if err := store.Disconnect(context.Background()); err != nil {
 			log.Printf("Erro ao desconectar: %v", err)
 		}
-	}()
+	}
 
 	log.Println("Conectado ao MongoDB com sucesso!")
 
@@ -47,21 +46,99 @@ func main() {
 	// Repositórios
 	userRepo := repository.NewUserRepository(store.DB)
 	storeRepo := repository.NewStoreRepository(store.DB)
+	sessionRepo := repository.NewSessionRepository(store.DB)
+
+	timeouts := repository.TimeoutPolicy{
+		Read:        cfg.Mongo.Timeouts.Read,
+		Write:       cfg.Mongo.Timeouts.Write,
+		Transaction: cfg.Mongo.Timeouts.Transaction,
+		Overrides:   cfg.Mongo.Timeouts.Overrides,
+	}
+	userRepo.Timeouts = timeouts
+	storeRepo.Timeouts = timeouts
+	sessionRepo.Timeouts = timeouts
 
 	// Serviços (Aqui que o erro de nil poderia acontecer se userRepo fosse nil)
-	authService := service.NewAuthService(userRepo)
-	paymentService := service.NewPaymentService()
-	storeService := service.NewStoreService(storeRepo, paymentService)
+	authService := service.NewAuthService(userRepo, sessionRepo)
+	authService.SessionTTL = cfg.Session.TTL
+	authService.SessionIdleTimeout = cfg.Session.IdleTimeout
+
+	paymentGateway, err := service.NewPaymentGateway(service.PaymentConfig{
+		Provider: cfg.Payment.Provider,
+		HTTPURL:  cfg.Payment.HTTPURL,
+		APIKey:   cfg.Payment.APIKey,
+		Pix: service.PixConfig{
+			Key:          cfg.Pix.Key,
+			MerchantName: cfg.Pix.MerchantName,
+			MerchantCity: cfg.Pix.MerchantCity,
+		},
+	})
+	if err != nil {
+		log.Fatalf("ERRO: %v", err)
+	}
+	storeService := service.NewStoreService(storeRepo, paymentGateway)
+	storeService.ReservationWindow = cfg.Pix.ReservationWindow
+	storeService.GuestCartTTL = cfg.Session.GuestCartTTL
+
+	// Subcomandos de CLI (ex: create-admin) rodam e saem sem subir o servidor
+	if len(os.Args) > 1 {
+		err := runCommand(os.Args[1:], store, authService)
+		disconnect()
+		if err != nil {
+			log.Printf("ERRO: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Índices e validadores (ver database.Migrations)
+	if cfg.Mongo.AutoMigrate {
+		migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), time.Minute)
+		ran, err := store.Migrate(migrateCtx)
+		cancelMigrate()
+		for _, m := range ran {
+			log.Printf("Migração %d aplicada: %s", m.Version, m.Description)
+		}
+		if err != nil {
+			disconnect()
+			log.Fatalf("FATAL: migrações: %v", err)
+		}
+	}
+
+	// Worker que expira pedidos PIX não pagos e devolve o estoque.
+	// Para junto com o servidor (workerCtx é cancelado no desligamento).
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		storeService.RunReservationWorker(workerCtx, time.Minute)
+	}()
 
 	// Handlers
+	handlers.StoreName = cfg.Store.Name
+	handlers.Cookies = handlers.CookieOptions{
+		Secure:   cfg.Session.CookieSecure,
+		SameSite: cfg.Session.SameSite(),
+		Domain:   cfg.Session.CookieDomain,
+	}
+	if cfg.Session.CookieSecret != "" { // <--- obrigatório fora de desenvolvimento (config.Validate)
+		handlers.GuestCartKey = []byte(cfg.Session.CookieSecret)
+		handlers.CSRFKey = []byte(cfg.Session.CookieSecret)
+	}
 	authHandler := handlers.NewAuthHandler(authService)
+	authHandler.Store = storeService // <--- junta o carrinho de visitante no login
 	storeHandler := handlers.NewStoreHandler(storeService)
+	storeHandler.DevMode = cfg.IsDevelopment()
+	webhookHandler := handlers.NewWebhookHandler(storeService, cfg.Payment.WebhookSecret)
+	apiHandler := handlers.NewAPIHandler(authService, storeService)
+	healthHandler := handlers.NewHealthHandler(store)
 
 	// 4. Rotas (Passamos authService também para o Middleware)
-	r := routes.NewRouter(authHandler, storeHandler, authService)
+	r := routes.NewRouter(authHandler, storeHandler, webhookHandler, apiHandler, healthHandler, authService)
 
 	// 5. Servidor
-	serverAddr := ":" + port
+	drainTimeout := cfg.ShutdownTimeout
+	serverAddr := ":" + cfg.Port
 	log.Printf("Servidor rodando em http://localhost%s", serverAddr)
 
 	srv := &http.Server{
@@ -71,7 +148,37 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 	}
 
-	if err := srv.ListenAndServe(); err != nil {
-		log.Fatal(err)
+	// 6. Desligamento gracioso: no SIGINT/SIGTERM para de aceitar conexões e
+	// espera os requests em andamento (ex: checkout) por até SHUTDOWN_TIMEOUT
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	exitCode := 0
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-serverErr:
+		// Nem chegou a subir (ex: porta em uso)
+		log.Printf("ERRO: %v", err)
+		exitCode = 1
+	case <-sigCtx.Done():
+		stop() // um segundo Ctrl+C mata na hora
+		log.Printf("Desligando; aguardando requests em andamento (até %s)", drainTimeout)
+
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), drainTimeout)
+		err := srv.Shutdown(shutdownCtx)
+		cancelShutdown()
+		if err != nil {
+			log.Printf("ERRO: requests não terminaram a tempo: %v", err)
+			srv.Close()
+			exitCode = 1
+		}
 	}
+
+	stopWorker()
+	<-workerDone
+	disconnect()
+	log.Println("Servidor encerrado")
+	os.Exit(exitCode)
 }
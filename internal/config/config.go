@@ -0,0 +1,295 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config é a configuração efetiva da aplicação. Ordem de precedência
+// (o último vence): padrões < arquivo (CONFIG_FILE) < .env < variáveis de ambiente.
+// O .env nunca sobrescreve uma variável que já está no ambiente.
+type Config struct {
+	Env             string        `yaml:"env" toml:"env"` // development libera o "Simular Pagamento"
+	Port            string        `yaml:"port" toml:"port"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+
+	Mongo   MongoConfig   `yaml:"mongo" toml:"mongo"`
+	Session SessionConfig `yaml:"session" toml:"session"`
+	Payment PaymentConfig `yaml:"payment" toml:"payment"`
+	Pix     PixConfig     `yaml:"pix" toml:"pix"`
+	Store   StoreConfig   `yaml:"store" toml:"store"`
+
+	// De onde vieram os valores (só para o log de inicialização)
+	Sources []string `yaml:"-" toml:"-"`
+}
+
+type MongoConfig struct {
+	URI      string         `yaml:"uri" toml:"uri"`
+	DBName   string         `yaml:"db_name" toml:"db_name"`
+	Timeouts TimeoutsConfig `yaml:"timeouts" toml:"timeouts"`
+
+	// Aplica as migrações pendentes ao subir o servidor (senão: `migrate`)
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate"`
+}
+
+// TimeoutsConfig: prazos das operações no banco (o main monta o
+// repository.TimeoutPolicy com eles)
+type TimeoutsConfig struct {
+	Read        time.Duration            `yaml:"read" toml:"read"`
+	Write       time.Duration            `yaml:"write" toml:"write"`
+	Transaction time.Duration            `yaml:"transaction" toml:"transaction"`
+	Overrides   map[string]time.Duration `yaml:"overrides" toml:"overrides"`
+}
+
+type SessionConfig struct {
+	TTL         time.Duration `yaml:"ttl" toml:"ttl"`
+	IdleTimeout time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+
+	// Atributos dos cookies de sessão e CSRF
+	CookieSecure   bool   `yaml:"cookie_secure" toml:"cookie_secure"`
+	CookieSameSite string `yaml:"cookie_same_site" toml:"cookie_same_site"` // lax, strict ou none
+	CookieDomain   string `yaml:"cookie_domain" toml:"cookie_domain"`
+
+	// Assina o carrinho de visitante e o token CSRF; vazio (só em desenvolvimento) = chave aleatória por processo
+	CookieSecret string        `yaml:"cookie_secret" toml:"cookie_secret"`
+	GuestCartTTL time.Duration `yaml:"guest_cart_ttl" toml:"guest_cart_ttl"`
+}
+
+// SameSite converte cookie_same_site para o valor do net/http
+func (s SessionConfig) SameSite() http.SameSite {
+	switch strings.ToLower(s.CookieSameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+type PaymentConfig struct {
+	Provider      string `yaml:"provider" toml:"provider"` // fake ou http
+	HTTPURL       string `yaml:"http_url" toml:"http_url"`
+	APIKey        string `yaml:"http_api_key" toml:"http_api_key"`
+	WebhookSecret string `yaml:"webhook_secret" toml:"webhook_secret"`
+}
+
+type PixConfig struct {
+	Key               string        `yaml:"key" toml:"key"`
+	MerchantName      string        `yaml:"merchant_name" toml:"merchant_name"`
+	MerchantCity      string        `yaml:"merchant_city" toml:"merchant_city"`
+	ReservationWindow time.Duration `yaml:"reservation_window" toml:"reservation_window"`
+}
+
+type StoreConfig struct {
+	Name     string `yaml:"name" toml:"name"`
+	Currency string `yaml:"currency" toml:"currency"`
+}
+
+// Default devolve a configuração usada quando nada é informado
+func Default() Config {
+	return Config{
+		Env:             "production",
+		Port:            "8080",
+		ShutdownTimeout: 20 * time.Second,
+		Mongo: MongoConfig{
+			DBName:      "ecommerce_go",
+			AutoMigrate: true,
+			Timeouts: TimeoutsConfig{
+				Read:        5 * time.Second,
+				Write:       5 * time.Second,
+				Transaction: 15 * time.Second,
+			},
+		},
+		Session: SessionConfig{
+			TTL:            24 * time.Hour,
+			IdleTimeout:    2 * time.Hour,
+			CookieSameSite: "lax",
+			GuestCartTTL:   30 * 24 * time.Hour,
+		},
+		Payment: PaymentConfig{Provider: "fake"},
+		Pix: PixConfig{
+			MerchantName:      "Olecram Shop",
+			MerchantCity:      "Sao Paulo",
+			ReservationWindow: 30 * time.Minute,
+		},
+		Store: StoreConfig{Name: "Olecram Commerce", Currency: "BRL"},
+	}
+}
+
+// Load monta a configuração (.env, CONFIG_FILE e ambiente) e a valida
+func Load() (*Config, error) {
+	cfg := Default()
+
+	// O .env vai para o ambiente do processo, sem sobrescrever o que já existe
+	if err := godotenv.Load(); err == nil {
+		cfg.Sources = append(cfg.Sources, ".env")
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf(".env: %w", err)
+	}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+		cfg.Sources = append(cfg.Sources, path)
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	cfg.Sources = append(cfg.Sources, "ambiente")
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadFile lê um arquivo YAML (.yaml/.yml) ou TOML (.toml) por cima dos valores atuais
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("arquivo de configuração: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true) // chave com erro de digitação não passa em silêncio
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: chave desconhecida %q", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("%s: formato não suportado (use .yaml, .yml ou .toml)", path)
+	}
+	return nil
+}
+
+// loadEnv aplica as variáveis de ambiente definidas (e não vazias).
+// Os nomes são os mesmos documentados no .env.example.
+func (c *Config) loadEnv() error {
+	var errs []error
+
+	str := func(key string, dst *string) {
+		if v := os.Getenv(key); v != "" {
+			*dst = v
+		}
+	}
+	dur := func(key string, dst *time.Duration) {
+		if v := os.Getenv(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s inválido: %q", key, v))
+				return
+			}
+			*dst = d
+		}
+	}
+	boolean := func(key string, dst *bool) {
+		if v := os.Getenv(key); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s inválido: %q (use true ou false)", key, v))
+				return
+			}
+			*dst = b
+		}
+	}
+
+	str("APP_ENV", &c.Env)
+	str("PORT", &c.Port)
+	dur("SHUTDOWN_TIMEOUT", &c.ShutdownTimeout)
+
+	str("MONGO_URI", &c.Mongo.URI)
+	str("DB_NAME", &c.Mongo.DBName)
+	boolean("DB_AUTO_MIGRATE", &c.Mongo.AutoMigrate)
+	dur("DB_TIMEOUT_READ", &c.Mongo.Timeouts.Read)
+	dur("DB_TIMEOUT_WRITE", &c.Mongo.Timeouts.Write)
+	dur("DB_TIMEOUT_TRANSACTION", &c.Mongo.Timeouts.Transaction)
+	if v := os.Getenv("DB_TIMEOUT_OVERRIDES"); v != "" {
+		overrides, err := parseTimeoutOverrides(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("DB_TIMEOUT_OVERRIDES: %w", err))
+		} else {
+			c.Mongo.Timeouts.Overrides = overrides
+		}
+	}
+
+	dur("SESSION_TTL", &c.Session.TTL)
+	dur("SESSION_IDLE_TIMEOUT", &c.Session.IdleTimeout)
+	boolean("COOKIE_SECURE", &c.Session.CookieSecure)
+	str("COOKIE_SAME_SITE", &c.Session.CookieSameSite)
+	str("COOKIE_DOMAIN", &c.Session.CookieDomain)
+	str("COOKIE_SECRET", &c.Session.CookieSecret)
+	dur("GUEST_CART_TTL", &c.Session.GuestCartTTL)
+
+	str("PAYMENT_PROVIDER", &c.Payment.Provider)
+	str("PAYMENT_HTTP_URL", &c.Payment.HTTPURL)
+	str("PAYMENT_HTTP_API_KEY", &c.Payment.APIKey)
+	str("PAYMENT_WEBHOOK_SECRET", &c.Payment.WebhookSecret)
+
+	str("PIX_KEY", &c.Pix.Key)
+	str("PIX_MERCHANT_NAME", &c.Pix.MerchantName)
+	str("PIX_MERCHANT_CITY", &c.Pix.MerchantCity)
+	if v := os.Getenv("PIX_RESERVATION_MINUTES"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes <= 0 {
+			errs = append(errs, fmt.Errorf("PIX_RESERVATION_MINUTES inválido: %q", v))
+		} else {
+			c.Pix.ReservationWindow = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	str("STORE_NAME", &c.Store.Name)
+	str("STORE_CURRENCY", &c.Store.Currency)
+
+	return errors.Join(errs...)
+}
+
+// IsDevelopment indica o ambiente local
+func (c *Config) IsDevelopment() bool {
+	return c.Env == "development"
+}
+
+// parseTimeoutOverrides lê "GetAllOrders=10s,RunInTransaction=30s"
+func parseTimeoutOverrides(s string) (map[string]time.Duration, error) {
+	overrides := map[string]time.Duration{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		op, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("timeout inválido %q: use Operacao=duração", pair)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("timeout inválido para %s: %w", op, err)
+		}
+		overrides[strings.TrimSpace(op)] = d
+	}
+	return overrides, nil
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+port: "9000"
+mongo:
+  uri: mongodb://arquivo:27017
+  db_name: loja_arquivo
+  timeouts:
+    read: 2s
+    overrides:
+      GetAllOrders: 30s
+pix:
+  reservation_window: 45m
+store:
+  name: Loja do Arquivo
+`,
+		"config.toml": `
+port = "9000"
+
+[mongo]
+uri = "mongodb://arquivo:27017"
+db_name = "loja_arquivo"
+
+[mongo.timeouts]
+read = "2s"
+
+[mongo.timeouts.overrides]
+GetAllOrders = "30s"
+
+[pix]
+reservation_window = "45m"
+
+[store]
+name = "Loja do Arquivo"
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", writeFile(t, name, content))
+			t.Setenv("MONGO_URI", "")
+			t.Setenv("PORT", "")
+			t.Setenv("DB_NAME", "loja_env") // ambiente vence o arquivo
+			t.Setenv("APP_ENV", "development")
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Port != "9000" || cfg.Mongo.URI != "mongodb://arquivo:27017" {
+				t.Errorf("valores do arquivo não aplicados: port=%q uri=%q", cfg.Port, cfg.Mongo.URI)
+			}
+			if cfg.Mongo.DBName != "loja_env" {
+				t.Errorf("DB_NAME do ambiente deveria vencer o arquivo, veio %q", cfg.Mongo.DBName)
+			}
+			if cfg.Mongo.Timeouts.Read != 2*time.Second || cfg.Mongo.Timeouts.Overrides["GetAllOrders"] != 30*time.Second {
+				t.Errorf("timeouts do arquivo: %+v", cfg.Mongo.Timeouts)
+			}
+			if cfg.Mongo.Timeouts.Write != 5*time.Second {
+				t.Errorf("padrão de write perdido: %v", cfg.Mongo.Timeouts.Write)
+			}
+			if cfg.Pix.ReservationWindow != 45*time.Minute || cfg.Store.Name != "Loja do Arquivo" {
+				t.Errorf("pix/store do arquivo: %+v %+v", cfg.Pix, cfg.Store)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	t.Setenv("MONGO_URI", "mongodb://localhost:27017")
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", "mongo:\n  db_nome: errado\n"))
+
+	if _, err := Load(); err == nil {
+		t.Fatal("chave desconhecida deveria falhar")
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("MONGO_URI", "mongodb://localhost:27017")
+	t.Setenv("PIX_RESERVATION_MINUTES", "10")
+	t.Setenv("DB_TIMEOUT_OVERRIDES", "RunInTransaction=1m")
+	t.Setenv("COOKIE_SECURE", "true")
+	t.Setenv("APP_ENV", "development")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Pix.ReservationWindow != 10*time.Minute {
+		t.Errorf("reserva = %v", cfg.Pix.ReservationWindow)
+	}
+	if cfg.Mongo.Timeouts.Overrides["RunInTransaction"] != time.Minute {
+		t.Errorf("overrides = %v", cfg.Mongo.Timeouts.Overrides)
+	}
+	if !cfg.Session.CookieSecure || !cfg.IsDevelopment() {
+		t.Errorf("COOKIE_SECURE/APP_ENV não aplicados: %+v", cfg.Session)
+	}
+	if cfg.Mongo.DBName != "ecommerce_go" {
+		t.Errorf("DB_NAME padrão = %q", cfg.Mongo.DBName)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.Port = "porta"
+	cfg.Payment.Provider = "http"
+	cfg.Session.CookieSameSite = "none"
+	cfg.Pix.MerchantCity = "Cidade Com Nome Muito Longo"
+	cfg.Store.Currency = "USD"
+	cfg.Session.CookieSecret = "curto"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("esperava erro")
+	}
+	for _, want := range []string{
+		"PORT", "MONGO_URI", "PAYMENT_HTTP_URL", "PAYMENT_WEBHOOK_SECRET",
+		"COOKIE_SECURE", "PIX_MERCHANT_CITY", "STORE_CURRENCY", "COOKIE_SECRET",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("erro não menciona %s:\n%v", want, err)
+		}
+	}
+}
+
+func TestValidateRejectsFakeProviderOutsideDevelopment(t *testing.T) {
+	cfg := Default()
+	cfg.Mongo.URI = "mongodb://localhost:27017"
+	cfg.Pix.Key = "chave-pix-da-loja"
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "PAYMENT_PROVIDER=fake") {
+		t.Fatalf("produção com o provedor fake deveria falhar, veio %v", err)
+	}
+
+	cfg.Env = "development"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("fake em desenvolvimento deveria passar: %v", err)
+	}
+}
+
+func TestValidateRequiresPixKeyOutsideDevelopment(t *testing.T) {
+	cfg := Default()
+	cfg.Mongo.URI = "mongodb://localhost:27017"
+	cfg.Payment = PaymentConfig{Provider: "http", HTTPURL: "http://pagamentos:9090", WebhookSecret: "segredo"}
+
+	if cfg.Pix.Key != "" {
+		t.Fatalf("PIX_KEY não deveria ter padrão, veio %q", cfg.Pix.Key)
+	}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "PIX_KEY") {
+		t.Fatalf("produção sem PIX_KEY deveria falhar, veio %v", err)
+	}
+
+	cfg.Pix.Key = "chave-pix-da-loja"
+	cfg.Session.CookieSecret = strings.Repeat("s", minCookieSecret)
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("configuração de produção completa deveria passar: %v", err)
+	}
+
+	cfg.Pix.Key = ""
+	cfg.Env = "development"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("desenvolvimento sem PIX_KEY deveria passar: %v", err)
+	}
+}
+
+func TestValidateRequiresCookieSecretOutsideDevelopment(t *testing.T) {
+	cfg := Default()
+	cfg.Mongo.URI = "mongodb://localhost:27017"
+	cfg.Payment = PaymentConfig{Provider: "http", HTTPURL: "http://pagamentos:9090", WebhookSecret: "segredo"}
+	cfg.Pix.Key = "chave-pix-da-loja"
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "COOKIE_SECRET não definido") {
+		t.Fatalf("produção sem COOKIE_SECRET deveria falhar, veio %v", err)
+	}
+
+	cfg.Session.CookieSecret = strings.Repeat("s", minCookieSecret)
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("produção com COOKIE_SECRET deveria passar: %v", err)
+	}
+
+	cfg.Session.CookieSecret = ""
+	cfg.Env = "development"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("desenvolvimento sem COOKIE_SECRET deveria passar: %v", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Mongo.URI = "mongodb://loja:senha-do-banco@db:27017"
+	cfg.Payment.APIKey = "chave-da-api"
+	cfg.Payment.WebhookSecret = "segredo-do-webhook"
+	cfg.Session.CookieSecret = "segredo-do-cookie-do-carrinho-de-visitante"
+	cfg.Pix.Key = "chave-pix-da-loja"
+
+	out := cfg.Redacted()
+	for _, secret := range []string{"senha-do-banco", "chave-da-api", "segredo-do-webhook", cfg.Session.CookieSecret, cfg.Pix.Key} {
+		if strings.Contains(out, secret) {
+			t.Errorf("segredo %q apareceu no log:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "db_name: ecommerce_go") || !strings.Contains(out, "read: 5s") {
+		t.Errorf("configuração efetiva incompleta:\n%s", out)
+	}
+}
+
+func TestParseTimeoutOverrides(t *testing.T) {
+	got, err := parseTimeoutOverrides(" GetAllOrders=10s, RunInTransaction=1m ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["GetAllOrders"] != 10*time.Second || got["RunInTransaction"] != time.Minute || len(got) != 2 {
+		t.Errorf("overrides = %v", got)
+	}
+
+	for _, bad := range []string{"GetAllOrders", "GetAllOrders=dez"} {
+		if _, err := parseTimeoutOverrides(bad); err == nil {
+			t.Errorf("%q deveria ser inválido", bad)
+		}
+	}
+}
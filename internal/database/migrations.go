@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Coleção onde fica registrada cada migração aplicada
+const migrationsCollection = "schema_migrations"
+
+// Migration é uma mudança versionada de esquema/índices. Up precisa ser
+// idempotente: duas instâncias subindo juntas podem rodar a mesma versão.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// MigrationRecord é o documento gravado em schema_migrations
+type MigrationRecord struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// MigrationState junta a migração conhecida com o registro (nil = pendente)
+type MigrationState struct {
+	Migration
+	Applied *MigrationRecord
+}
+
+// Migrator aplica as migrações em ordem de versão
+type Migrator struct {
+	db         *mongo.Database
+	migrations []Migration
+}
+
+func NewMigrator(db *mongo.Database, migrations []Migration) (*Migrator, error) {
+	if err := checkMigrations(migrations); err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// checkMigrations exige versões positivas, em ordem crescente e sem repetição
+func checkMigrations(migrations []Migration) error {
+	last := 0
+	for _, m := range migrations {
+		if m.Version <= last {
+			return fmt.Errorf("migração %d fora de ordem (depois da %d)", m.Version, last)
+		}
+		if m.Up == nil {
+			return fmt.Errorf("migração %d sem Up", m.Version)
+		}
+		last = m.Version
+	}
+	return nil
+}
+
+// Status lista todas as migrações e quando cada uma foi aplicada
+func (m *Migrator) Status(ctx context.Context) ([]MigrationState, error) {
+	cursor, err := m.db.Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var records []MigrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := map[int]*MigrationRecord{}
+	for i := range records {
+		applied[records[i].Version] = &records[i]
+	}
+
+	states := make([]MigrationState, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		states = append(states, MigrationState{Migration: mig, Applied: applied[mig.Version]})
+	}
+	return states, nil
+}
+
+// Up aplica as pendentes, em ordem, e devolve as que rodaram.
+// Para na primeira falha: as seguintes podem depender dela.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	states, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, state := range states {
+		if state.Applied != nil {
+			continue
+		}
+		mig := state.Migration
+		if err := mig.Up(ctx, m.db); err != nil {
+			return ran, fmt.Errorf("migração %d (%s): %w", mig.Version, mig.Description, err)
+		}
+
+		record := MigrationRecord{Version: mig.Version, Description: mig.Description, AppliedAt: time.Now()}
+		_, err := m.db.Collection(migrationsCollection).ReplaceOne(ctx,
+			bson.M{"_id": mig.Version}, record, options.Replace().SetUpsert(true))
+		if err != nil {
+			return ran, fmt.Errorf("migração %d aplicada mas não registrada: %w", mig.Version, err)
+		}
+		ran = append(ran, mig)
+	}
+	return ran, nil
+}
+
+// Migrate aplica as migrações da aplicação (Migrations) no banco conectado
+func (s *MongoStore) Migrate(ctx context.Context) ([]Migration, error) {
+	if s.DB == nil {
+		return nil, errors.New("mongo: not connected")
+	}
+	migrator, err := NewMigrator(s.DB, Migrations)
+	if err != nil {
+		return nil, err
+	}
+	return migrator.Up(ctx)
+}
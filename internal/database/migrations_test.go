@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	if err := checkMigrations(Migrations); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckMigrations(t *testing.T) {
+	up := func(context.Context, *mongo.Database) error { return nil }
+
+	tests := []struct {
+		name       string
+		migrations []Migration
+		ok         bool
+	}{
+		{"vazia", nil, true},
+		{"em ordem", []Migration{{Version: 1, Up: up}, {Version: 3, Up: up}}, true},
+		{"repetida", []Migration{{Version: 1, Up: up}, {Version: 1, Up: up}}, false},
+		{"fora de ordem", []Migration{{Version: 2, Up: up}, {Version: 1, Up: up}}, false},
+		{"versão zero", []Migration{{Version: 0, Up: up}}, false},
+		{"sem Up", []Migration{{Version: 1}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMigrations(tt.migrations)
+			if (err == nil) != tt.ok {
+				t.Errorf("checkMigrations = %v, ok esperado = %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+// Roda contra um Mongo de verdade (ex: MONGO_TEST_URI=mongodb://localhost:27017/?replicaSet=rs0)
+func TestMigratorMongo(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI não definido")
+	}
+
+	store := NewMongoStore("ecommerce_migrations_" + primitive.NewObjectID().Hex())
+	if err := store.Connect(t.Context(), uri); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		store.DB.Drop(context.Background())
+		store.Disconnect(context.Background())
+	})
+
+	ran, err := store.Migrate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != len(Migrations) {
+		t.Fatalf("aplicou %d de %d migrações", len(ran), len(Migrations))
+	}
+
+	// Segunda rodada não faz nada
+	ran, err = store.Migrate(t.Context())
+	if err != nil || len(ran) != 0 {
+		t.Fatalf("segunda rodada: %d migrações, erro %v", len(ran), err)
+	}
+
+	// Up é idempotente mesmo sem o registro (duas instâncias subindo juntas)
+	for _, m := range Migrations {
+		if err := m.Up(t.Context(), store.DB); err != nil {
+			t.Errorf("migração %d não é idempotente: %v", m.Version, err)
+		}
+	}
+
+	users := store.DB.Collection("users")
+	user := bson.M{"name": "Ana", "email": "ana@example.com", "password_hash": "x"}
+	if _, err := users.InsertOne(t.Context(), user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.InsertOne(t.Context(), bson.M{"name": "Ana 2", "email": "ana@example.com", "password_hash": "x"}); !mongo.IsDuplicateKeyError(err) {
+		t.Errorf("e-mail repetido: erro = %v, quer duplicate key", err)
+	}
+	if _, err := users.InsertOne(t.Context(), bson.M{"name": "Sem senha", "email": "b@example.com"}); err == nil {
+		t.Error("validador aceitou usuário sem password_hash")
+	}
+
+	// v6: linhas repetidas do carrinho viram uma só
+	pid := primitive.NewObjectID()
+	res, err := users.InsertOne(t.Context(), bson.M{"name": "Bia", "email": "bia@example.com", "password_hash": "x", "cart": bson.A{
+		bson.M{"product_id": pid, "size": "M", "quantity": 1, "price": 100},
+		bson.M{"product_id": pid, "size": "G", "quantity": 1, "price": 100},
+		bson.M{"product_id": pid, "size": "M", "quantity": 2, "price": 100},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := consolidateCarts(t.Context(), store.DB); err != nil {
+		t.Fatal(err)
+	}
+	var bia struct {
+		Cart []struct {
+			Size     string `bson:"size"`
+			Quantity int    `bson:"quantity"`
+		} `bson:"cart"`
+	}
+	if err := users.FindOne(t.Context(), bson.M{"_id": res.InsertedID}).Decode(&bia); err != nil {
+		t.Fatal(err)
+	}
+	if len(bia.Cart) != 2 || bia.Cart[0].Size != "M" || bia.Cart[0].Quantity != 3 {
+		t.Errorf("carrinho consolidado = %+v", bia.Cart)
+	}
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"errors"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -24,7 +25,7 @@ func NewMongoStore(dbName string) *MongoStore {
 // Connect connects to a MongoDB instance
 func (s *MongoStore) Connect(ctx context.Context, uri string) error {
 	clientOptions := options.Client().ApplyURI(uri)
-	client, err := mongo.Connect(context.Background(), clientOptions)
+	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		return err
 	}
@@ -39,6 +40,14 @@ func (s *MongoStore) Connect(ctx context.Context, uri string) error {
 	return nil
 }
 
+// Ping checks that the primary is reachable (used by /readyz)
+func (s *MongoStore) Ping(ctx context.Context) error {
+	if s.client == nil {
+		return errors.New("mongo: not connected")
+	}
+	return s.client.Ping(ctx, nil)
+}
+
 // Disconnect disconnects from the MongoDB instance
 func (s *MongoStore) Disconnect(ctx context.Context) error {
 	if s.client == nil {
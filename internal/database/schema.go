@@ -0,0 +1,274 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+)
+
+// Migrations é o histórico de esquema da loja. Nunca edite uma versão já
+// publicada: crie a próxima.
+var Migrations = []Migration{
+	{Version: 1, Description: "índices de users, orders, sessions e payment_events", Up: createInitialIndexes},
+	{Version: 2, Description: "validadores $jsonSchema de users, products e orders", Up: addValidators},
+	{Version: 3, Description: "índice único de SKU das variantes", Up: createVariantSKUIndex},
+	{Version: 4, Description: "índices de categorias, coleções e products.category_ids", Up: createCatalogIndexes},
+	{Version: 5, Description: "índice de texto e de ordenação da busca de produtos", Up: createSearchIndexes},
+	{Version: 6, Description: "junta as linhas repetidas dos carrinhos", Up: consolidateCarts},
+	{Version: 7, Description: "expiração (TTL) dos carrinhos de visitantes", Up: createGuestCartIndexes},
+	{Version: 8, Description: "índices de promoções e dos usos de cupons", Up: createPromotionIndexes},
+	{Version: 9, Description: "índice de pedidos por conta (orders.user_id)", Up: createOrderUserIndex},
+}
+
+func createInitialIndexes(ctx context.Context, db *mongo.Database) error {
+	indexes := map[string][]mongo.IndexModel{
+		"users": {
+			// Garante no banco o que RegisterCustomer só checava antes de inserir
+			{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName("email_unique").SetUnique(true)},
+		},
+		"orders": {
+			// Dashboard do cliente (GetOrdersByEmail) e painel (GetAllOrders)
+			{Keys: bson.D{{Key: "customer_email", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("customer_email_created_at")},
+			{Keys: bson.D{{Key: "created_at", Value: -1}}, Options: options.Index().SetName("created_at")},
+			// Worker de reservas (GetExpiredReservations)
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "reserved_until", Value: 1}}, Options: options.Index().SetName("status_reserved_until")},
+			// Webhooks (GetOrderByPaymentID); só pedidos que já têm cobrança
+			{Keys: bson.D{{Key: "payment_id", Value: 1}}, Options: options.Index().SetName("payment_id_unique").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"payment_id": bson.M{"$type": "string"}})},
+		},
+		"sessions": {
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetName("token_hash_unique").SetUnique(true)},
+			// O Mongo apaga a sessão sozinho quando expires_at passa
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0)},
+		},
+		"payment_events": {
+			{Keys: bson.D{{Key: "payment_id", Value: 1}}, Options: options.Index().SetName("payment_id")},
+		},
+	}
+
+	for coll, idx := range indexes {
+		if _, err := db.Collection(coll).Indexes().CreateMany(ctx, idx); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return fmt.Errorf("%s: há documentos duplicados que impedem o índice único; corrija-os e rode de novo: %w", coll, err)
+			}
+			return fmt.Errorf("%s: %w", coll, err)
+		}
+	}
+	return nil
+}
+
+// Os validadores usam validationLevel "moderate": documentos antigos que não
+// passam continuam editáveis, mas todo insert novo é validado.
+func addValidators(ctx context.Context, db *mongo.Database) error {
+	statuses := bson.A{}
+	for _, s := range []models.OrderStatus{
+		models.OrderStatusPending, models.OrderStatusPaid, models.OrderStatusPicking, models.OrderStatusShipped,
+		models.OrderStatusDelivered, models.OrderStatusCancelled, models.OrderStatusRefunded, models.OrderStatusExpired,
+	} {
+		statuses = append(statuses, string(s))
+	}
+	integer := bson.A{"int", "long"}
+
+	schemas := map[string]bson.M{
+		"users": {
+			"bsonType": "object",
+			"required": bson.A{"name", "email", "password_hash"},
+			"properties": bson.M{
+				"name":          bson.M{"bsonType": "string"},
+				"email":         bson.M{"bsonType": "string", "minLength": 1},
+				"password_hash": bson.M{"bsonType": "string", "minLength": 1},
+				"is_admin":      bson.M{"bsonType": "bool"},
+				"roles":         bson.M{"bsonType": "array", "items": bson.M{"bsonType": "string"}},
+				"cart":          bson.M{"bsonType": "array"},
+			},
+		},
+		"products": {
+			"bsonType": "object",
+			"required": bson.A{"name", "price", "stock"},
+			"properties": bson.M{
+				"name":  bson.M{"bsonType": "string", "minLength": 1},
+				"price": bson.M{"bsonType": integer, "minimum": 0}, // centavos
+				"stock": bson.M{"bsonType": integer, "minimum": 0},
+				"sizes": bson.M{"bsonType": bson.A{"array", "null"}},
+			},
+		},
+		"orders": {
+			"bsonType": "object",
+			"required": bson.A{"customer_email", "items", "total", "status", "created_at"},
+			"properties": bson.M{
+				"customer_email": bson.M{"bsonType": "string"},
+				"items":          bson.M{"bsonType": "array", "minItems": 1},
+				"total":          bson.M{"bsonType": integer, "minimum": 0},
+				"status":         bson.M{"enum": statuses},
+				"created_at":     bson.M{"bsonType": "date"},
+			},
+		},
+	}
+
+	for coll, schema := range schemas {
+		if err := setValidator(ctx, db, coll, bson.M{"$jsonSchema": schema}); err != nil {
+			return fmt.Errorf("%s: %w", coll, err)
+		}
+	}
+	return nil
+}
+
+// Um SKU identifica uma única variante na loja toda (entre produtos
+// diferentes; dentro do mesmo produto quem garante é o StoreService)
+func createVariantSKUIndex(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("products").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "variants.sku", Value: 1}},
+		Options: options.Index().SetName("variants_sku_unique").SetUnique(true).
+			SetPartialFilterExpression(bson.M{"variants.sku": bson.M{"$type": "string"}}),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("products: há SKUs repetidos entre produtos; corrija-os e rode de novo: %w", err)
+	}
+	return err
+}
+
+func createCatalogIndexes(ctx context.Context, db *mongo.Database) error {
+	indexes := map[string][]mongo.IndexModel{
+		// /category/{slug} e /collection/{slug}
+		"categories":  {{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetName("slug_unique").SetUnique(true)}},
+		"collections": {{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetName("slug_unique").SetUnique(true)}},
+		// Listagem de uma categoria (GetProductsByCategories)
+		"products": {{Keys: bson.D{{Key: "category_ids", Value: 1}}, Options: options.Index().SetName("category_ids")}},
+	}
+
+	for coll, idx := range indexes {
+		if _, err := db.Collection(coll).Indexes().CreateMany(ctx, idx); err != nil {
+			return fmt.Errorf("%s: %w", coll, err)
+		}
+	}
+	return nil
+}
+
+func createSearchIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("products").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// $text da busca; o nome conta mais que a descrição na relevância
+		{
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().SetName("products_text").
+				SetWeights(bson.M{"name": 10, "description": 2}).
+				SetDefaultLanguage("portuguese"),
+		},
+		// Ordenações da busca (SortPriceAsc/Desc e SortNewest)
+		{Keys: bson.D{{Key: "price", Value: 1}}, Options: options.Index().SetName("price")},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}, Options: options.Index().SetName("created_at")},
+	})
+	if err != nil {
+		return fmt.Errorf("products: %w", err)
+	}
+	return nil
+}
+
+// Antes do AddItemToCart somar na linha existente, adicionar o mesmo
+// produto duas vezes criava duas linhas no carrinho
+func consolidateCarts(ctx context.Context, db *mongo.Database) error {
+	users := db.Collection("users")
+	cur, err := users.Find(ctx, bson.M{"cart.1": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"cart": 1}))
+	if err != nil {
+		return fmt.Errorf("users: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var user struct {
+			ID   any                `bson:"_id"`
+			Cart []models.OrderItem `bson:"cart"`
+		}
+		if err := cur.Decode(&user); err != nil {
+			return fmt.Errorf("users: %w", err)
+		}
+
+		merged := models.MergeCartLines(user.Cart)
+		if len(merged) == len(user.Cart) {
+			continue
+		}
+		// Só troca se o carrinho não mudou desde a leitura
+		_, err := users.UpdateOne(ctx,
+			bson.M{"_id": user.ID, "cart": cur.Current.Lookup("cart")},
+			bson.M{"$set": bson.M{"cart": merged}})
+		if err != nil {
+			return fmt.Errorf("users %v: %w", user.ID, err)
+		}
+	}
+	return cur.Err()
+}
+
+// Carrinho de visitante abandonado some sozinho em expires_at
+func createGuestCartIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("carts").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("carts: %w", err)
+	}
+	return nil
+}
+
+func createPromotionIndexes(ctx context.Context, db *mongo.Database) error {
+	indexes := map[string][]mongo.IndexModel{
+		// Cupom por código (GetPromotionByCode); promoções automáticas não têm código
+		"promotions": {{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetName("code_unique").SetUnique(true).
+			SetPartialFilterExpression(bson.M{"code": bson.M{"$type": "string"}})}},
+		"promotion_redemptions": {
+			// Limite por cliente (CountPromotionRedemptions)
+			{Keys: bson.D{{Key: "promotion_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetName("promotion_id_user_id")},
+			// Devolução dos usos de um pedido cancelado/expirado
+			{Keys: bson.D{{Key: "order_id", Value: 1}}, Options: options.Index().SetName("order_id")},
+		},
+	}
+
+	for coll, idx := range indexes {
+		if _, err := db.Collection(coll).Indexes().CreateMany(ctx, idx); err != nil {
+			return fmt.Errorf("%s: %w", coll, err)
+		}
+	}
+	return nil
+}
+
+func createOrderUserIndex(ctx context.Context, db *mongo.Database) error {
+	// Pedidos da conta (GetOrdersByUserID): dashboard e /api/v1/orders
+	_, err := db.Collection("orders").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		Options: options.Index().SetName("user_id_created_at"),
+	})
+	return err
+}
+
+// setValidator cria a coleção com o validador ou troca o de uma já existente
+func setValidator(ctx context.Context, db *mongo.Database, coll string, validator bson.M) error {
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": coll})
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		err := db.CreateCollection(ctx, coll, options.CreateCollection().
+			SetValidator(validator).
+			SetValidationLevel("moderate").
+			SetValidationAction("error"))
+		// Outra instância pode ter criado no meio do caminho: cai no collMod
+		var cmdErr mongo.CommandError
+		if err == nil || !errors.As(err, &cmdErr) || cmdErr.Name != "NamespaceExists" {
+			return err
+		}
+	}
+
+	return db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: coll},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+		{Key: "validationAction", Value: "error"},
+	}).Err()
+}
@@ -0,0 +1,529 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// APIHandler expõe a loja em JSON (/api/v1) para o app mobile.
+// Reaproveita os mesmos serviços das páginas HTML.
+type APIHandler struct {
+	Auth  *service.AuthService
+	Store *service.StoreService
+}
+
+func NewAPIHandler(auth *service.AuthService, store *service.StoreService) *APIHandler {
+	return &APIHandler{Auth: auth, Store: store}
+}
+
+// Formato de erro comum a todas as respostas da API
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type apiErrorBody struct {
+	Error APIError `json:"error"`
+}
+
+type apiDataBody struct {
+	Data any `json:"data"`
+}
+
+// WriteJSON responde {"data": ...} com o status informado
+func WriteJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiDataBody{Data: data})
+}
+
+// WriteJSONError responde {"error": {"code", "message"}}
+func WriteJSONError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiErrorBody{Error: APIError{Code: code, Message: message}})
+}
+
+// writeServiceError traduz os erros dos serviços para status HTTP
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrProductNotFound), errors.Is(err, service.ErrOrderNotFound),
+		errors.Is(err, service.ErrVariantNotFound):
+		WriteJSONError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, repository.ErrInsufficientStock):
+		WriteJSONError(w, http.StatusConflict, "out_of_stock", err.Error())
+	case errors.Is(err, service.ErrCouponNotFound), errors.Is(err, service.ErrCouponNotApplicable):
+		WriteJSONError(w, http.StatusUnprocessableEntity, "invalid_coupon", err.Error())
+	case errors.Is(err, repository.ErrPromotionLimitReached), errors.Is(err, service.ErrPromotionNotFound):
+		WriteJSONError(w, http.StatusConflict, "promotion_unavailable", err.Error())
+	case errors.Is(err, service.ErrInvalidZip):
+		WriteJSONError(w, http.StatusBadRequest, "invalid_zip", err.Error())
+	case errors.Is(err, service.ErrShippingUnavailable), errors.Is(err, service.ErrShippingMethodNotFound):
+		WriteJSONError(w, http.StatusUnprocessableEntity, "shipping_unavailable", err.Error())
+	case errors.Is(err, service.ErrCartChanged):
+		WriteJSONError(w, http.StatusConflict, "cart_changed", err.Error())
+	case errors.Is(err, service.ErrOrderNotPending):
+		WriteJSONError(w, http.StatusConflict, "order_not_pending", err.Error())
+	case errors.Is(err, service.ErrEmailTaken):
+		WriteJSONError(w, http.StatusConflict, "email_taken", err.Error())
+	case errors.Is(err, service.ErrPaymentDeclined):
+		WriteJSONError(w, http.StatusPaymentRequired, "payment_declined", err.Error())
+	case errors.Is(err, service.ErrPaymentRequires3DS):
+		WriteJSONError(w, http.StatusUnprocessableEntity, "payment_requires_3ds", err.Error())
+	case errors.Is(err, service.ErrPaymentTimeout), errors.Is(err, service.ErrPaymentProviderFailure):
+		WriteJSONError(w, http.StatusBadGateway, "payment_unavailable", err.Error())
+	case errors.Is(err, service.ErrCardNumberInvalid), errors.Is(err, service.ErrCardBrandUnknown),
+		errors.Is(err, service.ErrCardExpiryInvalid), errors.Is(err, service.ErrCardExpired),
+		errors.Is(err, service.ErrCardCVVInvalid):
+		WriteJSONError(w, http.StatusUnprocessableEntity, "invalid_card", err.Error())
+	case errors.Is(err, service.ErrUnsupportedPaymentMethod), errors.Is(err, service.ErrMissingCard),
+		errors.Is(err, service.ErrNoItemsSelected), errors.Is(err, service.ErrVariantRequired),
+		errors.Is(err, service.ErrInvalidSearch), errors.Is(err, service.ErrCategoryNotFound):
+		WriteJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+	default:
+		log.Printf("Erro na API: %v", err)
+		WriteJSONError(w, http.StatusInternalServerError, "internal_error", "erro interno")
+	}
+}
+
+// decodeJSON lê o corpo da requisição; em caso de erro já responde 400
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		WriteJSONError(w, http.StatusBadRequest, "invalid_json", "JSON inválido")
+		return false
+	}
+	return true
+}
+
+// --- AUTH ---
+
+type apiLoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type apiRegisterRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type apiLoginResponse struct {
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	User      *models.User `json:"user"`
+}
+
+func (h *APIHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req apiLoginRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.Auth.AuthenticateUser(r.Context(), req.Email, req.Password)
+	if err != nil {
+		WriteJSONError(w, http.StatusUnauthorized, "invalid_credentials", err.Error())
+		return
+	}
+
+	token, expiresAt, err := h.Auth.CreateSession(r.Context(), user.ID, r.UserAgent())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, apiLoginResponse{Token: token, ExpiresAt: expiresAt, User: user})
+}
+
+func (h *APIHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	var req apiRegisterRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Name == "" || req.Email == "" || req.Password == "" {
+		WriteJSONError(w, http.StatusBadRequest, "invalid_request", "nome, e-mail e senha são obrigatórios")
+		return
+	}
+
+	if err := h.Auth.RegisterCustomer(r.Context(), req.Name, req.Email, req.Password); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	// Já devolve um token para o app não precisar logar em seguida
+	user, err := h.Auth.AuthenticateUser(r.Context(), req.Email, req.Password)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	token, expiresAt, err := h.Auth.CreateSession(r.Context(), user.ID, r.UserAgent())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, apiLoginResponse{Token: token, ExpiresAt: expiresAt, User: user})
+}
+
+func (h *APIHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.Auth.RevokeSession(r.Context(), BearerToken(r)); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BearerToken extrai o token do cabeçalho "Authorization: Bearer <token>"
+func BearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(auth[7:])
+}
+
+// --- CONTA ---
+
+type apiAccount struct {
+	User   *models.User   `json:"user"`
+	Orders []models.Order `json:"orders"`
+}
+
+func (h *APIHandler) AccountHandler(w http.ResponseWriter, r *http.Request) {
+	user, orders, err := h.Auth.GetDashboardData(r.Context(), CurrentUser(r).ID.Hex())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if orders == nil {
+		orders = []models.Order{}
+	}
+	WriteJSON(w, http.StatusOK, apiAccount{User: user, Orders: orders})
+}
+
+// --- CATÁLOGO ---
+
+func (h *APIHandler) ListProductsHandler(w http.ResponseWriter, r *http.Request) {
+	products, err := h.Store.GetShowcase(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if products == nil {
+		products = []models.Product{}
+	}
+	WriteJSON(w, http.StatusOK, products)
+}
+
+func (h *APIHandler) GetProductHandler(w http.ResponseWriter, r *http.Request) {
+	product, err := h.Store.GetProductDetails(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, product)
+}
+
+type apiSearchHighlight struct {
+	Name        string `json:"name"`        // HTML com <mark> nos termos
+	Description string `json:"description"` // trecho da descrição, idem
+}
+
+type apiSearchHit struct {
+	Product   models.Product     `json:"product"`
+	Highlight apiSearchHighlight `json:"highlight"`
+}
+
+type apiSearchResponse struct {
+	Query service.SearchQuery `json:"query"`
+	Items []apiSearchHit      `json:"items"`
+	Total int                 `json:"total"`
+	Pages int                 `json:"pages"`
+}
+
+func (h *APIHandler) SearchProductsHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := parseSearchQuery(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	result, err := h.Store.SearchProducts(r.Context(), q)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	resp := apiSearchResponse{Query: result.Query, Items: []apiSearchHit{}, Total: result.Total, Pages: result.Pages}
+	for _, p := range result.Products {
+		resp.Items = append(resp.Items, apiSearchHit{
+			Product: p,
+			Highlight: apiSearchHighlight{
+				Name:        service.Highlight(p.Name, result.Terms),
+				Description: service.Snippet(p.Description, result.Terms, searchSnippetLength),
+			},
+		})
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// --- CARRINHO ---
+
+type apiCartItemRequest struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+	SKU       string `json:"sku,omitempty"` // obrigatório se o produto tem variantes
+	Size      string `json:"size"`
+}
+
+type apiCartQuantityRequest struct {
+	Quantity int    `json:"quantity"`
+	SKU      string `json:"sku,omitempty"`
+	Size     string `json:"size"`
+}
+
+type apiCart struct {
+	Items []models.OrderItemWithStock `json:"items"`
+	Total int64                       `json:"total"` // em centavos, como os preços
+	// Changed: algum preço mudou (ou produto/tamanho sumiu) desde a última consulta
+	Changed bool `json:"changed"`
+}
+
+func (h *APIHandler) cart(r *http.Request) (*apiCart, error) {
+	_, review, err := h.Store.ReviewUserCart(r.Context(), CurrentUser(r).ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	cart := &apiCart{Items: review.Items, Total: review.Total(), Changed: review.Changed}
+	if cart.Items == nil {
+		cart.Items = []models.OrderItemWithStock{}
+	}
+	return cart, nil
+}
+
+func (h *APIHandler) GetCartHandler(w http.ResponseWriter, r *http.Request) {
+	cart, err := h.cart(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, cart)
+}
+
+func (h *APIHandler) AddCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	var req apiCartItemRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.Store.AddProductToCart(r.Context(), CurrentUser(r).ID.Hex(), req.ProductID, req.Quantity, req.SKU, req.Size); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	cart, err := h.cart(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusCreated, cart)
+}
+
+// UpdateCartItemHandler muda a quantidade, limitada ao estoque (igual ao /update-cart)
+func (h *APIHandler) UpdateCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	var req apiCartQuantityRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Quantity <= 0 {
+		WriteJSONError(w, http.StatusBadRequest, "invalid_request", "quantidade deve ser maior que zero")
+		return
+	}
+
+	productID := chi.URLParam(r, "product_id")
+	product, err := h.Store.GetProductDetails(r.Context(), productID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	stock := product.StockFor(req.SKU)
+	if stock == 0 {
+		WriteJSONError(w, http.StatusConflict, "out_of_stock", "produto fora de estoque")
+		return
+	}
+	if req.Quantity > stock {
+		req.Quantity = stock
+	}
+
+	if err := h.Store.UpdateCartItemQuantity(r.Context(), CurrentUser(r).ID.Hex(), productID, req.Quantity, req.SKU, req.Size); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	cart, err := h.cart(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, cart)
+}
+
+func (h *APIHandler) RemoveCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	productID := chi.URLParam(r, "product_id")
+	sku := r.URL.Query().Get("sku")
+	size := r.URL.Query().Get("size")
+
+	if err := h.Store.RemoveProductFromCart(r.Context(), CurrentUser(r).ID.Hex(), productID, sku, size); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	cart, err := h.cart(r)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, cart)
+}
+
+// --- CHECKOUT E PEDIDOS ---
+
+type apiCheckoutRequest struct {
+	SelectedItems []string             `json:"selected_items"` // line_key das linhas do carrinho
+	Name          string               `json:"name"`
+	Email         string               `json:"email"`
+	Address       string               `json:"address"`
+	PaymentMethod string               `json:"payment_method"`
+	Card          *service.CardDetails `json:"card,omitempty"`
+	Coupon        string               `json:"coupon,omitempty"`
+	// Entrega: CEP e a forma cotada em /api/v1/shipping/quote (vazia = a mais barata)
+	Zip            string `json:"zip,omitempty"`
+	ShippingMethod string `json:"shipping_method,omitempty"`
+}
+
+type apiPix struct {
+	Payload   string `json:"payload"`
+	QRCodeURL string `json:"qr_code_url"`
+}
+
+type apiCheckoutResponse struct {
+	Order *models.Order `json:"order"`
+	Pix   *apiPix       `json:"pix,omitempty"`
+}
+
+func (h *APIHandler) CheckoutHandler(w http.ResponseWriter, r *http.Request) {
+	var req apiCheckoutRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	// Mesma regra do PurchaseHandler: o cartão é tokenizado aqui e não desce para o serviço
+	var cardToken *service.CardToken
+	if req.PaymentMethod == models.PaymentMethodCard {
+		if req.Card == nil {
+			writeServiceError(w, service.ErrMissingCard)
+			return
+		}
+		card := *req.Card
+		card.Number = service.NormalizeCardNumber(card.Number)
+		card.CVV = strings.TrimSpace(card.CVV)
+
+		if _, err := service.ValidateCard(card, time.Now()); err != nil {
+			writeServiceError(w, err)
+			return
+		}
+
+		var err error
+		cardToken, err = h.Store.Payment.TokenizeCard(r.Context(), card)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+	}
+
+	user := CurrentUser(r)
+	order, pixCode, _, err := h.Store.ProcessCartPurchase(r.Context(), user.ID.Hex(), req.Name, req.Email, req.Address, req.PaymentMethod, cardToken, req.SelectedItems,
+		req.Coupon, req.Zip, req.ShippingMethod)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	resp := apiCheckoutResponse{Order: order}
+	if pixCode != "" {
+		// O PNG da página usa o cookie; o app busca pela rota da API com o token
+		resp.Pix = &apiPix{Payload: pixCode, QRCodeURL: "/api/v1/orders/" + order.ID.Hex() + "/pix.png"}
+	}
+	WriteJSON(w, http.StatusCreated, resp)
+}
+
+type apiShippingQuoteRequest struct {
+	Zip           string   `json:"zip"`
+	SelectedItems []string `json:"selected_items,omitempty"` // <--- line_key das linhas; vazio = o carrinho todo
+}
+
+// ShippingQuoteHandler cota as formas de entrega do carrinho para o CEP.
+// Lista vazia: a loja não tem frete configurado.
+func (h *APIHandler) ShippingQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	var req apiShippingQuoteRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	_, review, err := h.Store.ReviewUserCart(r.Context(), CurrentUser(r).ID.Hex())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	items, _ := review.Selected(req.SelectedItems)
+
+	quotes, err := h.Store.ShippingQuotes(r.Context(), items, req.Zip)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if quotes == nil {
+		quotes = []models.ShippingQuote{}
+	}
+	WriteJSON(w, http.StatusOK, quotes)
+}
+
+func (h *APIHandler) ListOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	_, orders, err := h.Auth.GetDashboardData(r.Context(), CurrentUser(r).ID.Hex())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if orders == nil {
+		orders = []models.Order{}
+	}
+	WriteJSON(w, http.StatusOK, orders)
+}
+
+func (h *APIHandler) GetOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order, err := h.Store.GetUserOrder(r.Context(), CurrentUser(r).ID.Hex(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, order)
+}
+
+func (h *APIHandler) OrderPixQRCodeHandler(w http.ResponseWriter, r *http.Request) {
+	png, err := h.Store.GetPixQRCode(r.Context(), CurrentUser(r).ID.Hex(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(png)
+}
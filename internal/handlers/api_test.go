@@ -0,0 +1,235 @@
+// Pacote externo: a autenticação Bearer fica no roteador (routes), que importa handlers
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/handlers"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/routes"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type testAPI struct {
+	t   *testing.T
+	mem *repository.MemoryStore
+	srv *httptest.Server
+}
+
+// newTestAPI sobe o roteador completo sobre o repositório em memória
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	mem := repository.NewMemoryStore()
+	authS := service.NewAuthService(mem, mem)
+	storeS := service.NewStoreService(mem, service.NewFakePaymentGateway(service.PixConfig{
+		Key: "loja@exemplo.com", MerchantName: "Loja", MerchantCity: "Sao Paulo",
+	}))
+
+	router := routes.NewRouter(
+		handlers.NewAuthHandler(authS),
+		handlers.NewStoreHandler(storeS),
+		handlers.NewWebhookHandler(storeS, ""),
+		handlers.NewAPIHandler(authS, storeS),
+		handlers.NewHealthHandler(nil),
+		authS,
+	)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return &testAPI{t: t, mem: mem, srv: srv}
+}
+
+type apiResponse struct {
+	Status int
+	Data   json.RawMessage   `json:"data"`
+	Error  handlers.APIError `json:"error"`
+}
+
+func (a *testAPI) do(method, path, token string, body any) apiResponse {
+	a.t.Helper()
+	var payload []byte
+	switch b := body.(type) {
+	case nil:
+	case string:
+		payload = []byte(b)
+	default:
+		payload, _ = json.Marshal(b)
+	}
+
+	req, _ := http.NewRequest(method, a.srv.URL+path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	out := apiResponse{Status: resp.StatusCode}
+	if resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			a.t.Fatalf("%s %s: resposta não é JSON: %v", method, path, err)
+		}
+	}
+	return out
+}
+
+// register cria a conta pela API e devolve o token
+func (a *testAPI) register(email string) (string, *models.User) {
+	a.t.Helper()
+	resp := a.do(http.MethodPost, "/api/v1/auth/register", "", map[string]string{"name": "Cliente", "email": email, "password": "senha-123"})
+	if resp.Status != http.StatusCreated {
+		a.t.Fatalf("cadastro: %d %+v", resp.Status, resp.Error)
+	}
+	var login struct {
+		Token string       `json:"token"`
+		User  *models.User `json:"user"`
+	}
+	json.Unmarshal(resp.Data, &login)
+	if login.Token == "" || login.User == nil {
+		a.t.Fatalf("cadastro sem token: %s", resp.Data)
+	}
+	return login.Token, login.User
+}
+
+func expectError(t *testing.T, name string, resp apiResponse, status int, code string) {
+	t.Helper()
+	if resp.Status != status || resp.Error.Code != code || resp.Error.Message == "" {
+		t.Errorf("%s: %d %+v, quer %d %q com mensagem", name, resp.Status, resp.Error, status, code)
+	}
+	if resp.Data != nil {
+		t.Errorf("%s: erro não deveria ter data: %s", name, resp.Data)
+	}
+}
+
+func TestAPIAuth(t *testing.T) {
+	api := newTestAPI(t)
+	token, user := api.register("ana@example.com")
+	if user.PasswordHash != "" {
+		t.Error("o hash da senha não pode sair na API")
+	}
+
+	expectError(t, "e-mail repetido", api.do(http.MethodPost, "/api/v1/auth/register", "",
+		map[string]string{"name": "Ana", "email": "ana@example.com", "password": "outra"}), http.StatusConflict, "email_taken")
+	expectError(t, "cadastro sem senha", api.do(http.MethodPost, "/api/v1/auth/register", "",
+		map[string]string{"name": "Ana", "email": "nova@example.com"}), http.StatusBadRequest, "invalid_request")
+	expectError(t, "JSON quebrado", api.do(http.MethodPost, "/api/v1/auth/login", "", `{"email":`), http.StatusBadRequest, "invalid_json")
+	expectError(t, "senha errada", api.do(http.MethodPost, "/api/v1/auth/login", "",
+		map[string]string{"email": "ana@example.com", "password": "errada"}), http.StatusUnauthorized, "invalid_credentials")
+
+	login := api.do(http.MethodPost, "/api/v1/auth/login", "", map[string]string{"email": "ana@example.com", "password": "senha-123"})
+	if login.Status != http.StatusOK {
+		t.Fatalf("login: %d %+v", login.Status, login.Error)
+	}
+
+	// Bearer
+	expectError(t, "sem token", api.do(http.MethodGet, "/api/v1/account", "", nil), http.StatusUnauthorized, "unauthorized")
+	expectError(t, "token inventado", api.do(http.MethodGet, "/api/v1/account", "nao-existe", nil), http.StatusUnauthorized, "unauthorized")
+	if resp := api.do(http.MethodGet, "/api/v1/account", token, nil); resp.Status != http.StatusOK {
+		t.Fatalf("conta: %d %+v", resp.Status, resp.Error)
+	}
+
+	// Logout derruba só o token usado
+	if resp := api.do(http.MethodPost, "/api/v1/auth/logout", token, nil); resp.Status != http.StatusNoContent {
+		t.Fatalf("logout: %d", resp.Status)
+	}
+	expectError(t, "token depois do logout", api.do(http.MethodGet, "/api/v1/account", token, nil), http.StatusUnauthorized, "unauthorized")
+}
+
+func TestAPIOrdersOwnership(t *testing.T) {
+	api := newTestAPI(t)
+	ctx := context.Background()
+
+	product := models.Product{ID: primitive.NewObjectID(), Name: "Camiseta", Price: 4990, Stock: 10, CreatedAt: time.Now()}
+	if err := api.mem.CreateProduct(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+
+	ana, _ := api.register("ana@example.com")
+	bia, biaUser := api.register("bia@example.com")
+
+	expectError(t, "produto inexistente", api.do(http.MethodPost, "/api/v1/cart/items", ana,
+		map[string]any{"product_id": primitive.NewObjectID().Hex(), "quantity": 1}), http.StatusNotFound, "not_found")
+	cart := api.do(http.MethodPost, "/api/v1/cart/items", ana, map[string]any{"product_id": product.ID.Hex(), "quantity": 2})
+	if cart.Status != http.StatusCreated {
+		t.Fatalf("carrinho: %d %+v", cart.Status, cart.Error)
+	}
+	var lines struct {
+		Items []struct {
+			LineKey string `json:"line_key"`
+		} `json:"items"`
+	}
+	json.Unmarshal(cart.Data, &lines)
+	if len(lines.Items) != 1 {
+		t.Fatalf("carrinho = %s", cart.Data)
+	}
+	selected := []string{lines.Items[0].LineKey}
+	expectError(t, "forma de pagamento inválida", api.do(http.MethodPost, "/api/v1/checkout", ana,
+		map[string]any{"name": "Ana", "email": "ana@example.com", "address": "Rua A, 1", "payment_method": "boleto", "selected_items": selected}), http.StatusBadRequest, "invalid_request")
+
+	checkout := api.do(http.MethodPost, "/api/v1/checkout", ana,
+		map[string]any{"name": "Ana", "email": "ana@example.com", "address": "Rua A, 1", "payment_method": models.PaymentMethodPix, "selected_items": selected})
+	if checkout.Status != http.StatusCreated {
+		t.Fatalf("checkout: %d %+v", checkout.Status, checkout.Error)
+	}
+	var created struct {
+		Order models.Order `json:"order"`
+		Pix   *struct {
+			QRCodeURL string `json:"qr_code_url"`
+		} `json:"pix"`
+	}
+	json.Unmarshal(checkout.Data, &created)
+	if created.Pix == nil || created.Order.ID.IsZero() {
+		t.Fatalf("checkout sem pedido ou PIX: %s", checkout.Data)
+	}
+	orderPath := "/api/v1/orders/" + created.Order.ID.Hex()
+
+	// A dona vê o pedido; a outra conta recebe 404, não 403 (não revela que existe)
+	if resp := api.do(http.MethodGet, orderPath, ana, nil); resp.Status != http.StatusOK {
+		t.Errorf("dona do pedido: %d %+v", resp.Status, resp.Error)
+	}
+	expectError(t, "pedido de outra conta", api.do(http.MethodGet, orderPath, bia, nil), http.StatusNotFound, "not_found")
+	expectError(t, "ID inválido", api.do(http.MethodGet, "/api/v1/orders/xyz", ana, nil), http.StatusNotFound, "not_found")
+
+	req, _ := http.NewRequest(http.MethodGet, api.srv.URL+created.Pix.QRCodeURL, nil)
+	req.Header.Set("Authorization", "Bearer "+bia)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("QR code de outra conta: %v %v", resp.StatusCode, err)
+	}
+
+	// Pedido de outra conta com o e-mail da Bia digitado no checkout não aparece para ela
+	foreign := models.Order{
+		ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), CustomerEmail: biaUser.Email,
+		Status: models.OrderStatusPaid, Total: 1000, CreatedAt: time.Now(),
+	}
+	if err := api.mem.CreateOrder(ctx, foreign); err != nil {
+		t.Fatal(err)
+	}
+
+	for token, want := range map[string]int{ana: 1, bia: 0} {
+		var orders []models.Order
+		resp := api.do(http.MethodGet, "/api/v1/orders", token, nil)
+		json.Unmarshal(resp.Data, &orders)
+		if resp.Status != http.StatusOK || len(orders) != want {
+			t.Errorf("lista de pedidos: %d, %d pedidos, quer %d", resp.Status, len(orders), want)
+		}
+
+		var account struct {
+			Orders []models.Order `json:"orders"`
+		}
+		resp = api.do(http.MethodGet, "/api/v1/account", token, nil)
+		json.Unmarshal(resp.Data, &account)
+		if len(account.Orders) != want {
+			t.Errorf("pedidos da conta: %d, quer %d", len(account.Orders), want)
+		}
+	}
+}
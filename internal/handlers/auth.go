@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"net/http"
-	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
 
 	"github.com/MarcosAndradeV/go-ecommerce/internal/service"
 )
 
 type AuthHandler struct {
 	Service *service.AuthService
+
+	// Store junta o carrinho de visitante ao do usuário no login/cadastro
+	Store *service.StoreService
 }
 
 func NewAuthHandler(s *service.AuthService) *AuthHandler {
@@ -30,19 +34,7 @@ func (h *AuthHandler) LoginPostHandler(w http.ResponseWriter, r *http.Request) {
 	password := r.FormValue("password")
 	next := r.FormValue("next") // <--- Captura o next
 
-	// 1. Admin Hardcoded
-	if email == "admin" && password == "admin123" {
-		http.SetCookie(w, &http.Cookie{
-			Name:  "sessao_admin",
-			Value: "true",
-			Path:  "/", // <--- OBRIGATÓRIO
-		})
-		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
-		return
-	}
-
-	// 2. Cliente
-	user, err := h.Service.AuthenticateUser(email, password)
+	user, err := h.Service.AuthenticateUser(r.Context(), email, password)
 	if err != nil {
 		// Se falhar, mostra o formulário novamente com mensagem de erro
 		data := map[string]any{
@@ -54,27 +46,40 @@ func (h *AuthHandler) LoginPostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// --- CORREÇÃO CRÍTICA: Path "/" ---
-	http.SetCookie(w, &http.Cookie{
-		Name:     "sessao_loja",
-		Value:    user.ID.Hex(),
-		Path:     "/", // <--- ISSO CONSERTA O LOOP DE LOGIN
-		Expires:  time.Now().Add(24 * time.Hour),
-		HttpOnly: true,
-	})
+	// Sessão no servidor: o cookie leva só um token opaco aleatório
+	token, expiresAt, err := h.Service.CreateSession(r.Context(), user.ID, r.UserAgent())
+	if err != nil {
+		http.Error(w, "Erro ao iniciar sessão", 500)
+		return
+	}
+
+	http.SetCookie(w, NewCookie(SessionCookieName, token, expiresAt)) // Path "/" conserta o loop de login
+	SetCSRFCookie(w, SessionCSRFToken(token))                         // <--- token novo, amarrado à sessão
+	h.mergeGuestCart(w, r, user.ID.Hex())
 
-	// Se tiver next, vai pra lá. Senão, home.
+	// Se tiver next, vai pra lá. Senão, home (ou painel, para a equipe).
 	if next != "" {
 		http.Redirect(w, r, next, http.StatusSeeOther)
 		return
 	}
+	if user.HasRole(models.RoleCatalogEditor) {
+		http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+		return
+	}
+	if user.IsStaff() {
+		http.Redirect(w, r, "/admin/orders", http.StatusSeeOther)
+		return
+	}
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
 func (h *AuthHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	// Para sair, "matamos" o cookie definindo MaxAge -1
-	http.SetCookie(w, &http.Cookie{Name: "sessao_loja", Value: "", Path: "/", MaxAge: -1})
-	http.SetCookie(w, &http.Cookie{Name: "sessao_admin", Value: "", Path: "/", MaxAge: -1})
+	// Revoga a sessão no banco e "matamos" o cookie definindo MaxAge -1
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		h.Service.RevokeSession(r.Context(), cookie.Value)
+	}
+	ClearSessionCookie(w)
+	SetCSRFCookie(w, NewCSRFToken())
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
@@ -88,25 +93,34 @@ func (h *AuthHandler) RegisterPostHandler(w http.ResponseWriter, r *http.Request
 	email := r.FormValue("email")
 	password := r.FormValue("password")
 
-	err := h.Service.RegisterCustomer(name, email, password)
+	err := h.Service.RegisterCustomer(r.Context(), name, email, password)
 	if err != nil {
 		http.Redirect(w, r, "/register?error=true", http.StatusSeeOther)
 		return
 	}
+
+	// O carrinho montado antes do cadastro já fica na conta nova
+	if guestCartID(r) != "" {
+		if user, err := h.Service.AuthenticateUser(r.Context(), email, password); err == nil {
+			h.mergeGuestCart(w, r, user.ID.Hex())
+		}
+	}
+	SetCSRFCookie(w, NewCSRFToken()) // <--- o token de antes do cadastro não segue valendo
 	http.Redirect(w, r, "/login?success=created", http.StatusSeeOther)
 }
 
 func (h *AuthHandler) DashboardHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("sessao_loja")
-	if err != nil {
+	current := CurrentUser(r)
+	if current == nil {
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
 
-	user, orders, err := h.Service.GetDashboardData(cookie.Value)
+	user, orders, err := h.Service.GetDashboardData(r.Context(), current.ID.Hex())
 	if err != nil {
 		// Se o usuário não existe mais, desloga
-		http.Redirect(w, r, "/logout", http.StatusSeeOther)
+		ClearSessionCookie(w)
+		http.Redirect(w, r, "/login?msg=session_expired", http.StatusSeeOther)
 		return
 	}
 
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// --- VITRINE: CATEGORIAS E COLEÇÕES ---
+
+func (h *StoreHandler) CategoryHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := h.Service.GetCategoryPage(r.Context(), chi.URLParam(r, "slug"))
+	if errors.Is(err, service.ErrCategoryNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao carregar categoria", 500)
+		return
+	}
+
+	data := map[string]any{
+		"Page": page,
+	}
+	RenderTemplate(w, r, "category.html", data)
+}
+
+func (h *StoreHandler) CollectionHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := h.Service.GetCollectionPage(r.Context(), chi.URLParam(r, "slug"))
+	if errors.Is(err, service.ErrCollectionNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao carregar coleção", 500)
+		return
+	}
+
+	data := map[string]any{
+		"Page": page,
+	}
+	RenderTemplate(w, r, "collection.html", data)
+}
+
+// --- ADMIN: CATEGORIAS E COLEÇÕES ---
+
+func (h *StoreHandler) AdminCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.Service.GetCategoryTree(r.Context())
+	if err != nil {
+		http.Error(w, "Erro ao carregar categorias", 500)
+		return
+	}
+	collections, err := h.Service.GetCollections(r.Context())
+	if err != nil {
+		http.Error(w, "Erro ao carregar coleções", 500)
+		return
+	}
+	products, _ := h.Service.GetShowcase(r.Context())
+
+	data := map[string]any{
+		"Categories":  categories,
+		"Collections": collections,
+		"Products":    products,
+	}
+	RenderTemplate(w, r, "admin_catalog.html", data)
+}
+
+// writeCatalogFormError: erro de validação é 400, o resto 500
+func writeCatalogFormError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCatalogEntry), errors.Is(err, service.ErrCategoryHasChildren),
+		errors.Is(err, service.ErrCategoryNotFound), errors.Is(err, service.ErrCollectionNotFound),
+		errors.Is(err, service.ErrProductNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Erro ao salvar: "+err.Error(), 500)
+	}
+}
+
+func (h *StoreHandler) AdminCreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	err := h.Service.CreateCategory(r.Context(), r.FormValue("name"), r.FormValue("slug"), r.FormValue("parent_id"))
+	if err != nil {
+		writeCatalogFormError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+func (h *StoreHandler) AdminDeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.DeleteCategory(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeCatalogFormError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+func (h *StoreHandler) AdminCreateCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	err := h.Service.CreateCollection(r.Context(), r.FormValue("name"), r.FormValue("slug"),
+		r.FormValue("description"), r.Form["product_ids"])
+	if err != nil {
+		writeCatalogFormError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+func (h *StoreHandler) AdminEditCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	err := h.Service.EditCollection(r.Context(), r.FormValue("id"), r.FormValue("name"), r.FormValue("slug"),
+		r.FormValue("description"), r.Form["product_ids"])
+	if err != nil {
+		writeCatalogFormError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+func (h *StoreHandler) AdminDeleteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.DeleteCollection(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeCatalogFormError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
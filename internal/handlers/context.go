@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+)
+
+// Nome do cookie que carrega o token opaco da sessão
+const SessionCookieName = "sessao_loja"
+
+// CookieOptions são os atributos comuns aos cookies da loja (sessão e CSRF)
+type CookieOptions struct {
+	Secure   bool
+	SameSite http.SameSite
+	Domain   string
+}
+
+// Cookies é definido na inicialização (config.Session); o padrão serve para dev em http
+var Cookies = CookieOptions{SameSite: http.SameSiteLaxMode}
+
+// NewCookie monta um cookie HttpOnly com os atributos configurados
+func NewCookie(name, value string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   Cookies.Domain,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   Cookies.Secure,
+		SameSite: Cookies.SameSite,
+	}
+}
+
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	csrfContextKey
+)
+
+// WithUser coloca o usuário autenticado no contexto do request
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// CurrentUser devolve o usuário resolvido pelo middleware de sessão (ou nil)
+func CurrentUser(r *http.Request) *models.User {
+	user, _ := r.Context().Value(userContextKey).(*models.User)
+	return user
+}
+
+// ClearSessionCookie apaga o cookie de sessão no navegador
+func ClearSessionCookie(w http.ResponseWriter) {
+	cookie := NewCookie(SessionCookieName, "", time.Time{})
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+}
+
+// WithCSRFToken guarda o token CSRF do request (definido pelo middleware)
+func WithCSRFToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, csrfContextKey, token)
+}
+
+// CSRFToken devolve o token que os formulários devem reenviar
+func CSRFToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfContextKey).(string)
+	return token
+}
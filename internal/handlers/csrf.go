@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// Cookie do double-submit: o mesmo valor volta no campo csrf_token ou no
+// header X-CSRF-Token
+const CSRFCookieName = "csrf_token"
+
+// CSRFKey amarra o token CSRF à sessão. Vem do COOKIE_SECRET; sem ele a chave
+// é aleatória por processo e os formulários abertos antes de um reinício
+// deixam de valer.
+var CSRFKey = randomKey()
+
+// NewCSRFToken gera o token de quem ainda não entrou
+func NewCSRFToken() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic("csrf: falha ao gerar token: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// SessionCSRFToken deriva o token de quem está logado: HMAC do hash do token
+// de sessão (o mesmo SHA-256 guardado no banco). Um cookie csrf_token plantado
+// antes do login não serve depois dele.
+func SessionCSRFToken(sessionToken string) string {
+	sum := sha256.Sum256([]byte(sessionToken))
+	mac := hmac.New(sha256.New, CSRFKey)
+	mac.Write([]byte("csrf:" + hex.EncodeToString(sum[:])))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SetCSRFCookie troca o token CSRF do navegador (login, cadastro e logout)
+func SetCSRFCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, NewCookie(CSRFCookieName, token, time.Time{}))
+}
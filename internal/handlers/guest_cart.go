@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Cookie do carrinho de quem ainda não entrou: "<id>.<assinatura>"
+const GuestCartCookieName = "carrinho_visitante"
+
+// GuestCartKey assina o cookie do carrinho de visitante. Sem COOKIE_SECRET
+// a chave é aleatória por processo: os carrinhos de visitante se perdem a
+// cada reinício e não valem entre instâncias.
+var GuestCartKey = randomKey()
+
+func randomKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic("guest cart: falha ao gerar chave: " + err.Error())
+	}
+	return key
+}
+
+func signGuestCart(id string) string {
+	mac := hmac.New(sha256.New, GuestCartKey)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// guestCartID devolve o ID do carrinho de visitante do cookie, ou "" se não
+// houver cookie ou a assinatura não bater
+func guestCartID(r *http.Request) string {
+	cookie, err := r.Cookie(GuestCartCookieName)
+	if err != nil {
+		return ""
+	}
+	id, sig, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(signGuestCart(id))) {
+		return ""
+	}
+	return id
+}
+
+func setGuestCartCookie(w http.ResponseWriter, id string, expires time.Time) {
+	http.SetCookie(w, NewCookie(GuestCartCookieName, id+"."+signGuestCart(id), expires))
+}
+
+func clearGuestCartCookie(w http.ResponseWriter) {
+	cookie := NewCookie(GuestCartCookieName, "", time.Time{})
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+}
+
+// mergeGuestCart leva o carrinho de visitante para a conta que acabou de
+// entrar. Uma falha aqui não impede o login: o cookie fica para a próxima.
+func (h *AuthHandler) mergeGuestCart(w http.ResponseWriter, r *http.Request, userID string) {
+	id := guestCartID(r)
+	if id == "" || h.Store == nil {
+		return
+	}
+	if err := h.Store.MergeGuestCart(r.Context(), userID, id); err != nil {
+		log.Printf("Erro ao juntar o carrinho de visitante: %v", err)
+		return
+	}
+	clearGuestCartCookie(w)
+}
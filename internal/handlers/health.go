@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Prazo do ping no banco feito pelo /readyz
+const readinessTimeout = 2 * time.Second
+
+// Pinger é o que o /readyz precisa do banco (database.MongoStore implementa)
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type HealthHandler struct {
+	DB Pinger
+}
+
+func NewHealthHandler(db Pinger) *HealthHandler {
+	return &HealthHandler{DB: db}
+}
+
+// LivenessHandler (/healthz): o processo está de pé. Não olha o banco,
+// senão uma queda do Mongo faria o orquestrador reiniciar todas as instâncias.
+func (h *HealthHandler) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// ReadinessHandler (/readyz): pronto para receber tráfego (banco respondendo)
+func (h *HealthHandler) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	if err := h.DB.Ping(ctx); err != nil {
+		writeHealth(w, http.StatusServiceUnavailable, healthResponse{Status: "unavailable", Error: err.Error()})
+		return
+	}
+	writeHealth(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+func writeHealth(w http.ResponseWriter, status int, body healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
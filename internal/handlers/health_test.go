@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// pingerFunc deixa o teste decidir a resposta do banco
+type pingerFunc func(ctx context.Context) error
+
+func (f pingerFunc) Ping(ctx context.Context) error { return f(ctx) }
+
+func TestHealthHandlers(t *testing.T) {
+	down := pingerFunc(func(ctx context.Context) error { return errors.New("server selection timeout") })
+	up := pingerFunc(func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			return errors.New("ping sem prazo")
+		}
+		return nil
+	})
+
+	cases := []struct {
+		name    string
+		db      Pinger
+		handler func(h *HealthHandler) http.HandlerFunc
+		status  int
+		body    string
+	}{
+		{"readyz com banco no ar", up, func(h *HealthHandler) http.HandlerFunc { return h.ReadinessHandler }, http.StatusOK, "ok"},
+		{"readyz com banco fora", down, func(h *HealthHandler) http.HandlerFunc { return h.ReadinessHandler }, http.StatusServiceUnavailable, "unavailable"},
+		// O liveness não pode depender do banco
+		{"healthz com banco fora", down, func(h *HealthHandler) http.HandlerFunc { return h.LivenessHandler }, http.StatusOK, "ok"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tc.handler(NewHealthHandler(tc.db))(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var body healthResponse
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tc.status || body.Status != tc.body {
+				t.Errorf("resposta = %d %+v, quer %d %q", w.Code, body, tc.status, tc.body)
+			}
+			if tc.status != http.StatusOK && body.Error == "" {
+				t.Error("503 sem o motivo")
+			}
+			if w.Header().Get("Cache-Control") != "no-store" {
+				t.Error("probe não pode ir para cache")
+			}
+		})
+	}
+}
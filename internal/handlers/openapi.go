@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/openapi"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/service"
+)
+
+// Nomes dos esquemas de autenticação usados no documento
+const (
+	securityBearer  = "bearerAuth"
+	securityCookie  = "sessionCookie"
+	securityWebhook = "webhookSignature"
+)
+
+// specBuilder junta as operações do documento. Os schemas vêm dos mesmos
+// tipos que os handlers codificam/decodificam.
+type specBuilder struct {
+	doc *openapi.Document
+}
+
+// page descreve uma rota HTML. auth indica que exige a sessão do cookie.
+// Em POST o campo csrf_token é acrescentado ao formulário.
+func (b specBuilder) page(method, path, summary string, auth bool, status int, form ...string) {
+	op := &openapi.Operation{Summary: summary, Tags: []string{"páginas"}}
+
+	switch status {
+	case http.StatusSeeOther:
+		op.Responses = map[string]openapi.Response{"303": {Description: "Redireciona após a ação"}}
+	default:
+		op.Responses = map[string]openapi.Response{strconv.Itoa(status): {
+			Description: "Página HTML",
+			Content:     map[string]openapi.MediaType{"text/html": {Schema: &openapi.Schema{Type: "string"}}},
+		}}
+	}
+
+	if method == http.MethodPost {
+		form = append(form, "csrf_token")
+		op.Responses["403"] = openapi.Response{Description: "Token CSRF inválido"}
+	}
+	if len(form) > 0 {
+		props := map[string]*openapi.Schema{}
+		for _, f := range form {
+			props[f] = &openapi.Schema{Type: "string"}
+		}
+		op.RequestBody = &openapi.RequestBody{Content: map[string]openapi.MediaType{
+			"application/x-www-form-urlencoded": {Schema: &openapi.Schema{Type: "object", Properties: props}},
+		}}
+	}
+	if auth {
+		op.Security = []map[string][]string{{securityCookie: {}}}
+	}
+
+	b.doc.Add(method, path, op)
+}
+
+// api descreve uma rota JSON de /api/v1. A resposta de sucesso vai em
+// {"data": resp}; os erros listados usam o envelope {"error": {...}}.
+func (b specBuilder) api(method, path, summary string, auth bool, req any, status int, resp any, errs ...int) {
+	op := &openapi.Operation{Summary: summary, Tags: []string{"api"}}
+
+	if req != nil {
+		op.RequestBody = &openapi.RequestBody{Required: true, Content: openapi.JSON(b.doc.SchemaOf(req))}
+	}
+
+	op.Responses = map[string]openapi.Response{}
+	if resp == nil {
+		op.Responses[strconv.Itoa(status)] = openapi.Response{Description: http.StatusText(status)}
+	} else {
+		op.Responses[strconv.Itoa(status)] = openapi.Response{
+			Description: http.StatusText(status),
+			Content:     openapi.JSON(b.dataEnvelope(resp)),
+		}
+	}
+
+	if auth {
+		op.Security = []map[string][]string{{securityBearer: {}}}
+		errs = append(errs, http.StatusUnauthorized)
+	}
+	errs = append(errs, http.StatusInternalServerError)
+	for _, code := range errs {
+		op.Responses[strconv.Itoa(code)] = openapi.Response{
+			Description: http.StatusText(code),
+			Content:     openapi.JSON(b.doc.SchemaOf(apiErrorBody{})),
+		}
+	}
+
+	b.doc.Add(method, path, op)
+}
+
+func (b specBuilder) dataEnvelope(resp any) *openapi.Schema {
+	return &openapi.Schema{
+		Type:       "object",
+		Properties: map[string]*openapi.Schema{"data": b.doc.SchemaOf(resp)},
+		Required:   []string{"data"},
+	}
+}
+
+func pngResponse() openapi.Response {
+	return openapi.Response{
+		Description: "QR Code PIX",
+		Content:     map[string]openapi.MediaType{"image/png": {Schema: &openapi.Schema{Type: "string", Format: "binary"}}},
+	}
+}
+
+// searchParameters: filtros aceitos por /search e /api/v1/search
+func searchParameters() []openapi.Parameter {
+	str := func() *openapi.Schema { return &openapi.Schema{Type: "string"} }
+	return []openapi.Parameter{
+		{Name: "q", In: "query", Schema: str()},
+		{Name: "min_price", In: "query", Schema: str()}, // em reais, "10,50"
+		{Name: "max_price", In: "query", Schema: str()},
+		{Name: "in_stock", In: "query", Schema: &openapi.Schema{Type: "boolean"}},
+		{Name: "size", In: "query", Schema: str()},
+		{Name: "category", In: "query", Schema: str()}, // slug
+		{Name: "sort", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []any{
+			string(repository.SortRelevance), string(repository.SortPriceAsc), string(repository.SortPriceDesc), string(repository.SortNewest),
+		}}},
+		{Name: "page", In: "query", Schema: &openapi.Schema{Type: "integer"}},
+	}
+}
+
+// OpenAPISpec descreve todas as rotas registradas em routes.NewRouter.
+// Ao criar uma rota nova, descreva-a aqui (o teste das rotas cobra isso).
+func OpenAPISpec() *openapi.Document {
+	doc := openapi.New("go-ecommerce", "1.0.0")
+	doc.Info.Description = "Loja: páginas HTML (sessão por cookie + CSRF), API JSON para o app (/api/v1, token Bearer) e webhooks do provedor de pagamento."
+
+	doc.Components.SecuritySchemes[securityBearer] = openapi.SecurityScheme{Type: "http", Scheme: "bearer"}
+	doc.Components.SecuritySchemes[securityCookie] = openapi.SecurityScheme{Type: "apiKey", In: "cookie", Name: SessionCookieName}
+	doc.Components.SecuritySchemes[securityWebhook] = openapi.SecurityScheme{Type: "apiKey", In: "header", Name: "X-Signature"}
+
+	doc.Enum(reflect.TypeOf(models.OrderStatus("")),
+		models.OrderStatusPending, models.OrderStatusPaid, models.OrderStatusPicking, models.OrderStatusShipped,
+		models.OrderStatusDelivered, models.OrderStatusCancelled, models.OrderStatusRefunded, models.OrderStatusExpired)
+	doc.Enum(reflect.TypeOf(service.CardBrand("")),
+		service.CardBrandVisa, service.CardBrandMastercard, service.CardBrandAmex, service.CardBrandElo,
+		service.CardBrandHipercard, service.CardBrandDiners, service.CardBrandDiscover, service.CardBrandJCB)
+
+	b := specBuilder{doc: doc}
+
+	// --- Infra ---
+	doc.Add(http.MethodGet, "/static/{path}", &openapi.Operation{
+		Summary:   "Arquivos estáticos (CSS, JS, imagens)",
+		Tags:      []string{"infra"},
+		Responses: map[string]openapi.Response{"200": {Description: "Arquivo"}, "404": {Description: "Não encontrado"}},
+	})
+	doc.Add(http.MethodGet, "/healthz", &openapi.Operation{
+		Summary:   "Liveness: o processo está respondendo",
+		Tags:      []string{"infra"},
+		Responses: map[string]openapi.Response{"200": {Description: "Vivo", Content: openapi.JSON(doc.SchemaOf(healthResponse{}))}},
+	})
+	doc.Add(http.MethodGet, "/readyz", &openapi.Operation{
+		Summary: "Readiness: pronto para tráfego (ping no MongoDB)",
+		Tags:    []string{"infra"},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Pronto", Content: openapi.JSON(doc.SchemaOf(healthResponse{}))},
+			"503": {Description: "Banco indisponível", Content: openapi.JSON(doc.SchemaOf(healthResponse{}))},
+		},
+	})
+	doc.Add(http.MethodGet, "/api/openapi.json", &openapi.Operation{
+		Summary: "Este documento",
+		Tags:    []string{"infra"},
+		Responses: map[string]openapi.Response{"200": {
+			Description: "Documento OpenAPI 3",
+			Content:     openapi.JSON(&openapi.Schema{Type: "object"}),
+		}},
+	})
+
+	// --- Webhooks ---
+	doc.Add(http.MethodPost, "/webhooks/payments", &openapi.Operation{
+		Summary:     "Callback do provedor de pagamento (assinado com HMAC)",
+		Tags:        []string{"webhooks"},
+		Security:    []map[string][]string{{securityWebhook: {}}},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.SchemaOf(models.PaymentEvent{}))},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Evento recebido", Content: openapi.JSON(doc.SchemaOf(webhookResponse{}))},
+			"400": {Description: "Corpo inválido"},
+			"401": {Description: "Assinatura inválida"},
+			"500": {Description: "Falha ao processar; o provedor deve reenviar"},
+		},
+	})
+
+	// --- Páginas públicas ---
+	b.page(http.MethodGet, "/", "Vitrine", false, http.StatusOK)
+	b.page(http.MethodGet, "/product/{id}", "Detalhes do produto", false, http.StatusOK)
+	b.page(http.MethodGet, "/category/{slug}", "Produtos de uma categoria (e subcategorias), com breadcrumbs", false, http.StatusOK)
+	b.page(http.MethodGet, "/collection/{slug}", "Produtos de uma coleção", false, http.StatusOK)
+	b.page(http.MethodGet, "/search", "Busca de produtos com filtros e ordenação", false, http.StatusOK)
+	doc.Operation(http.MethodGet, "/search").Parameters = searchParameters()
+	b.page(http.MethodGet, "/register", "Formulário de cadastro", false, http.StatusOK)
+	b.page(http.MethodPost, "/register", "Cadastra um cliente", false, http.StatusSeeOther, "name", "email", "password")
+	b.page(http.MethodGet, "/login", "Formulário de login", false, http.StatusOK)
+	b.page(http.MethodPost, "/do-login", "Login (cria a sessão do cookie)", false, http.StatusSeeOther, "email", "password", "next")
+	b.page(http.MethodPost, "/logout", "Encerra a sessão", false, http.StatusSeeOther)
+
+	// --- Páginas do cliente ---
+	b.page(http.MethodGet, "/dashboard", "Minha conta e pedidos", true, http.StatusOK)
+	// Carrinho: sem sessão usa o carrinho de visitante (cookie carrinho_visitante),
+	// que vai para a conta no login/cadastro
+	b.page(http.MethodGet, "/cart", "Carrinho (do usuário ou do visitante)", false, http.StatusOK)
+	b.page(http.MethodPost, "/add-to-cart", "Adiciona um produto ao carrinho (do usuário ou do visitante)", false, http.StatusSeeOther, "id", "quantity", "sku", "size")
+	b.page(http.MethodPost, "/remove-from-cart", "Remove um item do carrinho", false, http.StatusSeeOther, "id", "sku", "size")
+	b.page(http.MethodGet, "/checkout", "Checkout", true, http.StatusOK)
+	b.page(http.MethodPost, "/checkout", "Checkout com os itens selecionados", true, http.StatusOK, "selected_items")
+	b.page(http.MethodPost, "/payment", "Escolha da forma de pagamento", true, http.StatusOK,
+		"selected_items", "name", "email", "address", "city", "zip", "shipping_method", "coupon")
+	b.page(http.MethodPost, "/purchase", "Finaliza a compra", true, http.StatusOK,
+		"selected_items", "name", "email", "address", "payment_method", "card_number", "card_name", "card_expiry", "card_cvv", "coupon", "zip", "shipping_method")
+	b.page(http.MethodPost, "/purchase/simulate/{id}", "Simula o pagamento PIX (dev ou financeiro)", true, http.StatusSeeOther)
+
+	// O carrinho atualiza a quantidade via fetch com JSON (não é formulário)
+	doc.Add(http.MethodPost, "/update-cart", &openapi.Operation{
+		Summary:     "Atualiza a quantidade de um item (limitada ao estoque)",
+		Tags:        []string{"páginas"},
+		Security:    []map[string][]string{{securityCookie: {}}, {}}, // <--- visitante também
+		Parameters:  []openapi.Parameter{{Name: "X-CSRF-Token", In: "header", Required: true, Schema: &openapi.Schema{Type: "string"}}},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.SchemaOf(updateCartRequest{}))},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Quantidade atualizada", Content: openapi.JSON(doc.SchemaOf(updateCartResponse{}))},
+			"400": {Description: "JSON inválido ou produto fora de estoque", Content: openapi.JSON(doc.SchemaOf(updateCartResponse{}))},
+			"403": {Description: "Token CSRF inválido"},
+			"500": {Description: "Erro ao atualizar", Content: openapi.JSON(doc.SchemaOf(updateCartResponse{}))},
+		},
+	})
+	doc.Add(http.MethodPost, "/shipping/quote", &openapi.Operation{
+		Summary:  "Cota o frete dos itens selecionados para o CEP (checkout)",
+		Tags:     []string{"páginas"},
+		Security: []map[string][]string{{securityCookie: {}}},
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/x-www-form-urlencoded": {Schema: &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+				"zip":            {Type: "string"},
+				"selected_items": {Type: "string"},
+				"csrf_token":     {Type: "string"},
+			}}},
+		}},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Opções de entrega (vazia se a loja não tem frete configurado)", Content: openapi.JSON(doc.SchemaOf(shippingQuoteResponse{}))},
+			"403": {Description: "Token CSRF inválido"},
+			"422": {Description: "CEP inválido ou sem entrega", Content: openapi.JSON(doc.SchemaOf(shippingQuoteResponse{}))},
+			"500": {Description: "Erro ao cotar", Content: openapi.JSON(doc.SchemaOf(shippingQuoteResponse{}))},
+		},
+	})
+	doc.Add(http.MethodGet, "/pix/{id}.png", &openapi.Operation{
+		Summary:   "QR Code PIX de um pedido do usuário logado",
+		Tags:      []string{"páginas"},
+		Security:  []map[string][]string{{securityCookie: {}}},
+		Responses: map[string]openapi.Response{"200": pngResponse(), "404": {Description: "Pedido não encontrado"}},
+	})
+
+	// --- Admin ---
+	b.page(http.MethodGet, "/admin/dashboard", "Catálogo", true, http.StatusOK)
+	b.page(http.MethodPost, "/admin/create", "Cria um produto", true, http.StatusSeeOther,
+		"name", "price", "stock", "image_url", "sizes", "description", "category_ids",
+		"weight_grams", "length_cm", "width_cm", "height_cm")
+	b.page(http.MethodGet, "/admin/edit/product/{product_id}", "Formulário de edição de produto", true, http.StatusOK)
+	b.page(http.MethodPost, "/admin/edit/product", "Salva a edição de um produto", true, http.StatusSeeOther,
+		"id", "name", "price", "stock", "image_url", "sizes", "description", "category_ids",
+		"weight_grams", "length_cm", "width_cm", "height_cm",
+		"variant_sku", "variant_options", "variant_stock", "variant_price", "variant_image")
+	b.page(http.MethodPost, "/admin/delete/product/{id}", "Exclui um produto", true, http.StatusSeeOther)
+	b.page(http.MethodGet, "/admin/categories", "Categorias e coleções", true, http.StatusOK)
+	b.page(http.MethodPost, "/admin/categories", "Cria uma categoria", true, http.StatusSeeOther, "name", "slug", "parent_id")
+	b.page(http.MethodPost, "/admin/delete/category/{id}", "Exclui uma categoria sem subcategorias", true, http.StatusSeeOther)
+	b.page(http.MethodPost, "/admin/collections", "Cria uma coleção", true, http.StatusSeeOther, "name", "slug", "description", "product_ids")
+	b.page(http.MethodPost, "/admin/edit/collection", "Salva uma coleção", true, http.StatusSeeOther, "id", "name", "slug", "description", "product_ids")
+	b.page(http.MethodPost, "/admin/delete/collection/{id}", "Exclui uma coleção", true, http.StatusSeeOther)
+	b.page(http.MethodGet, "/admin/promotions", "Promoções e cupons", true, http.StatusOK)
+	b.page(http.MethodPost, "/admin/promotions", "Cria um cupom ou promoção automática", true, http.StatusSeeOther,
+		"name", "code", "kind", "percent", "amount", "buy_quantity", "get_quantity", "tiers", "category_ids",
+		"min_subtotal", "starts_at", "ends_at", "max_uses", "max_uses_per_customer", "stackable", "active")
+	b.page(http.MethodPost, "/admin/promotions/{id}/active", "Liga ou desliga uma promoção", true, http.StatusSeeOther, "active")
+	b.page(http.MethodPost, "/admin/delete/promotion/{id}", "Exclui uma promoção", true, http.StatusSeeOther)
+	b.page(http.MethodGet, "/admin/shipping", "Formas de entrega e tabelas de frete", true, http.StatusOK)
+	b.page(http.MethodPost, "/admin/shipping", "Cria uma forma de entrega", true, http.StatusSeeOther,
+		"name", "kind", "handling_days", "pickup_address", "rates", "active")
+	b.page(http.MethodPost, "/admin/shipping/{id}/active", "Liga ou desliga uma forma de entrega", true, http.StatusSeeOther, "active")
+	b.page(http.MethodPost, "/admin/delete/shipping/{id}", "Exclui uma forma de entrega", true, http.StatusSeeOther)
+	b.page(http.MethodGet, "/admin/orders", "Pedidos", true, http.StatusOK)
+	b.page(http.MethodPost, "/admin/orders/{id}/status", "Muda o status de um pedido", true, http.StatusSeeOther, "status")
+
+	// --- API JSON ---
+	b.api(http.MethodGet, "/api/v1/products", "Lista os produtos", false, nil, http.StatusOK, []models.Product{})
+	b.api(http.MethodGet, "/api/v1/products/{id}", "Detalhes de um produto", false, nil, http.StatusOK, models.Product{},
+		http.StatusNotFound)
+	b.api(http.MethodGet, "/api/v1/search", "Busca de produtos com filtros, ordenação e destaques", false, nil, http.StatusOK, apiSearchResponse{},
+		http.StatusBadRequest)
+	doc.Operation(http.MethodGet, "/api/v1/search").Parameters = searchParameters()
+	b.api(http.MethodPost, "/api/v1/auth/login", "Login; devolve o token Bearer", false, apiLoginRequest{}, http.StatusOK, apiLoginResponse{},
+		http.StatusBadRequest, http.StatusUnauthorized)
+	b.api(http.MethodPost, "/api/v1/auth/register", "Cadastro; devolve o token Bearer", false, apiRegisterRequest{}, http.StatusCreated, apiLoginResponse{},
+		http.StatusBadRequest, http.StatusConflict)
+	b.api(http.MethodPost, "/api/v1/auth/logout", "Revoga o token atual", true, nil, http.StatusNoContent, nil)
+	b.api(http.MethodGet, "/api/v1/account", "Conta e pedidos do usuário", true, nil, http.StatusOK, apiAccount{})
+	b.api(http.MethodGet, "/api/v1/cart", "Carrinho com estoque e preços atuais (changed avisa se algo mudou)", true, nil, http.StatusOK, apiCart{})
+	b.api(http.MethodPost, "/api/v1/cart/items", "Adiciona um item ao carrinho (soma à linha existente, limitada ao estoque)", true, apiCartItemRequest{}, http.StatusCreated, apiCart{},
+		http.StatusBadRequest, http.StatusNotFound, http.StatusConflict)
+	b.api(http.MethodPut, "/api/v1/cart/items/{product_id}", "Muda a quantidade (limitada ao estoque)", true, apiCartQuantityRequest{}, http.StatusOK, apiCart{},
+		http.StatusBadRequest, http.StatusNotFound, http.StatusConflict)
+	b.api(http.MethodDelete, "/api/v1/cart/items/{product_id}", "Remove um item do carrinho", true, nil, http.StatusOK, apiCart{})
+	doc.Operation(http.MethodDelete, "/api/v1/cart/items/{product_id}").Parameters = append(
+		doc.Operation(http.MethodDelete, "/api/v1/cart/items/{product_id}").Parameters,
+		openapi.Parameter{Name: "sku", In: "query", Schema: &openapi.Schema{Type: "string"}},
+		openapi.Parameter{Name: "size", In: "query", Schema: &openapi.Schema{Type: "string"}},
+	)
+	b.api(http.MethodPost, "/api/v1/shipping/quote", "Cota as formas de entrega do carrinho para o CEP (lista vazia = loja sem frete)", true, apiShippingQuoteRequest{}, http.StatusOK, []models.ShippingQuote{},
+		http.StatusBadRequest, http.StatusUnprocessableEntity)
+	b.api(http.MethodPost, "/api/v1/checkout", "Finaliza a compra dos itens selecionados", true, apiCheckoutRequest{}, http.StatusCreated, apiCheckoutResponse{},
+		http.StatusBadRequest, http.StatusPaymentRequired, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusBadGateway)
+	b.api(http.MethodGet, "/api/v1/orders", "Pedidos do usuário", true, nil, http.StatusOK, []models.Order{})
+	b.api(http.MethodGet, "/api/v1/orders/{id}", "Um pedido do usuário", true, nil, http.StatusOK, models.Order{},
+		http.StatusNotFound)
+	b.api(http.MethodGet, "/api/v1/orders/{id}/pix.png", "QR Code PIX do pedido", true, nil, http.StatusOK, nil,
+		http.StatusNotFound, http.StatusConflict)
+	doc.Operation(http.MethodGet, "/api/v1/orders/{id}/pix.png").Responses["200"] = pngResponse()
+
+	return doc
+}
+
+var openAPIJSON = sync.OnceValues(func() ([]byte, error) {
+	return json.MarshalIndent(OpenAPISpec(), "", "  ")
+})
+
+// OpenAPIHandler serve o documento em /api/openapi.json
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := openAPIJSON()
+	if err != nil {
+		http.Error(w, "erro ao gerar o documento", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/service"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// --- ADMIN: PROMOÇÕES E CUPONS ---
+
+func (h *StoreHandler) AdminPromotionsHandler(w http.ResponseWriter, r *http.Request) {
+	promotions, err := h.Service.GetPromotions(r.Context())
+	if err != nil {
+		http.Error(w, "Erro ao carregar promoções", 500)
+		return
+	}
+	categories, err := h.Service.GetCategoryTree(r.Context())
+	if err != nil {
+		http.Error(w, "Erro ao carregar categorias", 500)
+		return
+	}
+
+	data := map[string]any{
+		"Promotions": promotions,
+		"Categories": categories,
+		"Kinds":      models.PromotionKinds,
+		"Now":        time.Now(),
+	}
+	RenderTemplate(w, r, "admin_promotions.html", data)
+}
+
+// writePromotionFormError: erro de validação é 400, o resto 500
+func writePromotionFormError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPromotion), errors.Is(err, service.ErrCategoryNotFound),
+		errors.Is(err, service.ErrPromotionNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Erro ao salvar: "+err.Error(), 500)
+	}
+}
+
+func (h *StoreHandler) AdminCreatePromotionHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	promotion, err := parsePromotionForm(r)
+	if err != nil {
+		writePromotionFormError(w, err)
+		return
+	}
+	if err := h.Service.CreatePromotion(r.Context(), promotion); err != nil {
+		writePromotionFormError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/admin/promotions", http.StatusSeeOther)
+}
+
+// AdminSetPromotionActiveHandler liga/desliga a promoção (active=1 ou 0)
+func (h *StoreHandler) AdminSetPromotionActiveHandler(w http.ResponseWriter, r *http.Request) {
+	err := h.Service.SetPromotionActive(r.Context(), chi.URLParam(r, "id"), r.FormValue("active") == "1")
+	if err != nil {
+		writePromotionFormError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/admin/promotions", http.StatusSeeOther)
+}
+
+func (h *StoreHandler) AdminDeletePromotionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.DeletePromotion(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writePromotionFormError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/admin/promotions", http.StatusSeeOther)
+}
+
+// parsePromotionForm lê o formulário do admin; valores em reais viram centavos.
+// As regras de negócio ficam no service (CreatePromotion).
+func parsePromotionForm(r *http.Request) (models.Promotion, error) {
+	invalid := func(field string) error { return fmt.Errorf("%w: %s", service.ErrInvalidPromotion, field) }
+
+	p := models.Promotion{
+		Name:      r.FormValue("name"),
+		Code:      r.FormValue("code"),
+		Kind:      models.PromotionKind(r.FormValue("kind")),
+		Stackable: r.FormValue("stackable") != "",
+		Active:    r.FormValue("active") != "",
+	}
+
+	ints := []struct {
+		field, label string
+		dst          *int
+	}{
+		{"percent", "percentual", &p.Percent},
+		{"buy_quantity", "quantidade paga", &p.BuyQuantity},
+		{"get_quantity", "quantidade grátis", &p.GetQuantity},
+		{"max_uses", "limite total", &p.MaxUses},
+		{"max_uses_per_customer", "limite por cliente", &p.MaxUsesPerCustomer},
+	}
+	for _, f := range ints {
+		if v := strings.TrimSpace(r.FormValue(f.field)); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return p, invalid(f.label)
+			}
+			*f.dst = n
+		}
+	}
+
+	var err error
+	if p.Amount, err = parseReais(r.FormValue("amount")); err != nil {
+		return p, invalid("valor do desconto")
+	}
+	if p.MinSubtotal, err = parseReais(r.FormValue("min_subtotal")); err != nil {
+		return p, invalid("pedido mínimo")
+	}
+	if p.Tiers, err = parseTiers(r.FormValue("tiers")); err != nil {
+		return p, invalid(err.Error())
+	}
+	if p.StartsAt, err = parseFormTime(r.FormValue("starts_at")); err != nil {
+		return p, invalid("início")
+	}
+	if p.EndsAt, err = parseFormTime(r.FormValue("ends_at")); err != nil {
+		return p, invalid("fim")
+	}
+
+	for _, idStr := range r.Form["category_ids"] {
+		id, err := primitive.ObjectIDFromHex(idStr)
+		if err != nil {
+			return p, invalid("categoria")
+		}
+		p.CategoryIDs = append(p.CategoryIDs, id)
+	}
+	return p, nil
+}
+
+// parseTiers lê uma faixa por linha: "200 = 10%" ou "300 = 25,00"
+func parseTiers(text string) ([]models.PromotionTier, error) {
+	var tiers []models.PromotionTier
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		minStr, valueStr, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("faixa %q", line)
+		}
+
+		var tier models.PromotionTier
+		var err error
+		if tier.MinSubtotal, err = parseReais(minStr); err != nil {
+			return nil, fmt.Errorf("faixa %q", line)
+		}
+		valueStr = strings.TrimSpace(valueStr)
+		if percent, isPercent := strings.CutSuffix(valueStr, "%"); isPercent {
+			tier.Percent, err = strconv.Atoi(strings.TrimSpace(percent))
+		} else {
+			tier.Amount, err = parseReais(valueStr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("faixa %q", line)
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
+
+// parseFormTime lê o <input type="datetime-local"> no fuso do servidor; vazio é nil
+func parseFormTime(v string) (*time.Time, error) {
+	if strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04", v, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/service"
+)
+
+// Tamanho máximo do trecho da descrição nos resultados
+const searchSnippetLength = 160
+
+// parseSearchQuery lê os parâmetros de /search e /api/v1/search:
+// q, min_price e max_price (em reais, "10,50"), in_stock, size, category, sort e page
+func parseSearchQuery(r *http.Request) (service.SearchQuery, error) {
+	v := r.URL.Query()
+	q := service.SearchQuery{
+		Text:     v.Get("q"),
+		Size:     v.Get("size"),
+		Category: v.Get("category"),
+		Sort:     repository.SearchSort(v.Get("sort")),
+	}
+
+	var err error
+	if q.MinPrice, err = parseReais(v.Get("min_price")); err != nil {
+		return q, fmt.Errorf("%w: preço mínimo", service.ErrInvalidSearch)
+	}
+	if q.MaxPrice, err = parseReais(v.Get("max_price")); err != nil {
+		return q, fmt.Errorf("%w: preço máximo", service.ErrInvalidSearch)
+	}
+	switch v.Get("in_stock") {
+	case "", "0", "false":
+	default:
+		q.InStock = true
+	}
+	if p := v.Get("page"); p != "" {
+		if q.Page, err = strconv.Atoi(p); err != nil {
+			return q, fmt.Errorf("%w: página", service.ErrInvalidSearch)
+		}
+	}
+	return q, nil
+}
+
+// parseReais converte "10,50" ou "10.50" em centavos; vazio é 0 (sem limite)
+func parseReais(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	if err != nil || f < 0 {
+		return 0, errors.New("valor inválido")
+	}
+	return int64(math.Round(f * 100)), nil
+}
+
+// searchHit é um produto com o nome e a descrição já destacados
+type searchHit struct {
+	models.Product
+	NameHTML    template.HTML
+	SnippetHTML template.HTML
+}
+
+// searchPageURL monta o link para outra página mantendo os filtros
+func searchPageURL(r *http.Request, page int) string {
+	v := r.URL.Query()
+	v.Set("page", strconv.Itoa(page))
+	return (&url.URL{Path: "/search", RawQuery: v.Encode()}).String()
+}
+
+// --- BUSCA ---
+
+func (h *StoreHandler) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.Service.GetCategoryTree(r.Context())
+	if err != nil {
+		http.Error(w, "Erro ao carregar categorias", 500)
+		return
+	}
+	data := map[string]any{
+		"Categories": categories,
+		"Params":     r.URL.Query(), // <--- o formulário de filtros reaproveita a URL
+	}
+
+	q, err := parseSearchQuery(r)
+	var result *service.SearchResult
+	if err == nil {
+		result, err = h.Service.SearchProducts(r.Context(), q)
+	}
+	switch {
+	case errors.Is(err, service.ErrInvalidSearch), errors.Is(err, service.ErrCategoryNotFound):
+		w.WriteHeader(http.StatusBadRequest)
+		data["Error"] = err.Error()
+		RenderTemplate(w, r, "search.html", data)
+		return
+	case err != nil:
+		http.Error(w, "Erro ao buscar produtos", 500)
+		return
+	}
+
+	hits := make([]searchHit, len(result.Products))
+	for i, p := range result.Products {
+		hits[i] = searchHit{
+			Product:     p,
+			NameHTML:    template.HTML(service.Highlight(p.Name, result.Terms)),
+			SnippetHTML: template.HTML(service.Snippet(p.Description, result.Terms, searchSnippetLength)),
+		}
+	}
+
+	data["Sort"] = string(result.Query.Sort)
+	data["Hits"] = hits
+	data["Total"] = result.Total
+	data["Page"] = result.Query.Page
+	data["Pages"] = result.Pages
+	if result.Query.Page > 1 {
+		data["PrevURL"] = searchPageURL(r, result.Query.Page-1)
+	}
+	if result.Query.Page < result.Pages {
+		data["NextURL"] = searchPageURL(r, result.Query.Page+1)
+	}
+	RenderTemplate(w, r, "search.html", data)
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// --- COTAÇÃO DE FRETE (checkout) ---
+
+// shippingOption é uma linha da cotação já pronta para o JS do checkout
+type shippingOption struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Cost          int64  `json:"cost"`
+	FormattedCost string `json:"formatted_cost"`
+	Deadline      string `json:"deadline"`
+	PickupAddress string `json:"pickup_address,omitempty"`
+}
+
+type shippingQuoteResponse struct {
+	Options []shippingOption `json:"options"`
+	Error   string           `json:"error,omitempty"`
+}
+
+func writeShippingQuote(w http.ResponseWriter, status int, resp shippingQuoteResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ShippingQuoteHandler cota o frete dos itens selecionados para o CEP
+// (botão "Calcular frete" do checkout). Sem formas configuradas, options vem vazio.
+func (h *StoreHandler) ShippingQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	_, review, err := h.Service.ReviewUserCart(r.Context(), CurrentUser(r).ID.Hex())
+	if err != nil {
+		writeShippingQuote(w, http.StatusInternalServerError, shippingQuoteResponse{Error: "Erro ao carregar o carrinho"})
+		return
+	}
+	items, _ := review.Selected(r.Form["selected_items"])
+
+	quotes, err := h.Service.ShippingQuotes(r.Context(), items, r.FormValue("zip"))
+	if isShippingError(err) {
+		writeShippingQuote(w, http.StatusUnprocessableEntity, shippingQuoteResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		writeShippingQuote(w, http.StatusInternalServerError, shippingQuoteResponse{Error: "Erro ao calcular o frete"})
+		return
+	}
+
+	resp := shippingQuoteResponse{Options: []shippingOption{}}
+	for _, q := range quotes {
+		resp.Options = append(resp.Options, shippingOption{
+			ID:            q.MethodID.Hex(),
+			Name:          q.Name,
+			Cost:          q.Cost,
+			FormattedCost: q.FormattedCost(),
+			Deadline:      q.Deadline(),
+			PickupAddress: q.PickupAddress,
+		})
+	}
+	writeShippingQuote(w, http.StatusOK, resp)
+}
+
+// isShippingError: CEP inválido ou sem entrega; o cliente pode corrigir
+func isShippingError(err error) bool {
+	return errors.Is(err, service.ErrInvalidZip) || errors.Is(err, service.ErrShippingUnavailable) ||
+		errors.Is(err, service.ErrShippingMethodNotFound)
+}
+
+// --- ADMIN: FORMAS DE ENTREGA ---
+
+func (h *StoreHandler) AdminShippingHandler(w http.ResponseWriter, r *http.Request) {
+	methods, err := h.Service.GetShippingMethods(r.Context())
+	if err != nil {
+		http.Error(w, "Erro ao carregar formas de entrega", 500)
+		return
+	}
+
+	data := map[string]any{
+		"Methods": methods,
+		"Kinds":   models.ShippingMethodKinds,
+	}
+	RenderTemplate(w, r, "admin_shipping.html", data)
+}
+
+// writeShippingFormError: erro de validação é 400, o resto 500
+func writeShippingFormError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidShippingMethod), errors.Is(err, service.ErrShippingMethodNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Erro ao salvar: "+err.Error(), 500)
+	}
+}
+
+func (h *StoreHandler) AdminCreateShippingMethodHandler(w http.ResponseWriter, r *http.Request) {
+	method, err := parseShippingMethodForm(r)
+	if err != nil {
+		writeShippingFormError(w, err)
+		return
+	}
+	if err := h.Service.CreateShippingMethod(r.Context(), method); err != nil {
+		writeShippingFormError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/admin/shipping", http.StatusSeeOther)
+}
+
+// AdminSetShippingMethodActiveHandler liga/desliga a forma de entrega (active=1 ou 0)
+func (h *StoreHandler) AdminSetShippingMethodActiveHandler(w http.ResponseWriter, r *http.Request) {
+	err := h.Service.SetShippingMethodActive(r.Context(), chi.URLParam(r, "id"), r.FormValue("active") == "1")
+	if err != nil {
+		writeShippingFormError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/admin/shipping", http.StatusSeeOther)
+}
+
+func (h *StoreHandler) AdminDeleteShippingMethodHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.DeleteShippingMethod(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeShippingFormError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/admin/shipping", http.StatusSeeOther)
+}
+
+// parseShippingMethodForm lê o formulário do admin; as regras ficam no
+// service (CreateShippingMethod)
+func parseShippingMethodForm(r *http.Request) (models.ShippingMethod, error) {
+	invalid := func(field string) error { return fmt.Errorf("%w: %s", service.ErrInvalidShippingMethod, field) }
+
+	m := models.ShippingMethod{
+		Name:          r.FormValue("name"),
+		Kind:          models.ShippingMethodKind(r.FormValue("kind")),
+		PickupAddress: r.FormValue("pickup_address"),
+		Active:        r.FormValue("active") != "",
+	}
+	if v := strings.TrimSpace(r.FormValue("handling_days")); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil {
+			return m, invalid("prazo de manuseio")
+		}
+		m.HandlingDays = days
+	}
+
+	rates, err := parseRates(r.FormValue("rates"))
+	if err != nil {
+		return m, invalid(err.Error())
+	}
+	m.Rates = rates
+	return m, nil
+}
+
+// parseRates lê uma faixa por linha, no formato do RatesText:
+// "01000-000 | 19999-999 | 1000 | 25,90 | 3" (peso vazio ou 0 = sem limite)
+func parseRates(text string) ([]models.ShippingRate, error) {
+	var rates []models.ShippingRate
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		cols := strings.Split(line, "|")
+		if len(cols) != 5 {
+			return nil, fmt.Errorf("faixa %q: use CEP inicial | CEP final | peso máx. (g) | preço | prazo", line)
+		}
+		for i := range cols {
+			cols[i] = strings.TrimSpace(cols[i])
+		}
+
+		var rate models.ShippingRate
+		var err error
+		if rate.ZipFrom, err = service.NormalizeZip(cols[0]); err != nil {
+			return nil, fmt.Errorf("faixa %q: CEP inicial inválido", line)
+		}
+		if rate.ZipTo, err = service.NormalizeZip(cols[1]); err != nil {
+			return nil, fmt.Errorf("faixa %q: CEP final inválido", line)
+		}
+		if cols[2] != "" {
+			if rate.MaxWeightGrams, err = strconv.Atoi(cols[2]); err != nil {
+				return nil, fmt.Errorf("faixa %q: peso inválido", line)
+			}
+		}
+		if rate.Price, err = parseReais(cols[3]); err != nil {
+			return nil, fmt.Errorf("faixa %q: preço inválido", line)
+		}
+		if rate.Days, err = strconv.Atoi(cols[4]); err != nil {
+			return nil, fmt.Errorf("faixa %q: prazo inválido", line)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// parseDimensionsForm lê peso (g) e medidas (cm) do formulário de produto;
+// vazio é 0 (não informado)
+func parseDimensionsForm(r *http.Request) (models.Dimensions, error) {
+	var d models.Dimensions
+	fields := []struct {
+		field, invalid string
+		dst            *int
+	}{
+		{"weight_grams", "peso inválido", &d.WeightGrams},
+		{"length_cm", "comprimento inválido", &d.LengthCm},
+		{"width_cm", "largura inválida", &d.WidthCm},
+		{"height_cm", "altura inválida", &d.HeightCm},
+	}
+	for _, f := range fields {
+		v := strings.TrimSpace(r.FormValue(f.field))
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return d, errors.New(f.invalid)
+		}
+		*f.dst = n
+	}
+	return d, nil
+}
@@ -2,11 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
 	"github.com/MarcosAndradeV/go-ecommerce/internal/service"
 	"github.com/go-chi/chi/v5"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,6 +19,9 @@ import (
 
 type StoreHandler struct {
 	Service *service.StoreService
+
+	// DevMode libera o "Simular Pagamento" para clientes (ambiente de demonstração)
+	DevMode bool
 }
 
 func NewStoreHandler(s *service.StoreService) *StoreHandler {
@@ -23,14 +31,20 @@ func NewStoreHandler(s *service.StoreService) *StoreHandler {
 // --- PÁGINA INICIAL ---
 
 func (h *StoreHandler) HomeHandler(w http.ResponseWriter, r *http.Request) {
-	products, err := h.Service.GetShowcase()
+	products, err := h.Service.GetShowcase(r.Context())
 	if err != nil {
 		http.Error(w, "Erro ao carregar produtos", 500)
 		return
 	}
+	menu, err := h.Service.GetCatalogMenu(r.Context())
+	if err != nil {
+		http.Error(w, "Erro ao carregar categorias", 500)
+		return
+	}
 	// CORREÇÃO: Enviando como Mapa para o .Data.Products funcionar
 	data := map[string]any{
 		"Products": products,
+		"Menu":     menu, // <--- Categorias raiz e coleções
 	}
 	RenderTemplate(w, r, "index.html", data)
 }
@@ -39,7 +53,7 @@ func (h *StoreHandler) HomeHandler(w http.ResponseWriter, r *http.Request) {
 
 func (h *StoreHandler) ProductDetailHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
-	product, err := h.Service.GetProductDetails(idStr)
+	product, err := h.Service.GetProductDetails(r.Context(), idStr)
 	if err != nil {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
@@ -52,18 +66,33 @@ func (h *StoreHandler) ProductDetailHandler(w http.ResponseWriter, r *http.Reque
 }
 
 func (h *StoreHandler) AddToCartHandler(w http.ResponseWriter, r *http.Request) {
-	productID := r.URL.Query().Get("id")
-	quantityStr := r.URL.Query().Get("quantity")
-	size := r.URL.Query().Get("size")
+	productID := r.FormValue("id")
+	quantityStr := r.FormValue("quantity")
+	sku := r.FormValue("sku")
+	size := r.FormValue("size")
 
 	quantity, _ := strconv.Atoi(quantityStr)
 	if quantity <= 0 {
 		quantity = 1
 	}
 
-	cookie, _ := r.Cookie("sessao_loja")
+	user := CurrentUser(r)
 
-	err := h.Service.AddProductToCart(cookie.Value, productID, quantity, size)
+	var err error
+	if user != nil {
+		err = h.Service.AddProductToCart(r.Context(), user.ID.Hex(), productID, quantity, sku, size)
+	} else {
+		// Visitante: carrinho na coleção carts, achado pelo cookie assinado
+		cartID := guestCartID(r)
+		if cartID == "" {
+			cartID = service.NewGuestCartID()
+		}
+		var expiresAt time.Time
+		expiresAt, err = h.Service.AddProductToGuestCart(r.Context(), cartID, productID, quantity, sku, size)
+		if err == nil {
+			setGuestCartCookie(w, cartID, expiresAt)
+		}
+	}
 	if err != nil {
 		http.Redirect(w, r, "/?msg=error_cart", http.StatusSeeOther)
 		return
@@ -73,11 +102,17 @@ func (h *StoreHandler) AddToCartHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *StoreHandler) RemoveFromCartHandler(w http.ResponseWriter, r *http.Request) {
-	productID := r.URL.Query().Get("id")
-	size := r.URL.Query().Get("size")
-	cookie, _ := r.Cookie("sessao_loja")
+	productID := r.FormValue("id")
+	sku := r.FormValue("sku")
+	size := r.FormValue("size")
+	user := CurrentUser(r)
 
-	err := h.Service.RemoveProductFromCart(cookie.Value, productID, size)
+	var err error
+	if user != nil {
+		err = h.Service.RemoveProductFromCart(r.Context(), user.ID.Hex(), productID, sku, size)
+	} else {
+		err = h.Service.RemoveProductFromGuestCart(r.Context(), guestCartID(r), productID, sku, size)
+	}
 	if err != nil {
 		http.Redirect(w, r, "/cart?msg=error_remove", http.StatusSeeOther)
 		return
@@ -86,114 +121,132 @@ func (h *StoreHandler) RemoveFromCartHandler(w http.ResponseWriter, r *http.Requ
 	http.Redirect(w, r, "/cart", http.StatusSeeOther)
 }
 
-func (h *StoreHandler) UpdateCartHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{"success": false})
-		return
-	}
+// Contrato JSON do /update-cart (usado pelo cart.html via fetch)
+type updateCartRequest struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+	SKU       string `json:"sku,omitempty"`
+	Size      string `json:"size"`
+}
 
-	var req struct {
-		ProductID string `json:"product_id"`
-		Quantity  int    `json:"quantity"`
-		Size      string `json:"size"`
-	}
+type updateCartResponse struct {
+	Success      bool   `json:"success"`
+	Error        string `json:"error,omitempty"`
+	Quantity     int    `json:"quantity,omitempty"`
+	Stock        int    `json:"stock"`
+	IsOutOfStock bool   `json:"isOutOfStock"`
+}
 
+func writeUpdateCart(w http.ResponseWriter, status int, resp updateCartResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *StoreHandler) UpdateCartHandler(w http.ResponseWriter, r *http.Request) {
+	var req updateCartRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{"success": false})
+		writeUpdateCart(w, http.StatusBadRequest, updateCartResponse{})
 		return
 	}
 
-	cookie, _ := r.Cookie("sessao_loja")
+	user := CurrentUser(r)
 
 	// Verificar estoque disponível
-	product, err := h.Service.GetProductDetails(req.ProductID)
+	product, err := h.Service.GetProductDetails(r.Context(), req.ProductID)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]any{"success": false, "error": "Produto não encontrado"})
+		writeUpdateCart(w, http.StatusInternalServerError, updateCartResponse{Error: "Produto não encontrado"})
 		return
 	}
 
 	// Se a quantidade solicitada é maior que o estoque, limitar ao estoque disponível
-	if req.Quantity > product.Stock {
-		req.Quantity = product.Stock
+	stock := product.StockFor(req.SKU) // <--- da variante, se houver
+	if req.Quantity > stock {
+		req.Quantity = stock
 	}
 
 	// Se o estoque é zero, retornar erro
-	if product.Stock == 0 {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{
-			"success":      false,
-			"error":        "Produto fora de estoque",
-			"isOutOfStock": true,
-			"stock":        0,
+	if stock == 0 {
+		writeUpdateCart(w, http.StatusBadRequest, updateCartResponse{
+			Error:        "Produto fora de estoque",
+			IsOutOfStock: true,
 		})
 		return
 	}
 
-	err = h.Service.UpdateCartItemQuantity(cookie.Value, req.ProductID, req.Quantity, req.Size)
+	if user != nil {
+		err = h.Service.UpdateCartItemQuantity(r.Context(), user.ID.Hex(), req.ProductID, req.Quantity, req.SKU, req.Size)
+	} else {
+		err = h.Service.UpdateGuestCartItemQuantity(r.Context(), guestCartID(r), req.ProductID, req.Quantity, req.SKU, req.Size)
+	}
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]any{"success": false})
+		writeUpdateCart(w, http.StatusInternalServerError, updateCartResponse{})
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
-		"success":      true,
-		"quantity":     req.Quantity,
-		"stock":        product.Stock,
-		"isOutOfStock": product.Stock == 0,
+	writeUpdateCart(w, http.StatusOK, updateCartResponse{
+		Success:  true,
+		Quantity: req.Quantity,
+		Stock:    stock,
 	})
 }
 
 func (h *StoreHandler) ViewCartHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("sessao_loja")
-	// 1. Se não tem cookie, manda logar
-	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	current := CurrentUser(r)
+	// 1. Sem sessão: carrinho de visitante
+	if current == nil {
+		h.viewGuestCart(w, r)
 		return
 	}
 
-	user, total, err := h.Service.GetUserCart(cookie.Value)
+	// Confere os preços com o catálogo (e grava os novos) antes de mostrar
+	user, review, err := h.Service.ReviewUserCart(r.Context(), current.ID.Hex())
 	if err != nil {
-
-		http.SetCookie(w, &http.Cookie{
-			Name:   "sessao_loja",
-			MaxAge: -1,
-			Path:   "/",
-		})
+		ClearSessionCookie(w)
 		http.Redirect(w, r, "/login?msg=session_expired", http.StatusSeeOther)
 		return
 	}
 
-	// Enriquecer o carrinho com informações de estoque
-	cartWithStock, err := h.Service.EnrichCartWithStockInfo(user.Cart)
+	data := map[string]any{
+		"Cart":       review.Items,
+		"Total":      float64(review.Total()) / 100.0,
+		"Changed":    cartChanged(r, review),
+		"Changes":    changedLines(review),
+		"User":       user,
+		"IsLoggedIn": true,
+	}
+	RenderTemplate(w, r, "cart.html", data)
+}
+
+func (h *StoreHandler) viewGuestCart(w http.ResponseWriter, r *http.Request) {
+	review, err := h.Service.ReviewGuestCart(r.Context(), guestCartID(r))
 	if err != nil {
-		http.Error(w, "Erro ao carregar informações de estoque", 500)
+		http.Error(w, "Erro ao carregar o carrinho", 500)
 		return
 	}
 
 	data := map[string]any{
-		"Cart":       cartWithStock,
-		"Total":      total,
-		"User":       user,
-		"IsLoggedIn": true,
+		"Cart":    review.Items,
+		"Total":   float64(review.Total()) / 100.0,
+		"Changed": cartChanged(r, review),
+		"Changes": changedLines(review),
+		"Guest":   true, // <--- "Finalizar Compra" passa pelo login
 	}
 	RenderTemplate(w, r, "cart.html", data)
 }
 
+// cartChanged: mostra o aviso "seu carrinho mudou". A compra recusada por
+// ErrCartChanged já gravou o preço novo, então o motivo vem na URL.
+func cartChanged(r *http.Request, review *service.CartReview) bool {
+	return review.Changed || r.URL.Query().Get("msg") == "cart_changed"
+}
+
 // --- CHECKOUT E COMPRA ---
 
 func (h *StoreHandler) CheckoutPageHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, _ := r.Cookie("sessao_loja")
+	current := CurrentUser(r)
 
-	user, _, err := h.Service.GetUserCart(cookie.Value)
+	user, review, err := h.Service.ReviewUserCart(r.Context(), current.ID.Hex())
 	if err != nil {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
@@ -206,63 +259,104 @@ func (h *StoreHandler) CheckoutPageHandler(w http.ResponseWriter, r *http.Reques
 		selectedItems = r.Form["selected_items"]
 	}
 
-	// Filtra o carrinho para mostrar apenas os selecionados (se houver seleção)
-	// Se não houver seleção (acesso direto ou nada marcado), mostra tudo ou avisa
-	var total int64 = 0
-
 	// Se veio do POST mas nada foi selecionado, redireciona pro carrinho
 	if r.Method == http.MethodPost && len(selectedItems) == 0 {
 		http.Redirect(w, r, "/cart?msg=select_items", http.StatusSeeOther)
 		return
 	}
 
-	// Se é GET, assume tudo (ou nada, dependendo da regra. Vamos assumir tudo por compatibilidade)
-	// Mas o usuário pediu "selecionar quais comprar". Então GET direto pode ser "comprar tudo" ou vazio.
-	// Vamos assumir: GET = Tudo. POST = Selecionados.
+	// GET = Tudo. POST = Selecionados. Linhas sem estoque ou de produto
+	// excluído ficam de fora; os preços são os atuais.
+	finalCart, _ := review.Selected(selectedItems)
 
-	finalCart := user.Cart
-	if len(selectedItems) > 0 {
-		finalCart = nil
-		for _, item := range user.Cart {
-			for _, selID := range selectedItems {
-				if item.ProductID.Hex() == selID {
-					finalCart = append(finalCart, item)
-					break
-				}
-			}
-		}
-	}
-
-	for _, item := range finalCart {
-		total += item.Price * int64(item.Quantity)
+	// Promoções automáticas já aparecem aqui; o cupom e o frete entram na página de pagamento
+	pricing, err := h.Service.PriceOrder(r.Context(), current.ID.Hex(), finalCart, "", nil)
+	if err != nil {
+		http.Error(w, "Erro ao calcular descontos", 500)
+		return
 	}
 
 	data := map[string]any{
-		"Cart":  finalCart,
-		"Total": float64(total) / 100.0,
-		"User":  user,
+		"Cart":    finalCart,
+		"Pricing": pricing,
+		"Total":   float64(pricing.Total) / 100.0,
+		"Changed": review.Changed,
+		"Changes": changedLines(review),
+		"User":    user,
 	}
 	RenderTemplate(w, r, "checkout.html", data)
 }
 
+// changedLines: as linhas que entram no aviso "seu carrinho mudou"
+func changedLines(review *service.CartReview) []models.OrderItemWithStock {
+	var changes []models.OrderItemWithStock
+	for _, item := range review.Items {
+		if item.Change != "" {
+			changes = append(changes, item)
+		}
+	}
+	return changes
+}
+
 func (h *StoreHandler) PurchaseHandler(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 
 	// Captura novos campos
 	paymentMethod := r.FormValue("payment_method") // "pix" ou "credit_card"
-	cardNumber := r.FormValue("card_number")
-	cardCVV := r.FormValue("card_cvv")
 	selectedItems := r.Form["selected_items"]
 
 	name := r.FormValue("name")
 	email := r.FormValue("email")
 	address := r.FormValue("address")
 
-	cookie, _ := r.Cookie("sessao_loja")
+	user := CurrentUser(r)
+
+	// Cartão: valida e tokeniza aqui mesmo. Daqui pra frente só circula o token.
+	var cardToken *service.CardToken
+	if paymentMethod == models.PaymentMethodCard {
+		card := service.CardDetails{
+			Number:     service.NormalizeCardNumber(r.FormValue("card_number")),
+			HolderName: r.FormValue("card_name"),
+			CVV:        strings.TrimSpace(r.FormValue("card_cvv")),
+		}
+		month, year, err := service.ParseCardExpiry(r.FormValue("card_expiry"))
+		if err != nil {
+			http.Error(w, "Cartão inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		card.ExpMonth, card.ExpYear = month, year
+
+		if _, err := service.ValidateCard(card, time.Now()); err != nil {
+			http.Error(w, "Cartão inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		cardToken, err = h.Service.Payment.TokenizeCard(r.Context(), card)
+		if err != nil {
+			http.Error(w, "Cartão inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	// Chama o serviço atualizado
-	order, pixCode, qrCodeImg, err := h.Service.ProcessCartPurchase(cookie.Value, name, email, address, paymentMethod, cardNumber, cardCVV, selectedItems)
+	order, pixCode, qrCodeImg, err := h.Service.ProcessCartPurchase(r.Context(), user.ID.Hex(), name, email, address, paymentMethod, cardToken, selectedItems,
+		r.FormValue("coupon"), r.FormValue("zip"), r.FormValue("shipping_method"))
 
+	// Preço mudou entre a página de pagamento e a compra: volta ao carrinho
+	if errors.Is(err, service.ErrCartChanged) {
+		http.Redirect(w, r, "/cart?msg=cart_changed", http.StatusSeeOther)
+		return
+	}
+	// Cupom esgotado/expirado entre a página de pagamento e a compra
+	if isCouponError(err) || errors.Is(err, service.ErrPromotionNotFound) {
+		http.Error(w, "Erro na compra: "+err.Error(), http.StatusConflict)
+		return
+	}
+	// Forma de entrega desligada (ou o CEP deixou de ser atendido) no meio do caminho
+	if isShippingError(err) {
+		http.Error(w, "Erro na compra: "+err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Erro na compra: "+err.Error(), 500)
 		return
@@ -270,19 +364,42 @@ func (h *StoreHandler) PurchaseHandler(w http.ResponseWriter, r *http.Request) {
 
 	data := map[string]any{
 		"Order":       order, // <--- Passamos o objeto Order (com ID)
-		"PixCode":     pixCode,
+		"PaymentCode": pixCode,
 		"QRCodeImage": qrCodeImg,
-		"IsPix":       paymentMethod == "pix",
+		"IsPix":       paymentMethod == models.PaymentMethodPix,
+		"DevMode":     h.DevMode,
 	}
 
 	RenderTemplate(w, r, "success.html", data)
 }
 
+// PixQRCodeHandler serve o PNG do QR Code PIX de um pedido do usuário logado
+func (h *StoreHandler) PixQRCodeHandler(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "id")
+	user := CurrentUser(r)
+
+	png, err := h.Service.GetPixQRCode(r.Context(), user.ID.Hex(), orderID)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(png)
+}
+
 func (h *StoreHandler) SimulatePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	// Em produção quem confirma é o webhook; aqui só o financeiro pode forçar
+	if !h.DevMode && !CurrentUser(r).HasRole(models.RoleFinance) {
+		http.Error(w, "Acesso negado", http.StatusForbidden)
+		return
+	}
+
 	orderID := chi.URLParam(r, "id")
 
 	// Chama o serviço para mudar status para PAGO
-	err := h.Service.ConfirmPayment(orderID)
+	err := h.Service.ConfirmPayment(r.Context(), orderID)
 	if err != nil {
 		http.Error(w, "Erro ao simular pagamento: "+err.Error(), 500)
 		return
@@ -295,24 +412,59 @@ func (h *StoreHandler) SimulatePaymentHandler(w http.ResponseWriter, r *http.Req
 // --- ÁREA ADMIN ---
 
 func (h *StoreHandler) AdminDashboardHandler(w http.ResponseWriter, r *http.Request) {
-	if !CheckAuth(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	products, _ := h.Service.GetShowcase(r.Context())
+	categories, _ := h.Service.GetCategoryTree(r.Context())
+
+	data := map[string]any{
+		"Products":   products,
+		"Categories": categories,
+	}
+	RenderTemplate(w, r, "admin.html", data)
+}
+
+// --- ADMIN: PEDIDOS ---
+
+func (h *StoreHandler) AdminOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	orders, err := h.Service.GetAllOrders(r.Context())
+	if err != nil {
+		http.Error(w, "Erro ao carregar pedidos", 500)
 		return
 	}
-	products, _ := h.Service.GetShowcase()
+
+	// Cada pedido vai com a lista de próximos status permitidos (para o <select>)
+	type orderRow struct {
+		Order models.Order
+		Next  []models.OrderStatus
+	}
+	var rows []orderRow
+	for _, o := range orders {
+		rows = append(rows, orderRow{Order: o, Next: service.NextStatuses(o.Status)})
+	}
 
 	data := map[string]any{
-		"Products": products,
+		"Orders": rows,
 	}
-	RenderTemplate(w, r, "admin.html", data)
+	RenderTemplate(w, r, "admin_orders.html", data)
 }
 
-func (h *StoreHandler) AdminCreateProductHandler(w http.ResponseWriter, r *http.Request) {
-	if !CheckAuth(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+func (h *StoreHandler) AdminUpdateOrderStatusHandler(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "id")
+	status := models.OrderStatus(r.FormValue("status"))
+
+	err := h.Service.ChangeOrderStatus(r.Context(), orderID, status, CurrentUser(r).Email)
+	if errors.Is(err, service.ErrInvalidTransition) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if err != nil {
+		http.Error(w, "Erro ao atualizar pedido: "+err.Error(), 500)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/orders", http.StatusSeeOther)
+}
 
+func (h *StoreHandler) AdminCreateProductHandler(w http.ResponseWriter, r *http.Request) {
 	name := r.FormValue("name")
 	desc := r.FormValue("description")
 	img := r.FormValue("image_url")
@@ -330,33 +482,37 @@ func (h *StoreHandler) AdminCreateProductHandler(w http.ResponseWriter, r *http.
 		}
 	}
 
-	h.Service.CreateProduct(name, desc, img, priceInt, stock, sizes)
+	dims, err := parseDimensionsForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.ParseForm()
+	err = h.Service.CreateProduct(r.Context(), name, desc, img, priceInt, stock, sizes, nil, r.Form["category_ids"], dims)
+	if errors.Is(err, service.ErrCategoryNotFound) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
 }
 
 func (h *StoreHandler) EditProductFormHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "product_id")
-	if !CheckAuth(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
 
-	product, err := h.Service.GetProductDetails(idStr)
+	product, err := h.Service.GetProductDetails(r.Context(), idStr)
 	if err != nil {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
-	data := map[string]any{"Product": product}
+	categories, _ := h.Service.GetCategoryTree(r.Context())
+
+	data := map[string]any{"Product": product, "Categories": categories}
 	RenderTemplate(w, r, "edit.html", data)
 }
 
 func (h *StoreHandler) EditProductHandler(w http.ResponseWriter, r *http.Request) {
-	if !CheckAuth(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
-
 	idStr := r.FormValue("id")
 	id, _ := primitive.ObjectIDFromHex(idStr)
 
@@ -377,9 +533,25 @@ func (h *StoreHandler) EditProductHandler(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	h.Service.EditProduct(id, name, desc, img, priceInt, stock, sizes)
+	variants, err := parseVariantsForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	categories := r.Form["category_ids"]
+
+	dims, err := parseDimensionsForm(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	err := h.Service.EditProduct(id, name, desc, img, priceInt, stock, sizes)
+	err = h.Service.EditProduct(r.Context(), id, name, desc, img, priceInt, stock, sizes, variants, categories, dims)
+	if errors.Is(err, service.ErrInvalidVariant) || errors.Is(err, service.ErrCategoryNotFound) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		// Loga o erro no terminal para você ver o que houve
 		http.Error(w, "Erro ao atualizar produto: "+err.Error(), 500)
@@ -389,9 +561,62 @@ func (h *StoreHandler) EditProductHandler(w http.ResponseWriter, r *http.Request
 	http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
 }
 
+// parseVariantsForm lê as linhas de variantes do edit.html (campos variant_*
+// repetidos, um por linha). Linhas sem SKU são ignoradas; opções vêm como
+// "Tamanho=M; Cor=Azul" e preço vazio usa o do produto.
+func parseVariantsForm(r *http.Request) ([]models.Variant, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	skus := r.Form["variant_sku"]
+	field := func(name string, i int) string {
+		if values := r.Form[name]; i < len(values) {
+			return strings.TrimSpace(values[i])
+		}
+		return ""
+	}
+
+	var variants []models.Variant
+	for i := range skus {
+		sku := strings.TrimSpace(skus[i])
+		if sku == "" {
+			continue
+		}
+		v := models.Variant{SKU: sku, ImageURL: field("variant_image", i)}
+
+		for _, pair := range strings.Split(field("variant_options", i), ";") {
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(value) == "" {
+				if strings.TrimSpace(pair) != "" {
+					return nil, fmt.Errorf("variante %s: opção %q inválida (use Nome=Valor)", sku, strings.TrimSpace(pair))
+				}
+				continue
+			}
+			v.Options = append(v.Options, models.VariantOption{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+		}
+
+		stock, err := strconv.Atoi(field("variant_stock", i))
+		if err != nil {
+			return nil, fmt.Errorf("variante %s: estoque inválido", sku)
+		}
+		v.Stock = stock
+
+		if priceStr := field("variant_price", i); priceStr != "" {
+			priceFloat, err := strconv.ParseFloat(strings.ReplaceAll(priceStr, ",", "."), 64)
+			if err != nil {
+				return nil, fmt.Errorf("variante %s: preço inválido", sku)
+			}
+			price := int64(math.Round(priceFloat * 100))
+			v.Price = &price
+		}
+		variants = append(variants, v)
+	}
+	return variants, nil
+}
+
 func (h *StoreHandler) PaymentPageHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, _ := r.Cookie("sessao_loja")
-	user, _, err := h.Service.GetUserCart(cookie.Value)
+	current := CurrentUser(r)
+	_, review, err := h.Service.ReviewUserCart(r.Context(), current.ID.Hex())
 	if err != nil {
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
@@ -417,51 +642,70 @@ func (h *StoreHandler) PaymentPageHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Recalcular total dos itens selecionados
-	var total int64 = 0
-	var itemsToBuy []models.OrderItem
+	// Recalcular total dos itens selecionados, com os preços atuais
+	itemsToBuy, _ := review.Selected(selectedItems)
 
-	for _, item := range user.Cart {
-		for _, selID := range selectedItems {
-			if item.ProductID.Hex() == selID {
-				itemsToBuy = append(itemsToBuy, item)
-				total += item.Price * int64(item.Quantity)
-				break
-			}
-		}
+	// Frete da forma escolhida no checkout (sem escolha, a mais barata)
+	delivery, err := h.Service.ChooseShipping(r.Context(), itemsToBuy, zip, r.FormValue("shipping_method"))
+	if isShippingError(err) {
+		http.Error(w, "Entrega: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao calcular o frete", 500)
+		return
+	}
+
+	// Descontos: promoções automáticas e o cupom digitado no checkout.
+	// Cupom que não vale não impede a compra: avisa e segue sem ele.
+	var couponError string
+	pricing, err := h.Service.PriceOrder(r.Context(), current.ID.Hex(), itemsToBuy, r.FormValue("coupon"), delivery)
+	if isCouponError(err) {
+		couponError = err.Error()
+		pricing, err = h.Service.PriceOrder(r.Context(), current.ID.Hex(), itemsToBuy, "", delivery)
+	}
+	if err != nil {
+		http.Error(w, "Erro ao calcular descontos", 500)
+		return
 	}
 
 	data := map[string]any{
 		"Items":         itemsToBuy,
-		"Total":         float64(total) / 100.0,
+		"Pricing":       pricing,
+		"Total":         float64(pricing.Total) / 100.0,
+		"Coupon":        r.FormValue("coupon"),
+		"CouponError":   couponError,
 		"SelectedItems": selectedItems,
+		"Changed":       review.Changed,
+		"Changes":       changedLines(review),
 		"Shipping": map[string]string{
 			"Name":    name,
 			"Email":   email,
 			"Address": fullAddress,
+			"Zip":     zip,
 		},
 	}
 
 	RenderTemplate(w, r, "payment.html", data)
 }
 
-func (h *StoreHandler) AdminDeleteProductHandler(w http.ResponseWriter, r *http.Request) {
-	// 1. Segurança: Verifica se é Admin
-	if !CheckAuth(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
+// isCouponError: o cupom digitado não existe ou não vale para o pedido
+func isCouponError(err error) bool {
+	return errors.Is(err, service.ErrCouponNotFound) || errors.Is(err, service.ErrCouponNotApplicable) ||
+		errors.Is(err, repository.ErrPromotionLimitReached)
+}
 
-	// 2. Pega o ID da URL
+func (h *StoreHandler) AdminDeleteProductHandler(w http.ResponseWriter, r *http.Request) {
+	// 1. Pega o ID da URL
 	idStr := chi.URLParam(r, "id")
 
-	// 3. Chama o serviço para deletar
-	err := h.Service.DeleteProduct(idStr)
+	// 2. Chama o serviço para deletar
+	err := h.Service.DeleteProduct(r.Context(), idStr)
 	if err != nil {
 		http.Error(w, "Erro ao deletar produto: "+err.Error(), 500)
 		return
 	}
 
-	// 4. Redireciona de volta para o Dashboard
+	// 3. Redireciona de volta para o Dashboard
 	http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
 }
@@ -6,23 +6,27 @@ import (
 	"path/filepath"
 )
 
+// Nome exibido no layout (config.Store.Name)
+var StoreName = "Olecram Commerce"
+
 type PageData struct {
 	IsAdmin    bool
 	IsLoggedIn bool // <--- NOVO CAMPO
 	Data       any
 	Error      string
+	CSRFToken  string // <--- Reenviado em todo POST (campo csrf_token)
+	StoreName  string
 }
 
-// Verifica cookie de ADMIN
+// Verifica se o usuário da sessão faz parte da equipe (admin ou algum papel)
 func CheckAuth(r *http.Request) bool {
-	cookie, err := r.Cookie("sessao_admin")
-	return err == nil && cookie.Value == "true"
+	user := CurrentUser(r)
+	return user != nil && user.IsStaff()
 }
 
-// Verifica cookie de CLIENTE COMUM
+// Verifica se o middleware de sessão resolveu um CLIENTE COMUM
 func CheckUserLogin(r *http.Request) bool {
-	_, err := r.Cookie("sessao_loja")
-	return err == nil
+	return CurrentUser(r) != nil
 }
 
 func RenderTemplate(w http.ResponseWriter, r *http.Request, tmplName string, data any) {
@@ -36,6 +40,8 @@ func RenderTemplate(w http.ResponseWriter, r *http.Request, tmplName string, dat
 		IsAdmin:    isAdmin,
 		IsLoggedIn: isLoggedIn, // Passamos essa info pro HTML agora
 		Data:       data,
+		CSRFToken:  CSRFToken(r),
+		StoreName:  StoreName,
 	}
 
 	layout := filepath.Join("templates", "layouts", "base.html")
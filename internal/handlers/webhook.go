@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/service"
+)
+
+// Tamanho máximo aceito para o corpo de um callback
+const maxWebhookBody = 64 << 10
+
+type webhookResponse struct {
+	Received  bool `json:"received"`
+	Duplicate bool `json:"duplicate"`
+}
+
+type WebhookHandler struct {
+	Service *service.StoreService
+	Secret  string
+}
+
+func NewWebhookHandler(s *service.StoreService, secret string) *WebhookHandler {
+	return &WebhookHandler{Service: s, Secret: secret}
+}
+
+// PaymentWebhookHandler recebe os callbacks assinados do provedor de pagamento
+func (h *WebhookHandler) PaymentWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "corpo inválido", http.StatusBadRequest)
+		return
+	}
+
+	if err := service.VerifyWebhookSignature(h.Secret, r.Header.Get("X-Signature"), body, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var event models.PaymentEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+
+	duplicate, err := h.Service.HandlePaymentEvent(r.Context(), event)
+	if errors.Is(err, service.ErrInvalidTransition) {
+		// Não adianta o provedor reenviar: respondemos 200 e registramos
+		log.Printf("Webhook %s ignorado: %v", event.ID, err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro no webhook %s: %v", event.ID, err)
+		http.Error(w, "erro ao processar evento", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhookResponse{Received: true, Duplicate: duplicate})
+}
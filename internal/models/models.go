@@ -2,34 +2,172 @@ package models
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Papéis da equipe da loja. IsAdmin equivale a ter todos eles.
+const (
+	RoleAdmin         = "admin"
+	RoleCatalogEditor = "catalog-editor"
+	RoleSupport       = "support"
+	RoleFinance       = "finance"
+)
+
 type User struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty"`
-	Name         string             `bson:"name"`
-	Email        string             `bson:"email"`
-	PasswordHash string             `bson:"password_hash"`
-	IsAdmin      bool               `bson:"is_admin"`
-	CreatedAt    time.Time          `bson:"created_at"`
-	Cart         []OrderItem        `bson:"cart,omitempty"`
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name         string             `bson:"name" json:"name"`
+	Email        string             `bson:"email" json:"email"`
+	PasswordHash string             `bson:"password_hash" json:"-"`
+	IsAdmin      bool               `bson:"is_admin" json:"is_admin"`
+	Roles        []string           `bson:"roles,omitempty" json:"roles,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	Cart         []OrderItem        `bson:"cart,omitempty" json:"cart,omitempty"`
+}
+
+// GuestCart é o carrinho de quem ainda não entrou (coleção carts), achado
+// pelo cookie assinado do visitante. No login/cadastro vai para o User.Cart.
+type GuestCart struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	Cart      []OrderItem        `bson:"cart" json:"cart"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"` // <--- índice TTL
+}
+
+// HasRole: admin tem acesso a tudo; os demais só ao que está em Roles
+func (u User) HasRole(role string) bool {
+	if u.IsAdmin {
+		return true
+	}
+	for _, r := range u.Roles {
+		if r == role || r == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStaff indica se o usuário tem acesso a alguma parte do painel /admin
+func (u User) IsStaff() bool {
+	return u.IsAdmin || len(u.Roles) > 0
 }
 
 type Product struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty"`
-	Name        string             `bson:"name"`
-	Description string             `bson:"description"`
-	ImageURL    string             `bson:"image_url"`
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description" json:"description"`
+	ImageURL    string             `bson:"image_url" json:"image_url"`
 
-	Price int64 `bson:"price"`
+	Price int64 `bson:"price" json:"price"`
 
-	Stock int      `bson:"stock"`
-	Sizes []string `bson:"sizes"` // <--- Generic Size/Attribute
+	Stock int      `bson:"stock" json:"stock"` // <--- Com variantes, é a soma do estoque delas
+	Sizes []string `bson:"sizes" json:"sizes"` // <--- Generic Size/Attribute (produtos sem variantes)
+
+	// Variantes vendáveis (tamanho/cor/...), cada uma com SKU e estoque próprios
+	Variants []Variant `bson:"variants,omitempty" json:"variants,omitempty"`
+
+	// Categorias em que o produto aparece (a página de uma categoria inclui as filhas)
+	CategoryIDs []primitive.ObjectID `bson:"category_ids,omitempty" json:"category_ids,omitempty"`
+
+	Dimensions `bson:",inline"` // <--- peso e medidas da embalagem (frete)
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Dimensions é a embalagem de uma unidade do produto. Zero = não informado.
+type Dimensions struct {
+	WeightGrams int `bson:"weight_grams,omitempty" json:"weight_grams,omitempty"`
+	LengthCm    int `bson:"length_cm,omitempty" json:"length_cm,omitempty"`
+	WidthCm     int `bson:"width_cm,omitempty" json:"width_cm,omitempty"`
+	HeightCm    int `bson:"height_cm,omitempty" json:"height_cm,omitempty"`
+}
+
+// ShippingWeight é o peso cobrado pelas transportadoras: o maior entre o
+// peso real e o cúbico (C x L x A / 6000 kg, ou seja, cm³ / 6 em gramas)
+func (d Dimensions) ShippingWeight() int {
+	return max(d.WeightGrams, d.LengthCm*d.WidthCm*d.HeightCm/6)
+}
+
+// VariantOption é um eixo da variante (ex: Tamanho=M, Cor=Azul)
+type VariantOption struct {
+	Name  string `bson:"name" json:"name"`
+	Value string `bson:"value" json:"value"`
+}
+
+type Variant struct {
+	SKU     string          `bson:"sku" json:"sku"`
+	Options []VariantOption `bson:"options" json:"options"`
+	Stock   int             `bson:"stock" json:"stock"`
+
+	Price    *int64 `bson:"price,omitempty" json:"price,omitempty"` // <--- Se nil, vale o preço do produto
+	ImageURL string `bson:"image_url,omitempty" json:"image_url,omitempty"`
+}
+
+// Label junta os valores das opções ("M / Azul"); é o que aparece no carrinho
+func (v Variant) Label() string {
+	values := make([]string, 0, len(v.Options))
+	for _, o := range v.Options {
+		values = append(values, o.Value)
+	}
+	return strings.Join(values, " / ")
+}
+
+// OptionsText é o formato do formulário do admin ("Tamanho=M; Cor=Azul")
+func (v Variant) OptionsText() string {
+	pairs := make([]string, 0, len(v.Options))
+	for _, o := range v.Options {
+		pairs = append(pairs, o.Name+"="+o.Value)
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// PriceText é o preço próprio no formato do formulário ("" = preço do produto)
+func (v Variant) PriceText() string {
+	if v.Price == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", float64(*v.Price)/100)
+}
+
+func (p Product) HasVariants() bool {
+	return len(p.Variants) > 0
+}
+
+// Variant busca uma variante pelo SKU
+func (p Product) Variant(sku string) (*Variant, bool) {
+	for i := range p.Variants {
+		if p.Variants[i].SKU == sku {
+			return &p.Variants[i], true
+		}
+	}
+	return nil, false
+}
+
+// StockFor devolve o estoque de uma variante (ou do produto, se não tiver variantes)
+func (p Product) StockFor(sku string) int {
+	if !p.HasVariants() {
+		return p.Stock
+	}
+	if v, ok := p.Variant(sku); ok {
+		return v.Stock
+	}
+	return 0
+}
 
-	CreatedAt time.Time `bson:"created_at"`
-	UpdatedAt time.Time `bson:"updated_at"`
+// PriceFor aplica o preço da variante, se houver
+func (p Product) PriceFor(sku string) int64 {
+	if v, ok := p.Variant(sku); ok && v.Price != nil {
+		return *v.Price
+	}
+	return p.Price
+}
+
+func (p Product) FormattedPriceFor(sku string) string {
+	return fmt.Sprintf("R$ %.2f", float64(p.PriceFor(sku))/100)
 }
 
 func (p Product) FormattedPrice() string {
@@ -40,26 +178,139 @@ func (p Product) PriceToFloat() float64 {
 	return float64(p.Price) / 100.0
 }
 
+// InCategory indica se o produto foi atribuído diretamente à categoria
+func (p Product) InCategory(id primitive.ObjectID) bool {
+	for _, c := range p.CategoryIDs {
+		if c == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Category é um nó da árvore do catálogo (ParentID nil = raiz)
+type Category struct {
+	ID       primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name     string              `bson:"name" json:"name"`
+	Slug     string              `bson:"slug" json:"slug"` // <--- /category/{slug}
+	ParentID *primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Collection é uma vitrine montada à mão (ex: "Promoções de verão"),
+// com os produtos na ordem escolhida pelo admin
+type Collection struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name        string               `bson:"name" json:"name"`
+	Slug        string               `bson:"slug" json:"slug"` // <--- /collection/{slug}
+	Description string               `bson:"description" json:"description"`
+	ProductIDs  []primitive.ObjectID `bson:"product_ids" json:"product_ids"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// HasProduct é usado pelos checkboxes do admin
+func (c Collection) HasProduct(id primitive.ObjectID) bool {
+	for _, p := range c.ProductIDs {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
+
 type OrderItem struct {
-	ProductID   primitive.ObjectID `bson:"product_id"`
-	ProductName string             `bson:"product_name"`
+	ProductID   primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ProductName string             `bson:"product_name" json:"product_name"`
+
+	Price    int64  `bson:"price" json:"price"`
+	Quantity int    `bson:"quantity" json:"quantity"`
+	Size     string `bson:"size" json:"size"` // <--- Selected Size (com variante, o Label dela)
+	SKU      string `bson:"sku,omitempty" json:"sku,omitempty"`
+	ImageURL string `bson:"image_url" json:"image_url"`
+}
+
+// SameLine: duas linhas do carrinho são a mesma se têm o mesmo produto e o
+// mesmo SKU (ou o mesmo tamanho, quando nenhuma tem SKU)
+func (i OrderItem) SameLine(o OrderItem) bool {
+	if i.ProductID != o.ProductID {
+		return false
+	}
+	if i.SKU != "" || o.SKU != "" {
+		return i.SKU == o.SKU
+	}
+	return i.Size == o.Size
+}
+
+// LineKey identifica a linha na seleção do checkout (selected_items): o
+// produto mais o SKU, ou mais o tamanho quando não há variante. Só o ID do
+// produto levaria junto todas as variantes dele que estão no carrinho.
+func (i OrderItem) LineKey() string {
+	switch {
+	case i.SKU != "":
+		return i.ProductID.Hex() + ":sku:" + i.SKU
+	case i.Size != "":
+		return i.ProductID.Hex() + ":size:" + i.Size
+	}
+	return i.ProductID.Hex()
+}
 
-	Price    int64  `bson:"price"`
-	Quantity int    `bson:"quantity"`
-	Size     string `bson:"size"` // <--- Selected Size
-	ImageURL string `bson:"image_url"`
+// MergeCartLines junta as linhas repetidas somando as quantidades; fica a
+// primeira ocorrência (posição, preço, imagem...)
+func MergeCartLines(cart []OrderItem) []OrderItem {
+	var merged []OrderItem
+	for _, item := range cart {
+		i := slices.IndexFunc(merged, item.SameLine)
+		if i < 0 {
+			merged = append(merged, item)
+			continue
+		}
+		merged[i].Quantity += item.Quantity
+	}
+	return merged
 }
 
+// CartChange: o que mudou numa linha do carrinho desde a última vez que o
+// cliente a viu (o preço guardado na linha é o que ele viu)
+type CartChange string
+
+const (
+	CartChangePriceUp     CartChange = "price_up"
+	CartChangePriceDown   CartChange = "price_down"
+	CartChangeRemoved     CartChange = "product_removed" // produto excluído
+	CartChangeSizeRemoved CartChange = "size_removed"    // tamanho/variante não existe mais
+)
+
 type OrderItemWithStock struct {
-	ProductID   primitive.ObjectID `bson:"product_id"`
-	ProductName string             `bson:"product_name"`
+	ProductID   primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ProductName string             `bson:"product_name" json:"product_name"`
+
+	Price        int64  `bson:"price" json:"price"`
+	Quantity     int    `bson:"quantity" json:"quantity"`
+	Size         string `bson:"size" json:"size"` // <--- Selected Size
+	SKU          string `bson:"sku,omitempty" json:"sku,omitempty"`
+	ImageURL     string `bson:"image_url" json:"image_url"`
+	Stock        int    `bson:"-" json:"stock"`           // Stock disponível no banco (da variante, se houver)
+	IsOutOfStock bool   `bson:"-" json:"is_out_of_stock"` // Se está fora de estoque
+	LineKey      string `bson:"-" json:"line_key"`        // valor de selected_items (OrderItem.LineKey)
+
+	Change        CartChange `bson:"-" json:"change,omitempty"`
+	PreviousPrice int64      `bson:"-" json:"previous_price,omitempty"` // preço antes da mudança
+}
+
+// Unavailable: a linha não pode ir para o checkout (sem estoque ou o produto/tamanho sumiu)
+func (i OrderItemWithStock) Unavailable() bool {
+	return i.IsOutOfStock || i.Change == CartChangeRemoved || i.Change == CartChangeSizeRemoved
+}
 
-	Price        int64  `bson:"price"`
-	Quantity     int    `bson:"quantity"`
-	Size         string `bson:"size"` // <--- Selected Size
-	ImageURL     string `bson:"image_url"`
-	Stock        int    `bson:"-"` // Stock disponível no banco
-	IsOutOfStock bool   `bson:"-"` // Se está fora de estoque
+func (i OrderItemWithStock) FormattedPrice() string {
+	return fmt.Sprintf("R$ %.2f", float64(i.Price)/100)
+}
+
+func (i OrderItemWithStock) FormattedPreviousPrice() string {
+	return fmt.Sprintf("R$ %.2f", float64(i.PreviousPrice)/100)
 }
 
 func (i OrderItem) TotalItem() string {
@@ -72,21 +323,357 @@ func (i OrderItemWithStock) TotalItem() string {
 	return fmt.Sprintf("R$ %.2f", float64(total)/100)
 }
 
+// Formas de pagamento aceitas no checkout
+const (
+	PaymentMethodPix  = "pix"
+	PaymentMethodCard = "credit_card"
+)
+
+// OrderStatus é o estado do pedido no ciclo de vida. As transições
+// permitidas ficam na camada de serviço (service.CanTransition).
+type OrderStatus string
+
+const (
+	OrderStatusPending   OrderStatus = "AGUARDANDO_PAGAMENTO"
+	OrderStatusPaid      OrderStatus = "PAGO"
+	OrderStatusPicking   OrderStatus = "EM_SEPARACAO"
+	OrderStatusShipped   OrderStatus = "ENVIADO"
+	OrderStatusDelivered OrderStatus = "ENTREGUE"
+	OrderStatusCancelled OrderStatus = "CANCELADO"
+	OrderStatusRefunded  OrderStatus = "REEMBOLSADO"
+	OrderStatusExpired   OrderStatus = "EXPIRADO"
+)
+
+var orderStatusLabels = map[OrderStatus]string{
+	OrderStatusPending:   "Aguardando pagamento",
+	OrderStatusPaid:      "Pago",
+	OrderStatusPicking:   "Em separação",
+	OrderStatusShipped:   "Enviado",
+	OrderStatusDelivered: "Entregue",
+	OrderStatusCancelled: "Cancelado",
+	OrderStatusRefunded:  "Reembolsado",
+	OrderStatusExpired:   "Expirado",
+}
+
+// Label devolve o nome amigável do status para os templates
+func (s OrderStatus) Label() string {
+	if label, ok := orderStatusLabels[s]; ok {
+		return label
+	}
+	return string(s)
+}
+
+// OrderStatusChange registra quem mudou o status do pedido e quando
+type OrderStatusChange struct {
+	From      OrderStatus `bson:"from,omitempty" json:"from,omitempty"`
+	To        OrderStatus `bson:"to" json:"to"`
+	ChangedBy string      `bson:"changed_by" json:"changed_by"`
+	ChangedAt time.Time   `bson:"changed_at" json:"changed_at"`
+}
+
 type Order struct {
-	ID primitive.ObjectID `bson:"_id,omitempty"`
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"` // <--- Conta que fez o pedido
+
+	CustomerName    string `bson:"customer_name" json:"customer_name"`
+	CustomerEmail   string `bson:"customer_email" json:"customer_email"`
+	CustomerAddress string `bson:"customer_address,omitempty" json:"customer_address,omitempty"`
+
+	Items []OrderItem `bson:"items" json:"items"`
+
+	Total         int64               `bson:"total" json:"total"`
+	Status        OrderStatus         `bson:"status" json:"status"`
+	StatusHistory []OrderStatusChange `bson:"status_history,omitempty" json:"status_history,omitempty"`
+
+	PaymentMethod string `bson:"payment_method,omitempty" json:"payment_method,omitempty"`
+	CardBrand     string `bson:"card_brand,omitempty" json:"card_brand,omitempty"`   // <--- Só bandeira e final do cartão,
+	CardLast4     string `bson:"card_last4,omitempty" json:"card_last4,omitempty"`   //      nunca o número completo
+	PaymentID     string `bson:"payment_id,omitempty" json:"-"`                      // <--- ID da cobrança no cartão (para estorno)
+	PixPayload    string `bson:"pix_payload,omitempty" json:"pix_payload,omitempty"` // <--- BR Code "Copia e Cola"
 
-	CustomerName    string `bson:"customer_name"`
-	CustomerEmail   string `bson:"customer_email"`
-	CustomerAddress string `bson:"customer_address,omitempty"`
+	// Pedidos PIX seguram o estoque até aqui; depois disso o worker expira o pedido
+	ReservedUntil *time.Time `bson:"reserved_until,omitempty" json:"reserved_until,omitempty"`
 
-	Items []OrderItem `bson:"items"`
+	// Total = Subtotal + frete - descontos. Pedidos antigos (sem promoções) não têm Subtotal.
+	Subtotal     int64           `bson:"subtotal,omitempty" json:"subtotal,omitempty"`
+	Discounts    []OrderDiscount `bson:"discounts,omitempty" json:"discounts,omitempty"`
+	FreeShipping bool            `bson:"free_shipping,omitempty" json:"free_shipping,omitempty"`
 
-	Total  int64  `bson:"total"`
-	Status string `bson:"status"`
+	// Entrega escolhida no checkout (pedidos antigos não têm)
+	Delivery *ShippingQuote `bson:"shipping,omitempty" json:"shipping,omitempty"`
 
-	CreatedAt time.Time `bson:"created_at"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
 }
 
 func (o Order) FormattedTotal() string {
 	return fmt.Sprintf("R$ %.2f", float64(o.Total)/100)
 }
+
+func (o Order) FormattedSubtotal() string {
+	return fmt.Sprintf("R$ %.2f", float64(o.Subtotal)/100)
+}
+
+// Tipos de promoção. Com Code é um cupom; sem Code, é aplicada sozinha.
+type PromotionKind string
+
+const (
+	PromotionPercentage   PromotionKind = "percentage"    // Percent% dos itens elegíveis
+	PromotionFixedAmount  PromotionKind = "fixed_amount"  // Amount centavos (no máximo o valor dos itens)
+	PromotionFreeShipping PromotionKind = "free_shipping" // frete grátis
+	PromotionBuyXGetY     PromotionKind = "buy_x_get_y"   // a cada BuyQuantity+GetQuantity unidades, as GetQuantity mais baratas saem de graça
+	PromotionSpendTiers   PromotionKind = "spend_tiers"   // desconto da maior faixa (Tiers) atingida
+)
+
+var PromotionKinds = []PromotionKind{
+	PromotionPercentage, PromotionFixedAmount, PromotionFreeShipping, PromotionBuyXGetY, PromotionSpendTiers,
+}
+
+// Label devolve o nome amigável do tipo para os templates
+func (k PromotionKind) Label() string {
+	switch k {
+	case PromotionPercentage:
+		return "Percentual"
+	case PromotionFixedAmount:
+		return "Valor fixo"
+	case PromotionFreeShipping:
+		return "Frete grátis"
+	case PromotionBuyXGetY:
+		return "Leve X, ganhe Y"
+	case PromotionSpendTiers:
+		return "Faixas de gasto"
+	}
+	return string(k)
+}
+
+// PromotionTier: gastando MinSubtotal ou mais, ganha Percent% ou Amount centavos
+type PromotionTier struct {
+	MinSubtotal int64 `bson:"min_subtotal" json:"min_subtotal"`
+	Percent     int   `bson:"percent,omitempty" json:"percent,omitempty"`
+	Amount      int64 `bson:"amount,omitempty" json:"amount,omitempty"`
+}
+
+// Promotion é um cupom (Code) ou uma promoção automática. As regras de
+// cálculo e de combinação ficam no service (service.ApplyPromotions).
+type Promotion struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name string             `bson:"name" json:"name"`
+	Code string             `bson:"code,omitempty" json:"code,omitempty"` // <--- sempre em maiúsculas
+	Kind PromotionKind      `bson:"kind" json:"kind"`
+
+	Percent     int             `bson:"percent,omitempty" json:"percent,omitempty"`
+	Amount      int64           `bson:"amount,omitempty" json:"amount,omitempty"`
+	BuyQuantity int             `bson:"buy_quantity,omitempty" json:"buy_quantity,omitempty"`
+	GetQuantity int             `bson:"get_quantity,omitempty" json:"get_quantity,omitempty"`
+	Tiers       []PromotionTier `bson:"tiers,omitempty" json:"tiers,omitempty"`
+
+	// Só itens destas categorias (e das subcategorias); vazio = todos
+	CategoryIDs []primitive.ObjectID `bson:"category_ids,omitempty" json:"category_ids,omitempty"`
+	MinSubtotal int64                `bson:"min_subtotal,omitempty" json:"min_subtotal,omitempty"`
+
+	StartsAt *time.Time `bson:"starts_at,omitempty" json:"starts_at,omitempty"`
+	EndsAt   *time.Time `bson:"ends_at,omitempty" json:"ends_at,omitempty"`
+
+	// Limites de uso (0 = sem limite). Uses conta os pedidos que usaram.
+	MaxUses            int `bson:"max_uses,omitempty" json:"max_uses,omitempty"`
+	MaxUsesPerCustomer int `bson:"max_uses_per_customer,omitempty" json:"max_uses_per_customer,omitempty"`
+	Uses               int `bson:"uses" json:"uses"`
+
+	// Stackable: combina com as outras combináveis; senão vale sozinha
+	Stackable bool      `bson:"stackable" json:"stackable"`
+	Active    bool      `bson:"active" json:"active"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Rule descreve a regra para o admin ("10% de desconto", "leve 3, pague 2"...)
+func (p Promotion) Rule() string {
+	switch p.Kind {
+	case PromotionPercentage:
+		return fmt.Sprintf("%d%% de desconto", p.Percent)
+	case PromotionFixedAmount:
+		return fmt.Sprintf("R$ %.2f de desconto", float64(p.Amount)/100)
+	case PromotionFreeShipping:
+		return "frete grátis"
+	case PromotionBuyXGetY:
+		return fmt.Sprintf("leve %d, pague %d", p.BuyQuantity+p.GetQuantity, p.BuyQuantity)
+	case PromotionSpendTiers:
+		var tiers []string
+		for _, t := range p.Tiers {
+			tiers = append(tiers, "acima de "+t.String())
+		}
+		return strings.Join(tiers, "; ")
+	}
+	return ""
+}
+
+func (p Promotion) FormattedMinSubtotal() string {
+	return fmt.Sprintf("R$ %.2f", float64(p.MinSubtotal)/100)
+}
+
+// String: "R$ 200.00: 10%" ou "R$ 300.00: R$ 25.00"
+func (t PromotionTier) String() string {
+	if t.Percent > 0 {
+		return fmt.Sprintf("R$ %.2f: %d%%", float64(t.MinSubtotal)/100, t.Percent)
+	}
+	return fmt.Sprintf("R$ %.2f: R$ %.2f", float64(t.MinSubtotal)/100, float64(t.Amount)/100)
+}
+
+// ActiveAt: ligada e dentro da validade
+func (p Promotion) ActiveAt(now time.Time) bool {
+	if !p.Active {
+		return false
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	return p.EndsAt == nil || now.Before(*p.EndsAt)
+}
+
+// PromotionRedemption registra o uso de uma promoção por um pedido
+// (coleção promotion_redemptions); conta para o limite por cliente.
+type PromotionRedemption struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	PromotionID primitive.ObjectID `bson:"promotion_id"`
+	UserID      primitive.ObjectID `bson:"user_id"`
+	OrderID     primitive.ObjectID `bson:"order_id"`
+	CreatedAt   time.Time          `bson:"created_at"`
+}
+
+// OrderDiscount é uma linha de desconto do pedido, guardada para auditoria
+type OrderDiscount struct {
+	PromotionID  primitive.ObjectID `bson:"promotion_id" json:"promotion_id"`
+	Name         string             `bson:"name" json:"name"`
+	Code         string             `bson:"code,omitempty" json:"code,omitempty"`
+	Kind         PromotionKind      `bson:"kind" json:"kind"`
+	Amount       int64              `bson:"amount" json:"amount"` // centavos descontados
+	FreeShipping bool               `bson:"free_shipping,omitempty" json:"free_shipping,omitempty"`
+}
+
+func (d OrderDiscount) FormattedAmount() string {
+	return fmt.Sprintf("- R$ %.2f", float64(d.Amount)/100)
+}
+
+// ShippingMethodKind é o tipo de entrega
+type ShippingMethodKind string
+
+const (
+	ShippingStandard ShippingMethodKind = "standard"
+	ShippingExpress  ShippingMethodKind = "express"
+	ShippingPickup   ShippingMethodKind = "pickup" // <--- retirada na loja
+)
+
+var ShippingMethodKinds = []ShippingMethodKind{ShippingStandard, ShippingExpress, ShippingPickup}
+
+func (k ShippingMethodKind) Label() string {
+	switch k {
+	case ShippingStandard:
+		return "Normal"
+	case ShippingExpress:
+		return "Expressa"
+	case ShippingPickup:
+		return "Retirada na loja"
+	}
+	return string(k)
+}
+
+// ShippingRate é uma linha da tabela de frete: CEPs de ZipFrom a ZipTo
+// (8 dígitos, sem hífen) e peso até MaxWeightGrams (0 = sem limite)
+type ShippingRate struct {
+	ZipFrom        string `bson:"zip_from" json:"zip_from"`
+	ZipTo          string `bson:"zip_to" json:"zip_to"`
+	MaxWeightGrams int    `bson:"max_weight_grams,omitempty" json:"max_weight_grams,omitempty"`
+	Price          int64  `bson:"price" json:"price"`
+	Days           int    `bson:"days" json:"days"` // <--- prazo em dias úteis
+}
+
+// Covers: o CEP está na faixa e o peso cabe na linha
+func (r ShippingRate) Covers(zip string, weightGrams int) bool {
+	return zip >= r.ZipFrom && zip <= r.ZipTo && (r.MaxWeightGrams == 0 || weightGrams <= r.MaxWeightGrams)
+}
+
+// ShippingMethod é uma forma de entrega configurada no admin. A cotação
+// (service.QuoteShipping) usa a linha mais específica de Rates; retirada
+// sem tabela vale para qualquer CEP e é grátis.
+type ShippingMethod struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name          string             `bson:"name" json:"name"`
+	Kind          ShippingMethodKind `bson:"kind" json:"kind"`
+	Rates         []ShippingRate     `bson:"rates,omitempty" json:"rates,omitempty"`
+	HandlingDays  int                `bson:"handling_days,omitempty" json:"handling_days,omitempty"` // <--- somado ao prazo da tabela
+	PickupAddress string             `bson:"pickup_address,omitempty" json:"pickup_address,omitempty"`
+	Active        bool               `bson:"active" json:"active"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// RatesText é a tabela no formato do formulário do admin, uma linha por faixa:
+// "CEP inicial | CEP final | peso máx. (g) | preço | prazo (dias)"
+func (m ShippingMethod) RatesText() string {
+	lines := make([]string, 0, len(m.Rates))
+	for _, r := range m.Rates {
+		lines = append(lines, fmt.Sprintf("%s | %s | %d | %.2f | %d",
+			FormatZip(r.ZipFrom), FormatZip(r.ZipTo), r.MaxWeightGrams, float64(r.Price)/100, r.Days))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatZip: "01310100" vira "01310-100"
+func FormatZip(zip string) string {
+	if len(zip) != 8 {
+		return zip
+	}
+	return zip[:5] + "-" + zip[5:]
+}
+
+// ShippingQuote é uma opção de entrega cotada para um CEP. No pedido,
+// guarda a opção escolhida e o valor cobrado.
+type ShippingQuote struct {
+	MethodID      primitive.ObjectID `bson:"method_id" json:"method_id"`
+	Name          string             `bson:"name" json:"name"`
+	Kind          ShippingMethodKind `bson:"kind" json:"kind"`
+	Zip           string             `bson:"zip" json:"zip"`
+	WeightGrams   int                `bson:"weight_grams" json:"weight_grams"`
+	Cost          int64              `bson:"cost" json:"cost"`
+	Days          int                `bson:"days" json:"days"`
+	PickupAddress string             `bson:"pickup_address,omitempty" json:"pickup_address,omitempty"`
+}
+
+func (q ShippingQuote) FormattedCost() string {
+	if q.Cost == 0 {
+		return "Grátis"
+	}
+	return fmt.Sprintf("R$ %.2f", float64(q.Cost)/100)
+}
+
+// Deadline: "até 5 dias úteis" (ou o prazo para retirar)
+func (q ShippingQuote) Deadline() string {
+	switch {
+	case q.Kind == ShippingPickup && q.Days == 0:
+		return "pronto para retirar"
+	case q.Kind == ShippingPickup:
+		return fmt.Sprintf("retire em até %d dia(s) úteis", q.Days)
+	case q.Days == 0:
+		return "entrega no mesmo dia"
+	}
+	return fmt.Sprintf("até %d dia(s) úteis", q.Days)
+}
+
+// Session representa uma sessão de login persistida no servidor.
+// O cookie guarda apenas o token opaco; no banco fica só o hash dele.
+type Session struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	TokenHash  string             `bson:"token_hash"`
+	UserID     primitive.ObjectID `bson:"user_id"`
+	UserAgent  string             `bson:"user_agent,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at"`
+	LastSeenAt time.Time          `bson:"last_seen_at"`
+	ExpiresAt  time.Time          `bson:"expires_at"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty"`
+}
+
+// PaymentEvent é um callback do provedor de pagamento já processado.
+// O _id é o ID do evento no provedor, o que garante a idempotência.
+type PaymentEvent struct {
+	ID          string    `bson:"_id" json:"id"`
+	Type        string    `bson:"type" json:"type"`
+	PaymentID   string    `bson:"payment_id" json:"payment_id"`
+	ProcessedAt time.Time `bson:"processed_at" json:"-"`
+}
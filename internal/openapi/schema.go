@@ -0,0 +1,149 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// Enum registra os valores aceitos por um tipo nomeado (ex: models.OrderStatus)
+func (d *Document) Enum(t reflect.Type, values ...any) {
+	if d.enums == nil {
+		d.enums = map[reflect.Type][]any{}
+	}
+	d.enums[t] = values
+}
+
+// SchemaOf devolve o schema do valor v. Structs nomeadas viram componentes
+// em #/components/schemas e são referenciadas por $ref.
+func (d *Document) SchemaOf(v any) *Schema {
+	return d.schemaFor(reflect.TypeOf(v))
+}
+
+func (d *Document) schemaFor(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	if values, ok := d.enums[t]; ok {
+		s := &Schema{Type: "string"}
+		if t.Kind() != reflect.String {
+			s = &Schema{Type: "integer"}
+		}
+		s.Enum = values
+		return s
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case objectIDType:
+		return &Schema{Type: "string", Pattern: "^[0-9a-f]{24}$"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := d.schemaFor(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		name := componentName(t)
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// Reserva o nome antes de descer nos campos (tipos recursivos)
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	// interface{} / any: qualquer valor
+	return &Schema{}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, omitempty, skip := jsonField(f)
+		if skip {
+			continue
+		}
+
+		// Struct embutida sem nome no JSON: os campos sobem para o pai
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			inner := d.structSchema(f.Type)
+			for k, v := range inner.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, inner.Required...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		s.Properties[name] = d.schemaFor(f.Type)
+		if !omitempty && f.Type.Kind() != reflect.Pointer {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// jsonField lê a tag json do campo do mesmo jeito que o encoding/json
+func jsonField(f reflect.StructField) (name string, omitempty, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return parts[0], omitempty, false
+}
+
+// componentName usa o nome do tipo Go. Tipos internos dos handlers costumam
+// ter o prefixo "api" (apiCart), que não faz sentido no documento.
+func componentName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "api")
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
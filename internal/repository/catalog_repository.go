@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ---------------------------------------------------------
+// CATEGORIAS E COLEÇÕES
+// ---------------------------------------------------------
+
+// GetAllCategories: a árvore inteira (são poucas; o service monta a hierarquia)
+func (r *MongoStoreRepository) GetAllCategories(ctx context.Context) ([]models.Category, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetAllCategories")
+	defer cancel()
+
+	coll := r.db.Collection("categories")
+	cursor, err := coll.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var categories []models.Category
+	err = cursor.All(ctx, &categories)
+	return categories, err
+}
+
+func (r *MongoStoreRepository) CreateCategory(ctx context.Context, category models.Category) error {
+	ctx, cancel := r.Timeouts.write(ctx, "CreateCategory")
+	defer cancel()
+
+	_, err := r.db.Collection("categories").InsertOne(ctx, category)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateSlug // índice categories.slug_unique (migração 4)
+	}
+	return err
+}
+
+// DeleteCategory: Apaga a categoria e a remove dos produtos que a usavam
+func (r *MongoStoreRepository) DeleteCategory(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := r.Timeouts.write(ctx, "DeleteCategory")
+	defer cancel()
+
+	if _, err := r.db.Collection("categories").DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return err
+	}
+	_, err := r.db.Collection("products").UpdateMany(ctx,
+		bson.M{"category_ids": id},
+		bson.M{"$pull": bson.M{"category_ids": id}})
+	return err
+}
+
+func (r *MongoStoreRepository) GetProductsByCategories(ctx context.Context, ids []primitive.ObjectID) ([]models.Product, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetProductsByCategories")
+	defer cancel()
+
+	return r.findProducts(ctx, bson.M{"category_ids": bson.M{"$in": ids}})
+}
+
+func (r *MongoStoreRepository) GetProductsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Product, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetProductsByIDs")
+	defer cancel()
+
+	return r.findProducts(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (r *MongoStoreRepository) findProducts(ctx context.Context, filter bson.M) ([]models.Product, error) {
+	cursor, err := r.db.Collection("products").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var products []models.Product
+	err = cursor.All(ctx, &products)
+	return products, err
+}
+
+func (r *MongoStoreRepository) GetAllCollections(ctx context.Context) ([]models.Collection, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetAllCollections")
+	defer cancel()
+
+	coll := r.db.Collection("collections")
+	cursor, err := coll.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var collections []models.Collection
+	err = cursor.All(ctx, &collections)
+	return collections, err
+}
+
+func (r *MongoStoreRepository) GetCollectionBySlug(ctx context.Context, slug string) (*models.Collection, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetCollectionBySlug")
+	defer cancel()
+
+	var collection models.Collection
+	err := r.db.Collection("collections").FindOne(ctx, bson.M{"slug": slug}).Decode(&collection)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &collection, nil
+}
+
+func (r *MongoStoreRepository) CreateCollection(ctx context.Context, collection models.Collection) error {
+	ctx, cancel := r.Timeouts.write(ctx, "CreateCollection")
+	defer cancel()
+
+	_, err := r.db.Collection("collections").InsertOne(ctx, collection)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateSlug // índice collections.slug_unique (migração 4)
+	}
+	return err
+}
+
+func (r *MongoStoreRepository) EditCollection(ctx context.Context, id primitive.ObjectID, collection models.Collection) error {
+	ctx, cancel := r.Timeouts.write(ctx, "EditCollection")
+	defer cancel()
+
+	_, err := r.db.Collection("collections").ReplaceOne(ctx, bson.M{"_id": id}, collection)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateSlug
+	}
+	return err
+}
+
+func (r *MongoStoreRepository) DeleteCollection(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := r.Timeouts.write(ctx, "DeleteCollection")
+	defer cancel()
+
+	_, err := r.db.Collection("collections").DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,1072 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/database"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// backend reúne as implementações de um mesmo banco
+type backend struct {
+	Store    StoreRepository
+	Users    UserRepository
+	Sessions SessionRepository
+}
+
+func TestMemoryRepositories(t *testing.T) {
+	runContract(t, func(t *testing.T) backend {
+		m := NewMemoryStore()
+		return backend{Store: m, Users: m, Sessions: m}
+	})
+}
+
+// Para rodar contra o Mongo: MONGO_TEST_URI=mongodb://localhost:27017/?replicaSet=rs0
+// (transações exigem replica set). Cada teste usa um banco descartável.
+func TestMongoRepositories(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI não definido")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	runContract(t, func(t *testing.T) backend {
+		db := client.Database("ecommerce_test_" + primitive.NewObjectID().Hex())
+		t.Cleanup(func() { db.Drop(context.Background()) })
+		// Índices e validadores valem também aqui (ex: users.email único)
+		migrator, err := database.NewMigrator(db, database.Migrations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := migrator.Up(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+		return backend{Store: NewStoreRepository(db), Users: NewUserRepository(db), Sessions: NewSessionRepository(db)}
+	})
+}
+
+func runContract(t *testing.T, newBackend func(t *testing.T) backend) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, b backend)
+	}{
+		{"Products", testProducts},
+		{"DecrementStock", testDecrementStock},
+		{"ConcurrentDecrementStock", testConcurrentDecrementStock},
+		{"VariantStock", testVariantStock},
+		{"Cart", testCart},
+		{"CartVariants", testCartVariants},
+		{"CartMerge", testCartMerge},
+		{"GuestCarts", testGuestCarts},
+		{"RepriceCart", testRepriceCart},
+		{"Categories", testCategories},
+		{"Collections", testCollections},
+		{"SearchProducts", testSearchProducts},
+		{"Promotions", testPromotions},
+		{"PromotionLimits", testPromotionLimits},
+		{"ShippingMethods", testShippingMethods},
+		{"Orders", testOrders},
+		{"TransitionOrderStatus", testTransitionOrderStatus},
+		{"PaymentEvents", testPaymentEvents},
+		{"Users", testUsers},
+		{"Sessions", testSessions},
+		{"TransactionRollback", testTransactionRollback},
+		{"CancelledContext", testCancelledContext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { tt.fn(t, newBackend(t)) })
+	}
+}
+
+// Mongo guarda datas com precisão de milissegundos
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+func newProduct(t *testing.T, repo ProductRepository, stock int) models.Product {
+	t.Helper()
+	p := models.Product{
+		ID:        primitive.NewObjectID(),
+		Name:      "Camiseta",
+		Price:     4990,
+		Stock:     stock,
+		Sizes:     []string{"P", "M"},
+		CreatedAt: now(),
+	}
+	if err := repo.CreateProduct(t.Context(), p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func newUser(t *testing.T, repo UserRepository, email string) models.User {
+	t.Helper()
+	u := models.User{ID: primitive.NewObjectID(), Name: "Cliente", Email: email, PasswordHash: "x", CreatedAt: now()}
+	if err := repo.CreateUser(t.Context(), u); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func stockOf(t *testing.T, repo ProductRepository, id primitive.ObjectID) int {
+	t.Helper()
+	p, err := repo.GetProductByID(t.Context(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Stock
+}
+
+func testProducts(t *testing.T, b backend) {
+	p := newProduct(t, b.Store, 5)
+
+	got, err := b.Store.GetProductByID(t.Context(), p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != p.Name || got.Price != p.Price || !got.CreatedAt.Equal(p.CreatedAt) || len(got.Sizes) != 2 {
+		t.Errorf("produto lido = %+v, quero %+v", got, p)
+	}
+
+	p.Name = "Camiseta Azul"
+	if err := b.Store.EditProduct(t.Context(), p.ID, p); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := b.Store.GetProductByID(t.Context(), p.ID); got.Name != "Camiseta Azul" {
+		t.Errorf("nome após editar = %q", got.Name)
+	}
+
+	newProduct(t, b.Store, 1)
+	all, err := b.Store.GetAllProducts(t.Context())
+	if err != nil || len(all) != 2 {
+		t.Fatalf("GetAllProducts = %d produtos, %v", len(all), err)
+	}
+
+	if err := b.Store.DeleteProduct(t.Context(), p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Store.GetProductByID(t.Context(), p.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("produto excluído: erro = %v, quero ErrNotFound", err)
+	}
+}
+
+func testDecrementStock(t *testing.T, b backend) {
+	ctx := t.Context()
+	p := newProduct(t, b.Store, 3)
+
+	if err := b.Store.DecrementStock(ctx, p.ID, "", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Store.DecrementStock(ctx, p.ID, "", 2); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("baixar além do estoque: erro = %v", err)
+	}
+	if s := stockOf(t, b.Store, p.ID); s != 1 {
+		t.Errorf("estoque = %d, quero 1 (a baixa recusada não pode mexer)", s)
+	}
+	if err := b.Store.DecrementStock(ctx, primitive.NewObjectID(), "", 1); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("produto inexistente: erro = %v", err)
+	}
+
+	if err := b.Store.IncrementStock(ctx, p.ID, "", 4); err != nil {
+		t.Fatal(err)
+	}
+	if s := stockOf(t, b.Store, p.ID); s != 5 {
+		t.Errorf("estoque após devolver = %d, quero 5", s)
+	}
+}
+
+func testConcurrentDecrementStock(t *testing.T, b backend) {
+	const stock, buyers = 10, 25
+	p := newProduct(t, b.Store, stock)
+
+	var wg sync.WaitGroup
+	var sold atomic.Int32
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := b.Store.DecrementStock(t.Context(), p.ID, "", 1)
+			if err == nil {
+				sold.Add(1)
+			} else if !errors.Is(err, ErrInsufficientStock) {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if sold.Load() != stock {
+		t.Errorf("vendidos = %d, quero %d", sold.Load(), stock)
+	}
+	if s := stockOf(t, b.Store, p.ID); s != 0 {
+		t.Errorf("estoque final = %d, quero 0", s)
+	}
+}
+
+func testVariantStock(t *testing.T, b backend) {
+	ctx := t.Context()
+	p := models.Product{
+		ID:    primitive.NewObjectID(),
+		Name:  "Tênis",
+		Price: 19990,
+		Stock: 5,
+		Variants: []models.Variant{
+			{SKU: "TEN-38", Options: []models.VariantOption{{Name: "Tamanho", Value: "38"}}, Stock: 2},
+			{SKU: "TEN-40", Options: []models.VariantOption{{Name: "Tamanho", Value: "40"}}, Stock: 3},
+		},
+		CreatedAt: now(),
+	}
+	if err := b.Store.CreateProduct(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	other := models.Product{ID: primitive.NewObjectID(), Name: "Outro", Stock: 1, CreatedAt: now(),
+		Variants: []models.Variant{{SKU: "TEN-40", Options: []models.VariantOption{{Name: "Tamanho", Value: "40"}}, Stock: 1}}}
+	if err := b.Store.CreateProduct(ctx, other); !errors.Is(err, ErrDuplicateSKU) {
+		t.Errorf("SKU de outro produto: erro = %v, quero ErrDuplicateSKU", err)
+	}
+
+	if err := b.Store.DecrementStock(ctx, p.ID, "TEN-38", 2); err != nil {
+		t.Fatal(err)
+	}
+	// O total ainda tem 3, mas a variante acabou
+	if err := b.Store.DecrementStock(ctx, p.ID, "TEN-38", 1); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("variante esgotada: erro = %v", err)
+	}
+	if err := b.Store.DecrementStock(ctx, p.ID, "TEN-99", 1); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("SKU inexistente: erro = %v", err)
+	}
+
+	got, err := b.Store.GetProductByID(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Stock != 3 || got.StockFor("TEN-38") != 0 || got.StockFor("TEN-40") != 3 {
+		t.Errorf("estoque = %d (38: %d, 40: %d), quero 3 (0, 3)", got.Stock, got.StockFor("TEN-38"), got.StockFor("TEN-40"))
+	}
+
+	if err := b.Store.IncrementStock(ctx, p.ID, "TEN-38", 1); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = b.Store.GetProductByID(ctx, p.ID)
+	if got.Stock != 4 || got.StockFor("TEN-38") != 1 {
+		t.Errorf("após devolver: estoque = %d (38: %d), quero 4 (1)", got.Stock, got.StockFor("TEN-38"))
+	}
+}
+
+func testCart(t *testing.T, b backend) {
+	ctx := t.Context()
+	u := newUser(t, b.Users, "carrinho@example.com")
+	p1 := primitive.NewObjectID()
+	p2 := primitive.NewObjectID()
+
+	for _, item := range []models.OrderItem{
+		{ProductID: p1, Size: "P", Quantity: 1, Price: 100},
+		{ProductID: p1, Size: "M", Quantity: 1, Price: 100},
+		{ProductID: p2, Quantity: 2, Price: 300},
+	} {
+		if err := b.Store.AddItemToCart(ctx, u.ID, item, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := b.Store.UpdateCartItemQuantity(ctx, u.ID, p1, 4, "", "M"); err != nil {
+		t.Fatal(err)
+	}
+	user, err := b.Store.GetUserWithCart(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(user.Cart) != 3 || user.Cart[0].Quantity != 1 || user.Cart[1].Quantity != 4 {
+		t.Fatalf("carrinho após atualizar = %+v", user.Cart)
+	}
+
+	// Com tamanho: remove só aquele tamanho
+	if err := b.Store.RemoveItemFromCart(ctx, u.ID, p1, "", "P"); err != nil {
+		t.Fatal(err)
+	}
+	user, _ = b.Store.GetUserWithCart(ctx, u.ID)
+	if len(user.Cart) != 2 || user.Cart[0].Size != "M" {
+		t.Fatalf("carrinho após remover P = %+v", user.Cart)
+	}
+
+	if err := b.Store.RemoveItemsFromCart(ctx, u.ID, []models.OrderItem{{ProductID: p2}}); err != nil {
+		t.Fatal(err)
+	}
+	user, _ = b.Store.GetUserWithCart(ctx, u.ID)
+	if len(user.Cart) != 1 || user.Cart[0].ProductID != p1 {
+		t.Fatalf("carrinho após a compra = %+v", user.Cart)
+	}
+
+	// Sem tamanho: remove todos os itens do produto
+	b.Store.AddItemToCart(ctx, u.ID, models.OrderItem{ProductID: p1, Size: "G", Quantity: 1}, 10)
+	if err := b.Store.RemoveItemFromCart(ctx, u.ID, p1, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	user, _ = b.Store.GetUserWithCart(ctx, u.ID)
+	if len(user.Cart) != 0 {
+		t.Errorf("carrinho deveria estar vazio: %+v", user.Cart)
+	}
+
+	if _, err := b.Store.GetUserWithCart(ctx, primitive.NewObjectID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("usuário inexistente: erro = %v, quero ErrNotFound", err)
+	}
+}
+
+// Itens de variante são identificados pelo SKU, não pelo rótulo em Size
+func testCartVariants(t *testing.T, b backend) {
+	ctx := t.Context()
+	u := newUser(t, b.Users, "variantes@example.com")
+	p := primitive.NewObjectID()
+
+	for _, item := range []models.OrderItem{
+		{ProductID: p, SKU: "CAM-P-AZ", Size: "P / Azul", Quantity: 1, Price: 100},
+		{ProductID: p, SKU: "CAM-P-VD", Size: "P / Verde", Quantity: 1, Price: 100},
+		{ProductID: p, SKU: "CAM-M-AZ", Size: "M / Azul", Quantity: 1, Price: 120},
+	} {
+		if err := b.Store.AddItemToCart(ctx, u.ID, item, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := b.Store.UpdateCartItemQuantity(ctx, u.ID, p, 3, "CAM-P-VD", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Store.RemoveItemFromCart(ctx, u.ID, p, "CAM-P-AZ", ""); err != nil {
+		t.Fatal(err)
+	}
+	user, _ := b.Store.GetUserWithCart(ctx, u.ID)
+	if len(user.Cart) != 2 || user.Cart[0].SKU != "CAM-P-VD" || user.Cart[0].Quantity != 3 {
+		t.Fatalf("carrinho = %+v", user.Cart)
+	}
+
+	if err := b.Store.RemoveItemsFromCart(ctx, u.ID, []models.OrderItem{{ProductID: p, SKU: "CAM-M-AZ"}}); err != nil {
+		t.Fatal(err)
+	}
+	user, _ = b.Store.GetUserWithCart(ctx, u.ID)
+	if len(user.Cart) != 1 || user.Cart[0].SKU != "CAM-P-VD" {
+		t.Errorf("carrinho após a compra = %+v", user.Cart)
+	}
+}
+
+// Adicionar de novo a mesma linha soma a quantidade, até o máximo
+func testCartMerge(t *testing.T, b backend) {
+	ctx := t.Context()
+	u := newUser(t, b.Users, "soma@example.com")
+	p := primitive.NewObjectID()
+
+	steps := []struct {
+		item models.OrderItem
+		max  int
+	}{
+		{models.OrderItem{ProductID: p, Size: "M", Quantity: 2}, 5},
+		{models.OrderItem{ProductID: p, Size: "G", Quantity: 1}, 5},
+		{models.OrderItem{ProductID: p, Size: "M", Quantity: 2}, 5}, // M: 4
+		{models.OrderItem{ProductID: p, Size: "M", Quantity: 3}, 5}, // M: 5 (limite)
+		{models.OrderItem{ProductID: p, SKU: "CAM-M", Size: "M", Quantity: 9}, 3},
+		{models.OrderItem{ProductID: p, SKU: "CAM-M", Size: "M", Quantity: 1}, 2}, // estoque caiu
+	}
+	for _, st := range steps {
+		if err := b.Store.AddItemToCart(ctx, u.ID, st.item, st.max); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	user, _ := b.Store.GetUserWithCart(ctx, u.ID)
+	want := []struct {
+		sku, size string
+		quantity  int
+	}{{"", "M", 5}, {"", "G", 1}, {"CAM-M", "M", 2}}
+	if len(user.Cart) != len(want) {
+		t.Fatalf("carrinho = %+v", user.Cart)
+	}
+	for i, w := range want {
+		if c := user.Cart[i]; c.SKU != w.sku || c.Size != w.size || c.Quantity != w.quantity {
+			t.Errorf("linha %d = %+v, quero %+v", i, c, w)
+		}
+	}
+
+	err := b.Store.AddItemToCart(ctx, u.ID, models.OrderItem{ProductID: p, Size: "P", Quantity: 1}, 0)
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("sem estoque: erro = %v, quero ErrInsufficientStock", err)
+	}
+}
+
+func testGuestCarts(t *testing.T, b backend) {
+	ctx := t.Context()
+	id := primitive.NewObjectID()
+	p := primitive.NewObjectID()
+	expiresAt := now().Add(time.Hour)
+
+	if _, err := b.Store.GetGuestCart(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("carrinho inexistente: erro = %v, quero ErrNotFound", err)
+	}
+
+	for _, item := range []models.OrderItem{
+		{ProductID: p, Size: "M", Quantity: 2, Price: 100},
+		{ProductID: p, Size: "G", Quantity: 1, Price: 100},
+		{ProductID: p, Size: "M", Quantity: 5, Price: 100}, // soma, limitado a 4
+	} {
+		if err := b.Store.AddItemToGuestCart(ctx, id, item, 4, expiresAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Store.UpdateGuestCartItemQuantity(ctx, id, p, 3, "", "G"); err != nil {
+		t.Fatal(err)
+	}
+
+	cart, err := b.Store.GetGuestCart(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cart.Cart) != 2 || cart.Cart[0].Quantity != 4 || cart.Cart[1].Quantity != 3 || !cart.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("carrinho = %+v", cart)
+	}
+
+	if err := b.Store.RemoveItemFromGuestCart(ctx, id, p, "", "M"); err != nil {
+		t.Fatal(err)
+	}
+	cart, _ = b.Store.GetGuestCart(ctx, id)
+	if len(cart.Cart) != 1 || cart.Cart[0].Size != "G" {
+		t.Fatalf("carrinho após remover M = %+v", cart.Cart)
+	}
+
+	if err := b.Store.DeleteGuestCart(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Store.GetGuestCart(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("carrinho excluído: erro = %v, quero ErrNotFound", err)
+	}
+
+	// Expirado conta como inexistente, mesmo antes de o TTL apagar
+	old := primitive.NewObjectID()
+	if err := b.Store.AddItemToGuestCart(ctx, old, models.OrderItem{ProductID: p, Quantity: 1}, 4, now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Store.GetGuestCart(ctx, old); !errors.Is(err, ErrNotFound) {
+		t.Errorf("carrinho expirado: erro = %v, quero ErrNotFound", err)
+	}
+}
+
+func testRepriceCart(t *testing.T, b backend) {
+	ctx := t.Context()
+	u := newUser(t, b.Users, "preco@example.com")
+	guest := primitive.NewObjectID()
+	p := primitive.NewObjectID()
+
+	lines := []models.OrderItem{
+		{ProductID: p, ProductName: "Camiseta", Size: "M", Quantity: 2, Price: 4990},
+		{ProductID: p, ProductName: "Camiseta", SKU: "CAM-G", Size: "G", Quantity: 1, Price: 5990},
+	}
+	for _, item := range lines {
+		if err := b.Store.AddItemToCart(ctx, u.ID, item, 10); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Store.AddItemToGuestCart(ctx, guest, item, 10, now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Só a linha do SKU muda; a do tamanho M fica como estava
+	repriced := models.OrderItem{ProductID: p, ProductName: "Camiseta Nova", SKU: "CAM-G", Size: "G", Price: 6490, ImageURL: "/g.png"}
+	if err := b.Store.RepriceCartItem(ctx, u.ID, repriced); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Store.RepriceGuestCartItem(ctx, guest, repriced); err != nil {
+		t.Fatal(err)
+	}
+
+	user, _ := b.Store.GetUserWithCart(ctx, u.ID)
+	cart, _ := b.Store.GetGuestCart(ctx, guest)
+	for name, got := range map[string][]models.OrderItem{"usuário": user.Cart, "visitante": cart.Cart} {
+		if len(got) != 2 || got[0].Price != 4990 || got[0].ProductName != "Camiseta" || got[0].Quantity != 2 {
+			t.Errorf("%s: linha M = %+v", name, got)
+			continue
+		}
+		if g := got[1]; g.Price != 6490 || g.ProductName != "Camiseta Nova" || g.ImageURL != "/g.png" || g.Quantity != 1 {
+			t.Errorf("%s: linha G = %+v", name, g)
+		}
+	}
+}
+
+func testPromotions(t *testing.T, b backend) {
+	ctx := t.Context()
+	base := now()
+
+	auto := models.Promotion{ID: primitive.NewObjectID(), Name: "10% em tudo", Kind: models.PromotionPercentage, Percent: 10, Active: true, CreatedAt: base.Add(-time.Hour)}
+	coupon := models.Promotion{ID: primitive.NewObjectID(), Name: "Boas-vindas", Code: "BEMVINDO", Kind: models.PromotionFixedAmount, Amount: 1000, Active: true, CreatedAt: base}
+	for _, p := range []models.Promotion{auto, coupon} {
+		if err := b.Store.CreatePromotion(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Várias automáticas (sem código) convivem; o código é único
+	if err := b.Store.CreatePromotion(ctx, models.Promotion{ID: primitive.NewObjectID(), Name: "Outra", Kind: models.PromotionFreeShipping, CreatedAt: base.Add(-2 * time.Hour)}); err != nil {
+		t.Fatalf("segunda automática: %v", err)
+	}
+	err := b.Store.CreatePromotion(ctx, models.Promotion{ID: primitive.NewObjectID(), Name: "Cópia", Code: "BEMVINDO", Kind: models.PromotionFreeShipping, CreatedAt: base})
+	if !errors.Is(err, ErrDuplicateCode) {
+		t.Errorf("código repetido: erro = %v, quero ErrDuplicateCode", err)
+	}
+
+	all, err := b.Store.GetAllPromotions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].ID != coupon.ID || all[1].ID != auto.ID {
+		t.Fatalf("promoções = %+v", all)
+	}
+
+	got, err := b.Store.GetPromotionByCode(ctx, "BEMVINDO")
+	if err != nil || got.ID != coupon.ID || got.Amount != 1000 {
+		t.Fatalf("por código = %+v, %v", got, err)
+	}
+	if _, err := b.Store.GetPromotionByCode(ctx, "NADA"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("código inexistente: erro = %v, quero ErrNotFound", err)
+	}
+
+	if err := b.Store.SetPromotionActive(ctx, coupon.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = b.Store.GetPromotionByCode(ctx, "BEMVINDO")
+	if got.Active {
+		t.Error("cupom continua ativo")
+	}
+
+	if err := b.Store.DeletePromotion(ctx, coupon.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Store.GetPromotionByCode(ctx, "BEMVINDO"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("cupom excluído: erro = %v, quero ErrNotFound", err)
+	}
+}
+
+func testPromotionLimits(t *testing.T, b backend) {
+	ctx := t.Context()
+	promo := models.Promotion{ID: primitive.NewObjectID(), Name: "Limitada", Code: "LIMITE", Kind: models.PromotionPercentage,
+		Percent: 5, MaxUses: 3, MaxUsesPerCustomer: 2, Active: true, CreatedAt: now()}
+	if err := b.Store.CreatePromotion(ctx, promo); err != nil {
+		t.Fatal(err)
+	}
+	ana, bia := primitive.NewObjectID(), primitive.NewObjectID()
+	redeem := func(user, order primitive.ObjectID) error {
+		return b.Store.RedeemPromotion(ctx, models.PromotionRedemption{PromotionID: promo.ID, UserID: user, OrderID: order, CreatedAt: now()})
+	}
+
+	first := primitive.NewObjectID()
+	if err := redeem(ana, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := redeem(ana, primitive.NewObjectID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := redeem(ana, primitive.NewObjectID()); !errors.Is(err, ErrPromotionLimitReached) {
+		t.Errorf("3º uso da mesma cliente: erro = %v, quero ErrPromotionLimitReached", err)
+	}
+	if err := redeem(bia, primitive.NewObjectID()); err != nil {
+		t.Fatal(err)
+	}
+	if err := redeem(bia, primitive.NewObjectID()); !errors.Is(err, ErrPromotionLimitReached) {
+		t.Errorf("4º uso no total: erro = %v, quero ErrPromotionLimitReached", err)
+	}
+	if n, _ := b.Store.CountPromotionRedemptions(ctx, promo.ID, ana); n != 2 {
+		t.Errorf("usos da ana = %d, quero 2", n)
+	}
+
+	// Pedido cancelado devolve o uso, no total e para a cliente
+	if err := b.Store.ReleasePromotionRedemptions(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := b.Store.CountPromotionRedemptions(ctx, promo.ID, ana); n != 1 {
+		t.Errorf("usos da ana após devolver = %d, quero 1", n)
+	}
+	got, _ := b.Store.GetPromotionByCode(ctx, "LIMITE")
+	if got.Uses != 2 {
+		t.Errorf("usos = %d, quero 2", got.Uses)
+	}
+	if err := redeem(bia, primitive.NewObjectID()); err != nil {
+		t.Errorf("uso liberado: %v", err)
+	}
+
+	err := b.Store.RedeemPromotion(ctx, models.PromotionRedemption{PromotionID: primitive.NewObjectID(), UserID: ana, OrderID: primitive.NewObjectID()})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("promoção inexistente: erro = %v, quero ErrNotFound", err)
+	}
+}
+
+func testShippingMethods(t *testing.T, b backend) {
+	ctx := t.Context()
+	base := now()
+
+	standard := models.ShippingMethod{
+		ID: primitive.NewObjectID(), Name: "Normal", Kind: models.ShippingStandard, Active: true, CreatedAt: base,
+		Rates: []models.ShippingRate{{ZipFrom: "01000000", ZipTo: "19999999", MaxWeightGrams: 1000, Price: 1990, Days: 3}},
+	}
+	pickup := models.ShippingMethod{ID: primitive.NewObjectID(), Name: "Retirada", Kind: models.ShippingPickup, PickupAddress: "Rua A, 1", Active: true, CreatedAt: base.Add(time.Hour)}
+	for _, m := range []models.ShippingMethod{pickup, standard} {
+		if err := b.Store.CreateShippingMethod(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := b.Store.GetShippingMethods(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].ID != standard.ID || all[1].ID != pickup.ID {
+		t.Fatalf("formas de entrega = %+v", all)
+	}
+	if len(all[0].Rates) != 1 || all[0].Rates[0] != standard.Rates[0] {
+		t.Errorf("tabela = %+v", all[0].Rates)
+	}
+
+	if err := b.Store.SetShippingMethodActive(ctx, pickup.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Store.DeleteShippingMethod(ctx, standard.ID); err != nil {
+		t.Fatal(err)
+	}
+	all, _ = b.Store.GetShippingMethods(ctx)
+	if len(all) != 1 || all[0].ID != pickup.ID || all[0].Active {
+		t.Fatalf("depois de desligar e excluir = %+v", all)
+	}
+
+	// Peso e medidas ficam no próprio documento do produto
+	product := models.Product{ID: primitive.NewObjectID(), Name: "Caixa", Price: 1000, Stock: 1, CreatedAt: base,
+		Dimensions: models.Dimensions{WeightGrams: 300, LengthCm: 20, WidthCm: 10, HeightCm: 5}}
+	if err := b.Store.CreateProduct(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	got, err := b.Store.GetProductByID(ctx, product.ID)
+	if err != nil || got.Dimensions != product.Dimensions {
+		t.Errorf("dimensões = %+v, %v", got, err)
+	}
+}
+
+func newOrder(email string, status models.OrderStatus, createdAt time.Time) models.Order {
+	return models.Order{
+		ID:            primitive.NewObjectID(),
+		CustomerEmail: email,
+		Status:        status,
+		Total:         1000,
+		Items:         []models.OrderItem{{ProductID: primitive.NewObjectID(), Quantity: 1, Price: 1000}},
+		CreatedAt:     createdAt,
+	}
+}
+
+func testOrders(t *testing.T, b backend) {
+	ctx := t.Context()
+	base := now()
+
+	older := newOrder("a@example.com", models.OrderStatusPaid, base.Add(-time.Hour))
+	newer := newOrder("a@example.com", models.OrderStatusPaid, base)
+	other := newOrder("b@example.com", models.OrderStatusPaid, base.Add(-time.Minute))
+	newer.PaymentID = "pay_123"
+	newer.Delivery = &models.ShippingQuote{MethodID: primitive.NewObjectID(), Name: "Normal", Kind: models.ShippingStandard, Zip: "01310100", WeightGrams: 500, Cost: 1990, Days: 3}
+
+	expired := base.Add(-time.Minute)
+	future := base.Add(time.Hour)
+	pixExpired := newOrder("c@example.com", models.OrderStatusPending, base.Add(-2*time.Hour))
+	pixExpired.ReservedUntil = &expired
+	pixValid := newOrder("c@example.com", models.OrderStatusPending, base.Add(-2*time.Hour))
+	pixValid.ReservedUntil = &future
+
+	// older e other são da mesma conta; newer usa o e-mail de older, mas é de outra
+	owner := primitive.NewObjectID()
+	older.UserID, other.UserID, newer.UserID = owner, owner, primitive.NewObjectID()
+
+	for _, o := range []models.Order{older, newer, other, pixExpired, pixValid} {
+		if err := b.Store.CreateOrder(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := b.Store.GetOrderByID(ctx, newer.ID)
+	if err != nil || got.Total != 1000 || len(got.Items) != 1 {
+		t.Fatalf("GetOrderByID = %+v, %v", got, err)
+	}
+	if got.Delivery == nil || *got.Delivery != *newer.Delivery {
+		t.Errorf("entrega = %+v", got.Delivery)
+	}
+	if _, err := b.Store.GetOrderByID(ctx, primitive.NewObjectID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("pedido inexistente: erro = %v", err)
+	}
+
+	byPayment, err := b.Store.GetOrderByPaymentID(ctx, "pay_123")
+	if err != nil || byPayment.ID != newer.ID {
+		t.Errorf("GetOrderByPaymentID = %v, %v", byPayment, err)
+	}
+	if _, err := b.Store.GetOrderByPaymentID(ctx, "pay_nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("pagamento inexistente: erro = %v", err)
+	}
+
+	all, _ := b.Store.GetAllOrders(ctx)
+	if len(all) != 5 || all[0].ID != newer.ID {
+		t.Errorf("GetAllOrders deve trazer os 5, mais recente primeiro")
+	}
+
+	mine, _ := b.Users.GetOrdersByEmail(ctx, "a@example.com")
+	if len(mine) != 2 || mine[0].ID != newer.ID || mine[1].ID != older.ID {
+		t.Errorf("GetOrdersByEmail = %d pedidos, fora de ordem ou incompletos", len(mine))
+	}
+
+	// Pela conta: o pedido com o e-mail dela, mas feito por outra conta, não entra
+	owned, _ := b.Users.GetOrdersByUserID(ctx, owner)
+	if len(owned) != 2 || owned[0].ID != other.ID || owned[1].ID != older.ID {
+		t.Errorf("GetOrdersByUserID = %d pedidos, fora de ordem ou com pedido de outra conta", len(owned))
+	}
+	if none, err := b.Users.GetOrdersByUserID(ctx, primitive.NewObjectID()); err != nil || len(none) != 0 {
+		t.Errorf("conta sem pedidos = %v, %v", none, err)
+	}
+
+	reservations, _ := b.Store.GetExpiredReservations(ctx, base)
+	if len(reservations) != 1 || reservations[0].ID != pixExpired.ID {
+		t.Errorf("GetExpiredReservations = %d pedidos, quero só o vencido", len(reservations))
+	}
+}
+
+func testTransitionOrderStatus(t *testing.T, b backend) {
+	ctx := t.Context()
+	order := newOrder("x@example.com", models.OrderStatusPending, now())
+	b.Store.CreateOrder(ctx, order)
+
+	pay := models.OrderStatusChange{From: models.OrderStatusPending, To: models.OrderStatusPaid, ChangedBy: "teste", ChangedAt: now()}
+	if err := b.Store.TransitionOrderStatus(ctx, order.ID, pay); err != nil {
+		t.Fatal(err)
+	}
+
+	// O pedido já saiu de "aguardando": a mesma transição tem que falhar
+	expire := models.OrderStatusChange{From: models.OrderStatusPending, To: models.OrderStatusExpired, ChangedBy: "worker", ChangedAt: now()}
+	if err := b.Store.TransitionOrderStatus(ctx, order.ID, expire); !errors.Is(err, ErrOrderStatusChanged) {
+		t.Errorf("transição com status desatualizado: erro = %v", err)
+	}
+
+	got, _ := b.Store.GetOrderByID(ctx, order.ID)
+	if got.Status != models.OrderStatusPaid || len(got.StatusHistory) != 1 || got.StatusHistory[0].ChangedBy != "teste" {
+		t.Errorf("pedido após transição = %s %+v", got.Status, got.StatusHistory)
+	}
+
+	if err := b.Store.TransitionOrderStatus(ctx, primitive.NewObjectID(), pay); !errors.Is(err, ErrOrderStatusChanged) {
+		t.Errorf("pedido inexistente: erro = %v", err)
+	}
+}
+
+func testPaymentEvents(t *testing.T, b backend) {
+	event := models.PaymentEvent{ID: "evt_1", Type: "payment.paid", PaymentID: "pay_1", ProcessedAt: now()}
+
+	if ok, err := b.Store.PaymentEventExists(t.Context(), event.ID); ok || err != nil {
+		t.Fatalf("evento novo já existe? %v %v", ok, err)
+	}
+	if err := b.Store.SavePaymentEvent(t.Context(), event); err != nil {
+		t.Fatal(err)
+	}
+	// Entrega repetida não é erro
+	if err := b.Store.SavePaymentEvent(t.Context(), event); err != nil {
+		t.Errorf("salvar evento repetido: %v", err)
+	}
+	if ok, _ := b.Store.PaymentEventExists(t.Context(), event.ID); !ok {
+		t.Error("evento salvo não encontrado")
+	}
+}
+
+func testUsers(t *testing.T, b backend) {
+	u := newUser(t, b.Users, "ana@example.com")
+
+	byEmail, err := b.Users.GetUserByEmail(t.Context(), "ana@example.com")
+	if err != nil || byEmail.ID != u.ID {
+		t.Fatalf("GetUserByEmail = %v, %v", byEmail, err)
+	}
+	if _, err := b.Users.GetUserByEmail(t.Context(), "ninguem@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("e-mail inexistente: erro = %v", err)
+	}
+	if _, err := b.Users.GetUserByID(t.Context(), primitive.NewObjectID()); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("ID inexistente: erro = %v", err)
+	}
+
+	dup := models.User{ID: primitive.NewObjectID(), Name: "Outra Ana", Email: "ana@example.com", PasswordHash: "x", CreatedAt: now()}
+	if err := b.Users.CreateUser(t.Context(), dup); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("e-mail repetido: erro = %v, quer ErrDuplicateEmail", err)
+	}
+
+	if err := b.Users.SetAdmin(t.Context(), u.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	byID, err := b.Users.GetUserByID(t.Context(), u.ID)
+	if err != nil || !byID.IsAdmin {
+		t.Errorf("SetAdmin não persistiu: %+v, %v", byID, err)
+	}
+}
+
+func testSessions(t *testing.T, b backend) {
+	ctx := t.Context()
+	session := models.Session{
+		ID: primitive.NewObjectID(), TokenHash: "hash-1", UserID: primitive.NewObjectID(),
+		CreatedAt: now(), LastSeenAt: now(), ExpiresAt: now().Add(time.Hour),
+	}
+	if err := b.Sessions.CreateSession(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := b.Sessions.GetSessionByTokenHash(ctx, "hash-1")
+	if err != nil || got.UserID != session.UserID || !got.ExpiresAt.Equal(session.ExpiresAt) {
+		t.Fatalf("GetSessionByTokenHash = %+v, %v", got, err)
+	}
+	if _, err := b.Sessions.GetSessionByTokenHash(ctx, "hash-nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("sessão inexistente: erro = %v", err)
+	}
+
+	seen := now().Add(time.Minute)
+	if err := b.Sessions.TouchSession(ctx, "hash-1", seen); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := b.Sessions.GetSessionByTokenHash(ctx, "hash-1"); got == nil || !got.LastSeenAt.Equal(seen) {
+		t.Errorf("TouchSession não persistiu: %+v", got)
+	}
+
+	if err := b.Sessions.RevokeSession(ctx, "hash-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Sessions.GetSessionByTokenHash(ctx, "hash-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("sessão revogada: erro = %v, quer ErrNotFound", err)
+	}
+	// Revogar de novo não é erro (logout repetido)
+	if err := b.Sessions.RevokeSession(ctx, "hash-1"); err != nil {
+		t.Errorf("segunda revogação: %v", err)
+	}
+}
+
+func testTransactionRollback(t *testing.T, b backend) {
+	p := newProduct(t, b.Store, 5)
+	u := newUser(t, b.Users, "tx@example.com")
+	item := models.OrderItem{ProductID: p.ID, Quantity: 2, Price: p.Price}
+	b.Store.AddItemToCart(t.Context(), u.ID, item, 10)
+	order := newOrder(u.Email, models.OrderStatusPaid, now())
+
+	// Baixa o primeiro, falha no segundo: nada pode ficar gravado
+	err := b.Store.RunInTransaction(t.Context(), func(ctx context.Context) error {
+		if err := b.Store.DecrementStock(ctx, p.ID, "", 2); err != nil {
+			return err
+		}
+		if err := b.Store.RemoveItemsFromCart(ctx, u.ID, []models.OrderItem{item}); err != nil {
+			return err
+		}
+		if err := b.Store.CreateOrder(ctx, order); err != nil {
+			return err
+		}
+		return b.Store.DecrementStock(ctx, primitive.NewObjectID(), "", 1)
+	})
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("transação: erro = %v, quero ErrInsufficientStock", err)
+	}
+
+	if s := stockOf(t, b.Store, p.ID); s != 5 {
+		t.Errorf("estoque após rollback = %d, quero 5", s)
+	}
+	if user, _ := b.Store.GetUserWithCart(t.Context(), u.ID); len(user.Cart) != 1 {
+		t.Errorf("carrinho após rollback = %+v", user.Cart)
+	}
+	if _, err := b.Store.GetOrderByID(t.Context(), order.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("pedido não deveria existir após rollback: %v", err)
+	}
+
+	// Sem erro, tudo fica
+	err = b.Store.RunInTransaction(t.Context(), func(ctx context.Context) error {
+		return b.Store.DecrementStock(ctx, p.ID, "", 2)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := stockOf(t, b.Store, p.ID); s != 3 {
+		t.Errorf("estoque após commit = %d, quero 3", s)
+	}
+}
+
+// Request cancelado (cliente desconectou) não pode chegar a gravar nada
+func testCancelledContext(t *testing.T, b backend) {
+	p := newProduct(t, b.Store, 5)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := b.Store.GetProductByID(ctx, p.ID); err == nil {
+		t.Error("leitura com ctx cancelado deveria falhar")
+	}
+	if err := b.Store.DecrementStock(ctx, p.ID, "", 1); err == nil || errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("baixa com ctx cancelado: erro = %v, quero o erro do ctx", err)
+	}
+
+	err := b.Store.RunInTransaction(ctx, func(ctx context.Context) error {
+		return b.Store.DecrementStock(ctx, p.ID, "", 1)
+	})
+	if err == nil {
+		t.Error("transação com ctx cancelado deveria falhar")
+	}
+
+	if s := stockOf(t, b.Store, p.ID); s != 5 {
+		t.Errorf("estoque = %d, quero 5", s)
+	}
+}
+
+func testCategories(t *testing.T, b backend) {
+	ctx := t.Context()
+	roupas := models.Category{ID: primitive.NewObjectID(), Name: "Roupas", Slug: "roupas", CreatedAt: now()}
+	camisetas := models.Category{ID: primitive.NewObjectID(), Name: "Camisetas", Slug: "camisetas", ParentID: &roupas.ID, CreatedAt: now()}
+	for _, c := range []models.Category{roupas, camisetas} {
+		if err := b.Store.CreateCategory(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dup := models.Category{ID: primitive.NewObjectID(), Name: "Outra", Slug: "roupas", CreatedAt: now()}
+	if err := b.Store.CreateCategory(ctx, dup); !errors.Is(err, ErrDuplicateSlug) {
+		t.Errorf("slug repetido: erro = %v, quero ErrDuplicateSlug", err)
+	}
+
+	all, err := b.Store.GetAllCategories(ctx)
+	if err != nil || len(all) != 2 || all[0].Slug != "camisetas" || *all[0].ParentID != roupas.ID {
+		t.Fatalf("GetAllCategories = %+v, %v (quero ordem por nome)", all, err)
+	}
+
+	p1 := models.Product{ID: primitive.NewObjectID(), Name: "Camiseta", CategoryIDs: []primitive.ObjectID{camisetas.ID}, CreatedAt: now()}
+	p2 := models.Product{ID: primitive.NewObjectID(), Name: "Jaqueta", CategoryIDs: []primitive.ObjectID{roupas.ID}, CreatedAt: now()}
+	newProduct(t, b.Store, 1) // sem categoria
+	for _, p := range []models.Product{p1, p2} {
+		if err := b.Store.CreateProduct(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	products, err := b.Store.GetProductsByCategories(ctx, []primitive.ObjectID{roupas.ID, camisetas.ID})
+	if err != nil || len(products) != 2 || products[0].ID != p1.ID {
+		t.Fatalf("GetProductsByCategories = %d produtos, %v", len(products), err)
+	}
+
+	// Excluir a categoria a tira dos produtos
+	if err := b.Store.DeleteCategory(ctx, camisetas.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := b.Store.GetProductByID(ctx, p1.ID); len(got.CategoryIDs) != 0 {
+		t.Errorf("categorias do produto após excluir = %v", got.CategoryIDs)
+	}
+	if all, _ := b.Store.GetAllCategories(ctx); len(all) != 1 {
+		t.Errorf("categorias após excluir = %+v", all)
+	}
+}
+
+func testCollections(t *testing.T, b backend) {
+	ctx := t.Context()
+	p1 := newProduct(t, b.Store, 1)
+	p2 := newProduct(t, b.Store, 1)
+
+	c := models.Collection{ID: primitive.NewObjectID(), Name: "Verão", Slug: "verao", ProductIDs: []primitive.ObjectID{p2.ID, p1.ID}, CreatedAt: now()}
+	if err := b.Store.CreateCollection(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	other := models.Collection{ID: primitive.NewObjectID(), Name: "Inverno", Slug: "inverno", CreatedAt: now()}
+	if err := b.Store.CreateCollection(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := b.Store.GetCollectionBySlug(ctx, "verao")
+	if err != nil || got.ID != c.ID || len(got.ProductIDs) != 2 || got.ProductIDs[0] != p2.ID {
+		t.Fatalf("GetCollectionBySlug = %+v, %v", got, err)
+	}
+	if _, err := b.Store.GetCollectionBySlug(ctx, "nada"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("slug inexistente: erro = %v, quero ErrNotFound", err)
+	}
+
+	other.Slug = "verao"
+	if err := b.Store.EditCollection(ctx, other.ID, other); !errors.Is(err, ErrDuplicateSlug) {
+		t.Errorf("editar para slug repetido: erro = %v, quero ErrDuplicateSlug", err)
+	}
+
+	c.ProductIDs = []primitive.ObjectID{p1.ID, primitive.NewObjectID()}
+	if err := b.Store.EditCollection(ctx, c.ID, c); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = b.Store.GetCollectionBySlug(ctx, "verao")
+	products, err := b.Store.GetProductsByIDs(ctx, got.ProductIDs)
+	if err != nil || len(products) != 1 || products[0].ID != p1.ID {
+		t.Errorf("GetProductsByIDs = %d produtos, %v (quero só p1)", len(products), err)
+	}
+
+	if err := b.Store.DeleteCollection(ctx, c.ID); err != nil {
+		t.Fatal(err)
+	}
+	all, err := b.Store.GetAllCollections(ctx)
+	if err != nil || len(all) != 1 || all[0].Slug != "inverno" {
+		t.Errorf("coleções após excluir = %+v, %v", all, err)
+	}
+}
+
+func testSearchProducts(t *testing.T, b backend) {
+	ctx := t.Context()
+	calcados := primitive.NewObjectID()
+	t0 := now()
+	camiseta := models.Product{ID: primitive.NewObjectID(), Name: "Camiseta Azul", Description: "Algodão leve",
+		Price: 5000, Stock: 5, Sizes: []string{"P", "M"}, CreatedAt: t0}
+	calca := models.Product{ID: primitive.NewObjectID(), Name: "Calça Jeans", Description: "Combina com camiseta",
+		Price: 12000, Stock: 0, Sizes: []string{"M"}, CreatedAt: t0.Add(time.Second)}
+	tenis := models.Product{ID: primitive.NewObjectID(), Name: "Tênis", Description: "Corrida",
+		Price: 30000, Stock: 2, CategoryIDs: []primitive.ObjectID{calcados}, CreatedAt: t0.Add(2 * time.Second),
+		Variants: []models.Variant{
+			{SKU: "TN-38", Options: []models.VariantOption{{Name: "Tamanho", Value: "38"}}, Stock: 0},
+			{SKU: "TN-40", Options: []models.VariantOption{{Name: "Tamanho", Value: "40"}}, Stock: 2},
+		}}
+	for _, p := range []models.Product{camiseta, calca, tenis} {
+		if err := b.Store.CreateProduct(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		q     ProductSearch
+		want  []primitive.ObjectID
+		total int
+	}{
+		{"texto por relevância (nome pesa mais)", ProductSearch{Text: "camiseta", Sort: SortRelevance}, []primitive.ObjectID{camiseta.ID, calca.ID}, 2},
+		{"sem acento", ProductSearch{Text: "algodao", Sort: SortRelevance}, []primitive.ObjectID{camiseta.ID}, 1},
+		{"pedaço de palavra não bate", ProductSearch{Text: "cami"}, nil, 0},
+		{"termo excluído", ProductSearch{Text: "camiseta -jeans"}, []primitive.ObjectID{camiseta.ID}, 1},
+		{"em estoque, maior preço", ProductSearch{InStockOnly: true, Sort: SortPriceDesc}, []primitive.ObjectID{tenis.ID, camiseta.ID}, 2},
+		{"tamanho legado, mais novos", ProductSearch{Size: "m", Sort: SortNewest}, []primitive.ObjectID{calca.ID, camiseta.ID}, 2},
+		{"opção de variante", ProductSearch{Size: "38"}, []primitive.ObjectID{tenis.ID}, 1},
+		{"variante esgotada", ProductSearch{Size: "38", InStockOnly: true}, nil, 0},
+		{"categoria", ProductSearch{CategoryIDs: []primitive.ObjectID{calcados}}, []primitive.ObjectID{tenis.ID}, 1},
+		{"faixa de preço", ProductSearch{MinPrice: 6000, MaxPrice: 20000}, []primitive.ObjectID{calca.ID}, 1},
+		{"paginação", ProductSearch{Sort: SortPriceAsc, Skip: 1, Limit: 1}, []primitive.ObjectID{calca.ID}, 3},
+	}
+	for _, tt := range tests {
+		got, total, err := b.Store.SearchProducts(ctx, tt.q)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var ids []primitive.ObjectID
+		for _, p := range got {
+			ids = append(ids, p.ID)
+		}
+		if total != tt.total || !slices.Equal(ids, tt.want) {
+			t.Errorf("%s: %d produtos (total %d), quero %d (total %d)", tt.name, len(ids), total, len(tt.want), tt.total)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ---------------------------------------------------------
+// CARRINHOS DE VISITANTES
+// ---------------------------------------------------------
+
+func (r *MongoStoreRepository) GetGuestCart(ctx context.Context, id primitive.ObjectID) (*models.GuestCart, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetGuestCart")
+	defer cancel()
+
+	// O TTL do Mongo apaga com atraso (até ~1 min): o filtro garante o prazo
+	filter := bson.M{"_id": id, "expires_at": bson.M{"$gt": time.Now()}}
+
+	var cart models.GuestCart
+	if err := r.db.Collection("carts").FindOne(ctx, filter).Decode(&cart); err != nil {
+		return nil, notFound(err)
+	}
+	return &cart, nil
+}
+
+func (r *MongoStoreRepository) AddItemToGuestCart(ctx context.Context, id primitive.ObjectID, item models.OrderItem, maxQuantity int, expiresAt time.Time) error {
+	ctx, cancel := r.Timeouts.write(ctx, "AddItemToGuestCart")
+	defer cancel()
+
+	if maxQuantity <= 0 {
+		return ErrInsufficientStock
+	}
+
+	coll := r.db.Collection("carts")
+
+	// Garante o documento antes da linha (um upsert no $push duplicaria o _id)
+	_, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":         bson.M{"updated_at": time.Now(), "expires_at": expiresAt},
+		"$setOnInsert": bson.M{"cart": bson.A{}},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	return addCartLine(ctx, coll, id, item, maxQuantity)
+}
+
+func (r *MongoStoreRepository) RemoveItemFromGuestCart(ctx context.Context, id, productID primitive.ObjectID, sku, size string) error {
+	ctx, cancel := r.Timeouts.write(ctx, "RemoveItemFromGuestCart")
+	defer cancel()
+
+	return removeCartLine(ctx, r.db.Collection("carts"), id, productID, sku, size)
+}
+
+func (r *MongoStoreRepository) UpdateGuestCartItemQuantity(ctx context.Context, id, productID primitive.ObjectID, quantity int, sku, size string) error {
+	ctx, cancel := r.Timeouts.write(ctx, "UpdateGuestCartItemQuantity")
+	defer cancel()
+
+	return setCartLineQuantity(ctx, r.db.Collection("carts"), id, productID, quantity, sku, size)
+}
+
+func (r *MongoStoreRepository) RepriceGuestCartItem(ctx context.Context, id primitive.ObjectID, item models.OrderItem) error {
+	ctx, cancel := r.Timeouts.write(ctx, "RepriceGuestCartItem")
+	defer cancel()
+
+	return repriceCartLine(ctx, r.db.Collection("carts"), id, item)
+}
+
+func (r *MongoStoreRepository) DeleteGuestCart(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := r.Timeouts.write(ctx, "DeleteGuestCart")
+	defer cancel()
+
+	_, err := r.db.Collection("carts").DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,1215 @@
+package repository
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errDuplicateID = errors.New("registro com este _id já existe")
+
+// MemoryStore implementa StoreRepository, UserRepository e SessionRepository em memória, com a
+// mesma semântica do Mongo (ver contract_test.go). Serve para testes e para
+// rodar a loja sem banco.
+//
+// Os slices dentro dos modelos nunca são alterados no lugar (sempre se cria
+// um novo), então copiar os mapas basta para o snapshot das transações.
+type MemoryStore struct {
+	mu sync.Mutex
+	memoryData
+
+	// Preso durante toda a transação: as outras operações esperam por ela,
+	// então o rollback (que restaura o snapshot) só desfaz o que ela escreveu
+	txMu sync.Mutex
+}
+
+// memoryTxKey marca o ctx de dentro de uma transação deste store
+type memoryTxKey struct{ store *MemoryStore }
+
+type memoryData struct {
+	products      map[primitive.ObjectID]models.Product
+	productOrder  []primitive.ObjectID // ordem de inserção, como o find sem sort
+	users         map[primitive.ObjectID]models.User
+	userOrder     []primitive.ObjectID
+	orders        map[primitive.ObjectID]models.Order
+	paymentEvents map[string]models.PaymentEvent
+	categories    map[primitive.ObjectID]models.Category
+	collections   map[primitive.ObjectID]models.Collection
+	guestCarts    map[primitive.ObjectID]models.GuestCart
+	promotions    map[primitive.ObjectID]models.Promotion
+	redemptions   []models.PromotionRedemption
+	shipping      map[primitive.ObjectID]models.ShippingMethod
+	sessions      map[string]models.Session // pelo hash do token
+}
+
+var (
+	_ StoreRepository   = (*MemoryStore)(nil)
+	_ UserRepository    = (*MemoryStore)(nil)
+	_ SessionRepository = (*MemoryStore)(nil)
+)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{memoryData: memoryData{
+		products:      map[primitive.ObjectID]models.Product{},
+		users:         map[primitive.ObjectID]models.User{},
+		orders:        map[primitive.ObjectID]models.Order{},
+		paymentEvents: map[string]models.PaymentEvent{},
+		categories:    map[primitive.ObjectID]models.Category{},
+		collections:   map[primitive.ObjectID]models.Collection{},
+		guestCarts:    map[primitive.ObjectID]models.GuestCart{},
+		promotions:    map[primitive.ObjectID]models.Promotion{},
+		shipping:      map[primitive.ObjectID]models.ShippingMethod{},
+		sessions:      map[string]models.Session{},
+	}}
+}
+
+func (d memoryData) clone() memoryData {
+	return memoryData{
+		products:      cloneMap(d.products),
+		productOrder:  slices.Clone(d.productOrder),
+		users:         cloneMap(d.users),
+		userOrder:     slices.Clone(d.userOrder),
+		orders:        cloneMap(d.orders),
+		paymentEvents: cloneMap(d.paymentEvents),
+		categories:    cloneMap(d.categories),
+		collections:   cloneMap(d.collections),
+		guestCarts:    cloneMap(d.guestCarts),
+		promotions:    cloneMap(d.promotions),
+		redemptions:   slices.Clone(d.redemptions),
+		shipping:      cloneMap(d.shipping),
+		sessions:      cloneMap(d.sessions),
+	}
+}
+
+// lock trava o store, a menos que o ctx já tenha sido cancelado
+// (como o driver do Mongo, que nem envia a operação nesse caso). Fora de uma
+// transação, espera a transação em andamento terminar.
+func (m *MemoryStore) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.inTransaction(ctx) {
+		m.mu.Lock()
+		return nil
+	}
+	m.txMu.Lock()
+	m.mu.Lock()
+	m.txMu.Unlock()
+	return nil
+}
+
+func (m *MemoryStore) inTransaction(ctx context.Context) bool {
+	return ctx.Value(memoryTxKey{m}) != nil
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	out := make(map[K]V, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// RunInTransaction: se fn falhar, o estado volta ao que era antes dela.
+// Só o ctx recebido por fn enxerga o store enquanto ela roda.
+func (m *MemoryStore) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if m.inTransaction(ctx) {
+		return fn(ctx) // já dentro de uma: faz parte dela
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	ctx = context.WithValue(ctx, memoryTxKey{m}, true)
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	snapshot := m.memoryData.clone()
+	m.mu.Unlock()
+
+	if err := fn(ctx); err != nil {
+		m.mu.Lock()
+		m.memoryData = snapshot
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// ---------------------------------------------------------
+// PRODUTOS
+// ---------------------------------------------------------
+
+func (m *MemoryStore) GetAllProducts(ctx context.Context) ([]models.Product, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	var products []models.Product
+	for _, id := range m.productOrder {
+		products = append(products, m.products[id])
+	}
+	return products, nil
+}
+
+func (m *MemoryStore) GetProductByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	product, ok := m.products[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &product, nil
+}
+
+func (m *MemoryStore) CreateProduct(ctx context.Context, product models.Product) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if product.ID.IsZero() {
+		product.ID = primitive.NewObjectID()
+	}
+	if _, ok := m.products[product.ID]; ok {
+		return errDuplicateID
+	}
+	if m.skuTaken(product) {
+		return ErrDuplicateSKU
+	}
+	m.products[product.ID] = product
+	m.productOrder = append(slices.Clip(m.productOrder), product.ID)
+	return nil
+}
+
+func (m *MemoryStore) EditProduct(ctx context.Context, id primitive.ObjectID, product models.Product) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if _, ok := m.products[id]; !ok {
+		return nil // ReplaceOne sem match também não é erro
+	}
+	product.ID = id
+	if m.skuTaken(product) {
+		return ErrDuplicateSKU
+	}
+	m.products[id] = product
+	return nil
+}
+
+// skuTaken imita o índice único variants.sku: só conflita com outros produtos
+func (m *MemoryStore) skuTaken(product models.Product) bool {
+	for id, other := range m.products {
+		if id == product.ID {
+			continue
+		}
+		for _, v := range product.Variants {
+			if variantIndex(other, v.SKU) >= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *MemoryStore) DeleteProduct(ctx context.Context, id primitive.ObjectID) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	delete(m.products, id)
+	m.productOrder = slices.DeleteFunc(slices.Clone(m.productOrder), func(p primitive.ObjectID) bool { return p == id })
+	return nil
+}
+
+// SearchProducts imita o $text do Mongo de forma aproximada: um termo bate só
+// com a palavra inteira (sem acento/caixa), como o $text, mas sem stemming;
+// nome pesa 10 e descrição 2 (os pesos do índice products_text).
+func (m *MemoryStore) SearchProducts(ctx context.Context, q ProductSearch) ([]models.Product, int, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, 0, err
+	}
+	defer m.mu.Unlock()
+
+	terms, excluded := SearchTerms(q.Text)
+	hasText := len(terms) > 0 || len(excluded) > 0
+
+	var found []models.Product
+	scores := map[primitive.ObjectID]int{}
+	for _, id := range m.productOrder {
+		p := m.products[id]
+		if hasText {
+			name, desc := textWords(p.Name), textWords(p.Description)
+			if slices.ContainsFunc(excluded, func(t string) bool { return hasWord(name, t) || hasWord(desc, t) }) {
+				continue
+			}
+			score := 0
+			for _, t := range terms {
+				if hasWord(name, t) {
+					score += 10
+				}
+				if hasWord(desc, t) {
+					score += 2
+				}
+			}
+			if score == 0 {
+				continue
+			}
+			scores[id] = score
+		}
+		if !matchesSearchFilters(p, q) {
+			continue
+		}
+		found = append(found, p)
+	}
+
+	byID := func(a, b models.Product) int { return bytes.Compare(a.ID[:], b.ID[:]) }
+	switch {
+	case q.Sort == SortPriceAsc:
+		slices.SortStableFunc(found, func(a, b models.Product) int { return cmp.Or(cmp.Compare(a.Price, b.Price), byID(a, b)) })
+	case q.Sort == SortPriceDesc:
+		slices.SortStableFunc(found, func(a, b models.Product) int { return cmp.Or(cmp.Compare(b.Price, a.Price), byID(a, b)) })
+	case q.Sort == SortRelevance && strings.TrimSpace(q.Text) != "":
+		slices.SortStableFunc(found, func(a, b models.Product) int { return cmp.Or(cmp.Compare(scores[b.ID], scores[a.ID]), byID(a, b)) })
+	default:
+		slices.SortStableFunc(found, func(a, b models.Product) int { return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), byID(b, a)) })
+	}
+
+	total := len(found)
+	found = found[min(q.Skip, total):]
+	if q.Limit > 0 && len(found) > q.Limit {
+		found = found[:q.Limit]
+	}
+	return found, total, nil
+}
+
+// matchesSearchFilters: os filtros de searchFilter, fora o texto
+func matchesSearchFilters(p models.Product, q ProductSearch) bool {
+	if q.MinPrice > 0 && p.Price < q.MinPrice || q.MaxPrice > 0 && p.Price > q.MaxPrice {
+		return false
+	}
+	if q.InStockOnly && p.Stock <= 0 {
+		return false
+	}
+	if q.Size != "" {
+		sized := slices.ContainsFunc(p.Sizes, func(s string) bool { return strings.EqualFold(s, q.Size) }) ||
+			slices.ContainsFunc(p.Variants, func(v models.Variant) bool {
+				return (!q.InStockOnly || v.Stock > 0) &&
+					slices.ContainsFunc(v.Options, func(o models.VariantOption) bool { return strings.EqualFold(o.Value, q.Size) })
+			})
+		if !sized {
+			return false
+		}
+	}
+	if len(q.CategoryIDs) > 0 && !slices.ContainsFunc(q.CategoryIDs, p.InCategory) {
+		return false
+	}
+	return true
+}
+
+func textWords(s string) []string {
+	return strings.FieldsFunc(FoldText(s), func(r rune) bool {
+		return !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r > 127)
+	})
+}
+
+func hasWord(words []string, term string) bool {
+	return slices.Contains(words, term)
+}
+
+// DecrementStock confere e baixa o estoque sob o mesmo lock, o equivalente
+// ao filtro stock >= quantity + $inc do Mongo
+func (m *MemoryStore) DecrementStock(ctx context.Context, id primitive.ObjectID, sku string, quantity int) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	product, ok := m.products[id]
+	if !ok || product.Stock < quantity {
+		return ErrInsufficientStock
+	}
+	if sku != "" {
+		i := variantIndex(product, sku)
+		if i < 0 || product.Variants[i].Stock < quantity {
+			return ErrInsufficientStock
+		}
+		product.Variants = slices.Clone(product.Variants)
+		product.Variants[i].Stock -= quantity
+	}
+	product.Stock -= quantity
+	m.products[id] = product
+	return nil
+}
+
+func (m *MemoryStore) IncrementStock(ctx context.Context, id primitive.ObjectID, sku string, quantity int) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	product, ok := m.products[id]
+	if !ok {
+		return nil
+	}
+	if sku != "" {
+		i := variantIndex(product, sku)
+		if i < 0 {
+			return nil
+		}
+		product.Variants = slices.Clone(product.Variants)
+		product.Variants[i].Stock += quantity
+	}
+	product.Stock += quantity
+	m.products[id] = product
+	return nil
+}
+
+func variantIndex(product models.Product, sku string) int {
+	return slices.IndexFunc(product.Variants, func(v models.Variant) bool { return v.SKU == sku })
+}
+
+// ---------------------------------------------------------
+// CATEGORIAS E COLEÇÕES
+// ---------------------------------------------------------
+
+func (m *MemoryStore) GetAllCategories(ctx context.Context) ([]models.Category, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	categories := make([]models.Category, 0, len(m.categories))
+	for _, c := range m.categories {
+		categories = append(categories, c)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	return categories, nil
+}
+
+func (m *MemoryStore) CreateCategory(ctx context.Context, category models.Category) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if category.ID.IsZero() {
+		category.ID = primitive.NewObjectID()
+	}
+	if _, ok := m.categories[category.ID]; ok {
+		return errDuplicateID
+	}
+	for _, c := range m.categories {
+		if c.Slug == category.Slug {
+			return ErrDuplicateSlug
+		}
+	}
+	m.categories[category.ID] = category
+	return nil
+}
+
+func (m *MemoryStore) DeleteCategory(ctx context.Context, id primitive.ObjectID) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	delete(m.categories, id)
+	for pid, p := range m.products {
+		if p.InCategory(id) {
+			p.CategoryIDs = slices.DeleteFunc(slices.Clone(p.CategoryIDs), func(c primitive.ObjectID) bool { return c == id })
+			m.products[pid] = p
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetProductsByCategories(ctx context.Context, ids []primitive.ObjectID) ([]models.Product, error) {
+	return m.findProducts(ctx, func(p models.Product) bool {
+		return slices.ContainsFunc(ids, p.InCategory)
+	})
+}
+
+func (m *MemoryStore) GetProductsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Product, error) {
+	return m.findProducts(ctx, func(p models.Product) bool { return slices.Contains(ids, p.ID) })
+}
+
+// findProducts filtra na ordem de inserção, como o find sem sort
+func (m *MemoryStore) findProducts(ctx context.Context, match func(models.Product) bool) ([]models.Product, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	var products []models.Product
+	for _, id := range m.productOrder {
+		if p := m.products[id]; match(p) {
+			products = append(products, p)
+		}
+	}
+	return products, nil
+}
+
+func (m *MemoryStore) GetAllCollections(ctx context.Context) ([]models.Collection, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	collections := make([]models.Collection, 0, len(m.collections))
+	for _, c := range m.collections {
+		collections = append(collections, c)
+	}
+	sort.Slice(collections, func(i, j int) bool { return collections[i].Name < collections[j].Name })
+	return collections, nil
+}
+
+func (m *MemoryStore) GetCollectionBySlug(ctx context.Context, slug string) (*models.Collection, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	for _, c := range m.collections {
+		if c.Slug == slug {
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryStore) CreateCollection(ctx context.Context, collection models.Collection) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if collection.ID.IsZero() {
+		collection.ID = primitive.NewObjectID()
+	}
+	if _, ok := m.collections[collection.ID]; ok {
+		return errDuplicateID
+	}
+	if m.collectionSlugTaken(collection) {
+		return ErrDuplicateSlug
+	}
+	m.collections[collection.ID] = collection
+	return nil
+}
+
+func (m *MemoryStore) EditCollection(ctx context.Context, id primitive.ObjectID, collection models.Collection) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if _, ok := m.collections[id]; !ok {
+		return nil // ReplaceOne sem match também não é erro
+	}
+	collection.ID = id
+	if m.collectionSlugTaken(collection) {
+		return ErrDuplicateSlug
+	}
+	m.collections[id] = collection
+	return nil
+}
+
+func (m *MemoryStore) collectionSlugTaken(collection models.Collection) bool {
+	for id, c := range m.collections {
+		if id != collection.ID && c.Slug == collection.Slug {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryStore) DeleteCollection(ctx context.Context, id primitive.ObjectID) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	delete(m.collections, id)
+	return nil
+}
+
+// ---------------------------------------------------------
+// CARRINHO
+// ---------------------------------------------------------
+
+func (m *MemoryStore) GetUserWithCart(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+// updateCart aplica fn numa cópia do carrinho (usuário inexistente é ignorado,
+// como um UpdateOne sem match)
+func (m *MemoryStore) updateCart(ctx context.Context, userID primitive.ObjectID, fn func(cart []models.OrderItem) []models.OrderItem) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return nil
+	}
+	user.Cart = fn(slices.Clone(user.Cart))
+	m.users[userID] = user
+	return nil
+}
+
+func (m *MemoryStore) AddItemToCart(ctx context.Context, userID primitive.ObjectID, item models.OrderItem, maxQuantity int) error {
+	if maxQuantity <= 0 {
+		return ErrInsufficientStock
+	}
+	return m.updateCart(ctx, userID, func(cart []models.OrderItem) []models.OrderItem {
+		return addCartItem(cart, item, maxQuantity)
+	})
+}
+
+// addCartItem é o equivalente do addCartLine do Mongo
+func addCartItem(cart []models.OrderItem, item models.OrderItem, maxQuantity int) []models.OrderItem {
+	for i := range cart {
+		if cartLineMatches(cart[i], item.ProductID, item.SKU, item.Size) {
+			cart[i].Quantity = min(cart[i].Quantity+item.Quantity, maxQuantity)
+			return cart
+		}
+	}
+	item.Quantity = min(item.Quantity, maxQuantity)
+	return append(cart, item)
+}
+
+// cartLineMatches é o equivalente de cartLineMatch do Mongo
+func cartLineMatches(item models.OrderItem, productID primitive.ObjectID, sku, size string) bool {
+	if item.ProductID != productID {
+		return false
+	}
+	if sku != "" {
+		return item.SKU == sku
+	}
+	return item.Size == size
+}
+
+// RemoveItemFromCart: com sku/size remove só aquela linha; sem nenhum, todas do produto
+func (m *MemoryStore) RemoveItemFromCart(ctx context.Context, userID, productID primitive.ObjectID, sku, size string) error {
+	return m.updateCart(ctx, userID, func(cart []models.OrderItem) []models.OrderItem {
+		return removeCartItems(cart, productID, sku, size)
+	})
+}
+
+// removeCartItems é o equivalente do removeCartLine do Mongo
+func removeCartItems(cart []models.OrderItem, productID primitive.ObjectID, sku, size string) []models.OrderItem {
+	return slices.DeleteFunc(cart, func(i models.OrderItem) bool {
+		if sku == "" && size == "" {
+			return i.ProductID == productID
+		}
+		return cartLineMatches(i, productID, sku, size)
+	})
+}
+
+func (m *MemoryStore) UpdateCartItemQuantity(ctx context.Context, userID, productID primitive.ObjectID, quantity int, sku, size string) error {
+	return m.updateCart(ctx, userID, func(cart []models.OrderItem) []models.OrderItem {
+		return setCartItemQuantity(cart, productID, quantity, sku, size)
+	})
+}
+
+// setCartItemQuantity altera o primeiro item que bate (como o operador $)
+func setCartItemQuantity(cart []models.OrderItem, productID primitive.ObjectID, quantity int, sku, size string) []models.OrderItem {
+	for i := range cart {
+		if cartLineMatches(cart[i], productID, sku, size) {
+			cart[i].Quantity = quantity
+			break
+		}
+	}
+	return cart
+}
+
+func (m *MemoryStore) RepriceCartItem(ctx context.Context, userID primitive.ObjectID, item models.OrderItem) error {
+	return m.updateCart(ctx, userID, func(cart []models.OrderItem) []models.OrderItem {
+		return repriceCartItem(cart, item)
+	})
+}
+
+// repriceCartItem é o equivalente do repriceCartLine do Mongo
+func repriceCartItem(cart []models.OrderItem, item models.OrderItem) []models.OrderItem {
+	for i := range cart {
+		if cartLineMatches(cart[i], item.ProductID, item.SKU, item.Size) {
+			cart[i].Price = item.Price
+			cart[i].ProductName = item.ProductName
+			cart[i].ImageURL = item.ImageURL
+			break
+		}
+	}
+	return cart
+}
+
+func (m *MemoryStore) RemoveItemsFromCart(ctx context.Context, userID primitive.ObjectID, items []models.OrderItem) error {
+	return m.updateCart(ctx, userID, func(cart []models.OrderItem) []models.OrderItem {
+		return slices.DeleteFunc(cart, func(c models.OrderItem) bool {
+			for _, item := range items {
+				if cartLineMatches(c, item.ProductID, item.SKU, item.Size) {
+					return true
+				}
+			}
+			return false
+		})
+	})
+}
+
+// ---------------------------------------------------------
+// CARRINHOS DE VISITANTES
+// ---------------------------------------------------------
+
+// guestCart devolve o carrinho se existir e não tiver expirado (trava já obtida)
+func (m *MemoryStore) guestCart(id primitive.ObjectID) (models.GuestCart, bool) {
+	cart, ok := m.guestCarts[id]
+	if !ok || !cart.ExpiresAt.After(time.Now()) {
+		return models.GuestCart{}, false
+	}
+	return cart, true
+}
+
+func (m *MemoryStore) GetGuestCart(ctx context.Context, id primitive.ObjectID) (*models.GuestCart, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	cart, ok := m.guestCart(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &cart, nil
+}
+
+func (m *MemoryStore) AddItemToGuestCart(ctx context.Context, id primitive.ObjectID, item models.OrderItem, maxQuantity int, expiresAt time.Time) error {
+	if maxQuantity <= 0 {
+		return ErrInsufficientStock
+	}
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	cart, ok := m.guestCart(id)
+	if !ok {
+		cart = models.GuestCart{ID: id}
+	}
+	cart.Cart = addCartItem(slices.Clone(cart.Cart), item, maxQuantity)
+	cart.UpdatedAt = time.Now()
+	cart.ExpiresAt = expiresAt
+	m.guestCarts[id] = cart
+	return nil
+}
+
+// updateGuestCart é o updateCart dos visitantes
+func (m *MemoryStore) updateGuestCart(ctx context.Context, id primitive.ObjectID, fn func(cart []models.OrderItem) []models.OrderItem) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	cart, ok := m.guestCart(id)
+	if !ok {
+		return nil
+	}
+	cart.Cart = fn(slices.Clone(cart.Cart))
+	m.guestCarts[id] = cart
+	return nil
+}
+
+func (m *MemoryStore) RemoveItemFromGuestCart(ctx context.Context, id, productID primitive.ObjectID, sku, size string) error {
+	return m.updateGuestCart(ctx, id, func(cart []models.OrderItem) []models.OrderItem {
+		return removeCartItems(cart, productID, sku, size)
+	})
+}
+
+func (m *MemoryStore) UpdateGuestCartItemQuantity(ctx context.Context, id, productID primitive.ObjectID, quantity int, sku, size string) error {
+	return m.updateGuestCart(ctx, id, func(cart []models.OrderItem) []models.OrderItem {
+		return setCartItemQuantity(cart, productID, quantity, sku, size)
+	})
+}
+
+func (m *MemoryStore) RepriceGuestCartItem(ctx context.Context, id primitive.ObjectID, item models.OrderItem) error {
+	return m.updateGuestCart(ctx, id, func(cart []models.OrderItem) []models.OrderItem {
+		return repriceCartItem(cart, item)
+	})
+}
+
+func (m *MemoryStore) DeleteGuestCart(ctx context.Context, id primitive.ObjectID) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	delete(m.guestCarts, id)
+	return nil
+}
+
+// ---------------------------------------------------------
+// PROMOÇÕES E CUPONS
+// ---------------------------------------------------------
+
+func (m *MemoryStore) GetAllPromotions(ctx context.Context) ([]models.Promotion, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	promotions := make([]models.Promotion, 0, len(m.promotions))
+	for _, p := range m.promotions {
+		promotions = append(promotions, p)
+	}
+	sort.SliceStable(promotions, func(i, j int) bool { return promotions[i].CreatedAt.After(promotions[j].CreatedAt) })
+	return promotions, nil
+}
+
+func (m *MemoryStore) GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	for _, p := range m.promotions {
+		if p.Code != "" && p.Code == code {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryStore) CreatePromotion(ctx context.Context, promotion models.Promotion) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if promotion.ID.IsZero() {
+		promotion.ID = primitive.NewObjectID()
+	}
+	if _, ok := m.promotions[promotion.ID]; ok {
+		return errDuplicateID
+	}
+	for _, p := range m.promotions {
+		if promotion.Code != "" && p.Code == promotion.Code {
+			return ErrDuplicateCode
+		}
+	}
+	m.promotions[promotion.ID] = promotion
+	return nil
+}
+
+func (m *MemoryStore) SetPromotionActive(ctx context.Context, id primitive.ObjectID, active bool) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if p, ok := m.promotions[id]; ok {
+		p.Active = active
+		m.promotions[id] = p
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeletePromotion(ctx context.Context, id primitive.ObjectID) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	delete(m.promotions, id)
+	return nil
+}
+
+func (m *MemoryStore) CountPromotionRedemptions(ctx context.Context, promotionID, userID primitive.ObjectID) (int, error) {
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.mu.Unlock()
+
+	return m.countRedemptions(promotionID, userID), nil
+}
+
+func (m *MemoryStore) countRedemptions(promotionID, userID primitive.ObjectID) int {
+	n := 0
+	for _, r := range m.redemptions {
+		if r.PromotionID == promotionID && r.UserID == userID {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *MemoryStore) RedeemPromotion(ctx context.Context, redemption models.PromotionRedemption) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	p, ok := m.promotions[redemption.PromotionID]
+	if !ok {
+		return ErrNotFound
+	}
+	if p.MaxUsesPerCustomer > 0 && m.countRedemptions(p.ID, redemption.UserID) >= p.MaxUsesPerCustomer {
+		return ErrPromotionLimitReached
+	}
+	if p.MaxUses > 0 && p.Uses >= p.MaxUses {
+		return ErrPromotionLimitReached
+	}
+
+	p.Uses++
+	m.promotions[p.ID] = p
+	if redemption.ID.IsZero() {
+		redemption.ID = primitive.NewObjectID()
+	}
+	m.redemptions = append(slices.Clone(m.redemptions), redemption)
+	return nil
+}
+
+func (m *MemoryStore) ReleasePromotionRedemptions(ctx context.Context, orderID primitive.ObjectID) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	m.redemptions = slices.DeleteFunc(slices.Clone(m.redemptions), func(r models.PromotionRedemption) bool {
+		if r.OrderID != orderID {
+			return false
+		}
+		if p, ok := m.promotions[r.PromotionID]; ok && p.Uses > 0 {
+			p.Uses--
+			m.promotions[p.ID] = p
+		}
+		return true
+	})
+	return nil
+}
+
+// ---------------------------------------------------------
+// FRETE
+// ---------------------------------------------------------
+
+func (m *MemoryStore) GetShippingMethods(ctx context.Context) ([]models.ShippingMethod, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	methods := make([]models.ShippingMethod, 0, len(m.shipping))
+	for _, method := range m.shipping {
+		methods = append(methods, method)
+	}
+	slices.SortStableFunc(methods, func(a, b models.ShippingMethod) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), bytes.Compare(a.ID[:], b.ID[:]))
+	})
+	return methods, nil
+}
+
+func (m *MemoryStore) CreateShippingMethod(ctx context.Context, method models.ShippingMethod) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if method.ID.IsZero() {
+		method.ID = primitive.NewObjectID()
+	}
+	if _, ok := m.shipping[method.ID]; ok {
+		return errDuplicateID
+	}
+	m.shipping[method.ID] = method
+	return nil
+}
+
+func (m *MemoryStore) SetShippingMethodActive(ctx context.Context, id primitive.ObjectID, active bool) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if method, ok := m.shipping[id]; ok {
+		method.Active = active
+		m.shipping[id] = method
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteShippingMethod(ctx context.Context, id primitive.ObjectID) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	delete(m.shipping, id)
+	return nil
+}
+
+// ---------------------------------------------------------
+// PEDIDOS
+// ---------------------------------------------------------
+
+func (m *MemoryStore) CreateOrder(ctx context.Context, order models.Order) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if order.ID.IsZero() {
+		order.ID = primitive.NewObjectID()
+	}
+	if _, ok := m.orders[order.ID]; ok {
+		return errDuplicateID
+	}
+	m.orders[order.ID] = order
+	return nil
+}
+
+func (m *MemoryStore) GetOrderByID(ctx context.Context, orderID primitive.ObjectID) (*models.Order, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[orderID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &order, nil
+}
+
+// findOrders devolve os pedidos que passam no filtro, mais recentes primeiro
+func (m *MemoryStore) findOrders(ctx context.Context, match func(models.Order) bool) ([]models.Order, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	var orders []models.Order
+	for _, order := range m.orders {
+		if match(order) {
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt.After(orders[j].CreatedAt) })
+	return orders, nil
+}
+
+func (m *MemoryStore) GetAllOrders(ctx context.Context) ([]models.Order, error) {
+	return m.findOrders(ctx, func(models.Order) bool { return true })
+}
+
+func (m *MemoryStore) GetOrderByPaymentID(ctx context.Context, paymentID string) (*models.Order, error) {
+	orders, err := m.findOrders(ctx, func(o models.Order) bool { return o.PaymentID == paymentID })
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, ErrNotFound
+	}
+	return &orders[0], nil
+}
+
+func (m *MemoryStore) GetExpiredReservations(ctx context.Context, now time.Time) ([]models.Order, error) {
+	return m.findOrders(ctx, func(o models.Order) bool {
+		return o.Status == models.OrderStatusPending && o.ReservedUntil != nil && !o.ReservedUntil.After(now)
+	})
+}
+
+func (m *MemoryStore) TransitionOrderStatus(ctx context.Context, orderID primitive.ObjectID, change models.OrderStatusChange) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[orderID]
+	if !ok || order.Status != change.From {
+		return ErrOrderStatusChanged
+	}
+	order.Status = change.To
+	order.StatusHistory = append(slices.Clip(order.StatusHistory), change)
+	m.orders[orderID] = order
+	return nil
+}
+
+// ---------------------------------------------------------
+// EVENTOS DE PAGAMENTO
+// ---------------------------------------------------------
+
+func (m *MemoryStore) PaymentEventExists(ctx context.Context, eventID string) (bool, error) {
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mu.Unlock()
+
+	_, ok := m.paymentEvents[eventID]
+	return ok, nil
+}
+
+func (m *MemoryStore) SavePaymentEvent(ctx context.Context, event models.PaymentEvent) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if _, ok := m.paymentEvents[event.ID]; !ok {
+		m.paymentEvents[event.ID] = event
+	}
+	return nil
+}
+
+// ---------------------------------------------------------
+// USUÁRIOS
+// ---------------------------------------------------------
+
+func (m *MemoryStore) CreateUser(ctx context.Context, user models.User) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	if _, ok := m.users[user.ID]; ok {
+		return errDuplicateID
+	}
+	for _, existing := range m.users {
+		if existing.Email == user.Email {
+			return ErrDuplicateEmail
+		}
+	}
+	m.users[user.ID] = user
+	m.userOrder = append(slices.Clip(m.userOrder), user.ID)
+	return nil
+}
+
+func (m *MemoryStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	for _, id := range m.userOrder {
+		if user := m.users[id]; user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (m *MemoryStore) GetUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (m *MemoryStore) SetAdmin(ctx context.Context, id primitive.ObjectID, isAdmin bool) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if user, ok := m.users[id]; ok {
+		user.IsAdmin = isAdmin
+		m.users[id] = user
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetOrdersByEmail(ctx context.Context, email string) ([]models.Order, error) {
+	return m.findOrders(ctx, func(o models.Order) bool { return o.CustomerEmail == email })
+}
+
+func (m *MemoryStore) GetOrdersByUserID(ctx context.Context, userID primitive.ObjectID) ([]models.Order, error) {
+	return m.findOrders(ctx, func(o models.Order) bool { return o.UserID == userID })
+}
+
+// ---------------------------------------------------------
+// SESSÕES
+// ---------------------------------------------------------
+
+func (m *MemoryStore) CreateSession(ctx context.Context, session models.Session) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	m.sessions[session.TokenHash] = session
+	return nil
+}
+
+func (m *MemoryStore) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[tokenHash]
+	if !ok || session.RevokedAt != nil {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+func (m *MemoryStore) TouchSession(ctx context.Context, tokenHash string, seenAt time.Time) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[tokenHash]; ok {
+		session.LastSeenAt = seenAt
+		m.sessions[tokenHash] = session
+	}
+	return nil
+}
+
+func (m *MemoryStore) RevokeSession(ctx context.Context, tokenHash string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[tokenHash]; ok && session.RevokedAt == nil {
+		revokedAt := time.Now()
+		session.RevokedAt = &revokedAt
+		m.sessions[tokenHash] = session
+	}
+	return nil
+}
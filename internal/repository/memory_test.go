@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// O rollback restaura o snapshot: uma escrita de fora feita durante a
+// transação não pode sumir junto (no Mongo ela não sumiria)
+func TestMemoryRollbackKeepsOutsideWrites(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStore()
+	inside := models.Product{ID: primitive.NewObjectID(), Name: "Dentro", CreatedAt: now()}
+	outside := models.Product{ID: primitive.NewObjectID(), Name: "Fora", CreatedAt: now()}
+
+	done := make(chan error, 1)
+	err := m.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := m.CreateProduct(ctx, inside); err != nil {
+			return err
+		}
+		go func() { done <- m.CreateProduct(context.Background(), outside) }()
+		time.Sleep(20 * time.Millisecond) // dá tempo da escrita de fora tentar entrar
+		return errors.New("falha no meio da transação")
+	})
+	if err == nil {
+		t.Fatal("a transação deveria falhar")
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.GetProductByID(ctx, inside.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("escrita da transação sobreviveu ao rollback: %v", err)
+	}
+	if _, err := m.GetProductByID(ctx, outside.ID); err != nil {
+		t.Errorf("escrita de fora sumiu no rollback: %v", err)
+	}
+}
+
+func TestMemoryNestedTransaction(t *testing.T) {
+	m := NewMemoryStore()
+	p := models.Product{ID: primitive.NewObjectID(), Name: "Aninhada", CreatedAt: now()}
+
+	err := m.RunInTransaction(context.Background(), func(ctx context.Context) error {
+		if err := m.RunInTransaction(ctx, func(ctx context.Context) error { return m.CreateProduct(ctx, p) }); err != nil {
+			return err
+		}
+		return errors.New("falha depois da interna")
+	})
+	if err == nil {
+		t.Fatal("a transação deveria falhar")
+	}
+	if _, err := m.GetProductByID(context.Background(), p.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("a interna faz parte da externa e deveria ser desfeita: %v", err)
+	}
+}
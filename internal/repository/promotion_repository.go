@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ---------------------------------------------------------
+// PROMOÇÕES E CUPONS
+// ---------------------------------------------------------
+
+// GetAllPromotions: são poucas; o service filtra as que valem agora
+func (r *MongoStoreRepository) GetAllPromotions(ctx context.Context) ([]models.Promotion, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetAllPromotions")
+	defer cancel()
+
+	cursor, err := r.db.Collection("promotions").Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var promotions []models.Promotion
+	err = cursor.All(ctx, &promotions)
+	return promotions, err
+}
+
+func (r *MongoStoreRepository) GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetPromotionByCode")
+	defer cancel()
+
+	var promotion models.Promotion
+	err := r.db.Collection("promotions").FindOne(ctx, bson.M{"code": code}).Decode(&promotion)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &promotion, nil
+}
+
+func (r *MongoStoreRepository) CreatePromotion(ctx context.Context, promotion models.Promotion) error {
+	ctx, cancel := r.Timeouts.write(ctx, "CreatePromotion")
+	defer cancel()
+
+	_, err := r.db.Collection("promotions").InsertOne(ctx, promotion)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateCode // índice promotions.code_unique (migração 8)
+	}
+	return err
+}
+
+func (r *MongoStoreRepository) SetPromotionActive(ctx context.Context, id primitive.ObjectID, active bool) error {
+	ctx, cancel := r.Timeouts.write(ctx, "SetPromotionActive")
+	defer cancel()
+
+	_, err := r.db.Collection("promotions").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"active": active}})
+	return err
+}
+
+func (r *MongoStoreRepository) DeletePromotion(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := r.Timeouts.write(ctx, "DeletePromotion")
+	defer cancel()
+
+	_, err := r.db.Collection("promotions").DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *MongoStoreRepository) CountPromotionRedemptions(ctx context.Context, promotionID, userID primitive.ObjectID) (int, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "CountPromotionRedemptions")
+	defer cancel()
+
+	n, err := r.db.Collection("promotion_redemptions").CountDocuments(ctx, bson.M{"promotion_id": promotionID, "user_id": userID})
+	return int(n), err
+}
+
+// RedeemPromotion roda dentro da transação do checkout: se o pedido não for
+// gravado, o uso também não conta
+func (r *MongoStoreRepository) RedeemPromotion(ctx context.Context, redemption models.PromotionRedemption) error {
+	ctx, cancel := r.Timeouts.write(ctx, "RedeemPromotion")
+	defer cancel()
+
+	promotions := r.db.Collection("promotions")
+	redemptions := r.db.Collection("promotion_redemptions")
+
+	var promotion models.Promotion
+	if err := promotions.FindOne(ctx, bson.M{"_id": redemption.PromotionID}).Decode(&promotion); err != nil {
+		return notFound(err)
+	}
+
+	if promotion.MaxUsesPerCustomer > 0 {
+		n, err := redemptions.CountDocuments(ctx, bson.M{"promotion_id": redemption.PromotionID, "user_id": redemption.UserID})
+		if err != nil {
+			return err
+		}
+		if int(n) >= promotion.MaxUsesPerCustomer {
+			return ErrPromotionLimitReached
+		}
+	}
+
+	// O limite global vai no filtro: dois checkouts ao mesmo tempo não passam
+	// do MaxUses (como o DecrementStock)
+	filter := bson.M{"_id": redemption.PromotionID}
+	if promotion.MaxUses > 0 {
+		filter["uses"] = bson.M{"$lt": promotion.MaxUses}
+	}
+	res, err := promotions.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"uses": 1}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrPromotionLimitReached
+	}
+
+	if redemption.ID.IsZero() {
+		redemption.ID = primitive.NewObjectID()
+	}
+	_, err = redemptions.InsertOne(ctx, redemption)
+	return err
+}
+
+func (r *MongoStoreRepository) ReleasePromotionRedemptions(ctx context.Context, orderID primitive.ObjectID) error {
+	ctx, cancel := r.Timeouts.write(ctx, "ReleasePromotionRedemptions")
+	defer cancel()
+
+	redemptions := r.db.Collection("promotion_redemptions")
+	cursor, err := redemptions.Find(ctx, bson.M{"order_id": orderID})
+	if err != nil {
+		return err
+	}
+	var used []models.PromotionRedemption
+	if err := cursor.All(ctx, &used); err != nil {
+		return err
+	}
+
+	for _, u := range used {
+		_, err := r.db.Collection("promotions").UpdateOne(ctx,
+			bson.M{"_id": u.PromotionID, "uses": bson.M{"$gt": 0}},
+			bson.M{"$inc": bson.M{"uses": -1}})
+		if err != nil {
+			return err
+		}
+	}
+	_, err = redemptions.DeleteMany(ctx, bson.M{"order_id": orderID})
+	return err
+}
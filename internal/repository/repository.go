@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrNotFound: o produto/pedido/carrinho procurado não existe
+	ErrNotFound = errors.New("registro não encontrado")
+	// ErrUserNotFound: nenhum usuário com esse e-mail/ID
+	ErrUserNotFound = errors.New("usuário não encontrado")
+	// ErrDuplicateEmail: já existe usuário com esse e-mail (índice único)
+	ErrDuplicateEmail = errors.New("e-mail já cadastrado")
+	// ErrDuplicateSKU: o SKU de uma variante já é usado por outro produto
+	ErrDuplicateSKU = errors.New("SKU já usado por outro produto")
+	// ErrDuplicateSlug: já existe categoria/coleção com esse slug
+	ErrDuplicateSlug = errors.New("slug já usado")
+	// ErrDuplicateCode: já existe cupom com esse código
+	ErrDuplicateCode = errors.New("código de cupom já usado")
+	// ErrPromotionLimitReached: a promoção já foi usada o máximo de vezes
+	// (no total ou por este cliente)
+	ErrPromotionLimitReached = errors.New("limite de uso da promoção atingido")
+)
+
+// Os serviços dependem só destas interfaces. Há duas implementações:
+// Mongo (MongoStoreRepository/MongoUserRepository) e memória (MemoryStore),
+// e as duas passam pela mesma suíte de contrato (contract_test.go).
+
+type ProductRepository interface {
+	GetAllProducts(ctx context.Context) ([]models.Product, error)
+	GetProductByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error)
+	CreateProduct(ctx context.Context, product models.Product) error
+	EditProduct(ctx context.Context, id primitive.ObjectID, product models.Product) error
+	DeleteProduct(ctx context.Context, id primitive.ObjectID) error
+	// SearchProducts devolve a página pedida (Skip/Limit) e o total encontrado
+	SearchProducts(ctx context.Context, q ProductSearch) ([]models.Product, int, error)
+
+	// DecrementStock baixa quantity unidades de uma vez, só se houver estoque
+	// para tudo; senão devolve ErrInsufficientStock e não mexe em nada.
+	// Com sku, baixa da variante (e do total do produto); sem sku, do produto.
+	DecrementStock(ctx context.Context, id primitive.ObjectID, sku string, quantity int) error
+	IncrementStock(ctx context.Context, id primitive.ObjectID, sku string, quantity int) error
+}
+
+// CatalogRepository: árvore de categorias e coleções da vitrine.
+// Listas de categorias e coleções vêm ordenadas pelo nome.
+type CatalogRepository interface {
+	GetAllCategories(ctx context.Context) ([]models.Category, error)
+	// CreateCategory devolve ErrDuplicateSlug se o slug já existir
+	CreateCategory(ctx context.Context, category models.Category) error
+	// DeleteCategory também tira a categoria dos produtos
+	DeleteCategory(ctx context.Context, id primitive.ObjectID) error
+	// GetProductsByCategories: produtos em qualquer uma das categorias
+	GetProductsByCategories(ctx context.Context, ids []primitive.ObjectID) ([]models.Product, error)
+
+	GetAllCollections(ctx context.Context) ([]models.Collection, error)
+	GetCollectionBySlug(ctx context.Context, slug string) (*models.Collection, error)
+	CreateCollection(ctx context.Context, collection models.Collection) error
+	EditCollection(ctx context.Context, id primitive.ObjectID, collection models.Collection) error
+	DeleteCollection(ctx context.Context, id primitive.ObjectID) error
+	// GetProductsByIDs ignora IDs que não existem mais (produto excluído)
+	GetProductsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Product, error)
+}
+
+// CartRepository: o carrinho fica dentro do documento do usuário.
+// Uma linha é identificada pelo produto + SKU; itens sem SKU (produtos sem
+// variantes) usam o tamanho, e tamanho vazio pega todas as linhas do produto.
+type CartRepository interface {
+	GetUserWithCart(ctx context.Context, userID primitive.ObjectID) (*models.User, error)
+	// AddItemToCart soma a quantidade à linha que já existe (ou cria a linha),
+	// sem passar de maxQuantity (o estoque); maxQuantity <= 0 é ErrInsufficientStock
+	AddItemToCart(ctx context.Context, userID primitive.ObjectID, item models.OrderItem, maxQuantity int) error
+	RemoveItemFromCart(ctx context.Context, userID, productID primitive.ObjectID, sku, size string) error
+	UpdateCartItemQuantity(ctx context.Context, userID, productID primitive.ObjectID, quantity int, sku, size string) error
+	RemoveItemsFromCart(ctx context.Context, userID primitive.ObjectID, items []models.OrderItem) error
+	// RepriceCartItem grava preço, nome e imagem atuais na linha do item
+	// (identificada por produto + SKU/tamanho); a quantidade não muda
+	RepriceCartItem(ctx context.Context, userID primitive.ObjectID, item models.OrderItem) error
+}
+
+// GuestCartRepository: carrinhos de visitantes (coleção carts), com as
+// mesmas regras de linha do CartRepository.
+type GuestCartRepository interface {
+	// GetGuestCart devolve ErrNotFound se o carrinho não existe ou já expirou
+	GetGuestCart(ctx context.Context, id primitive.ObjectID) (*models.GuestCart, error)
+	// AddItemToGuestCart cria o carrinho se preciso e adia a expiração para expiresAt
+	AddItemToGuestCart(ctx context.Context, id primitive.ObjectID, item models.OrderItem, maxQuantity int, expiresAt time.Time) error
+	RemoveItemFromGuestCart(ctx context.Context, id, productID primitive.ObjectID, sku, size string) error
+	UpdateGuestCartItemQuantity(ctx context.Context, id, productID primitive.ObjectID, quantity int, sku, size string) error
+	RepriceGuestCartItem(ctx context.Context, id primitive.ObjectID, item models.OrderItem) error
+	DeleteGuestCart(ctx context.Context, id primitive.ObjectID) error
+}
+
+// PromotionRepository: cupons e promoções automáticas, e os usos de cada uma
+// (coleção promotion_redemptions)
+type PromotionRepository interface {
+	// GetAllPromotions: as mais novas primeiro
+	GetAllPromotions(ctx context.Context) ([]models.Promotion, error)
+	// GetPromotionByCode devolve ErrNotFound se não houver cupom com o código
+	GetPromotionByCode(ctx context.Context, code string) (*models.Promotion, error)
+	// CreatePromotion devolve ErrDuplicateCode se o código já existir
+	CreatePromotion(ctx context.Context, promotion models.Promotion) error
+	SetPromotionActive(ctx context.Context, id primitive.ObjectID, active bool) error
+	// DeletePromotion mantém os usos registrados (auditoria dos pedidos)
+	DeletePromotion(ctx context.Context, id primitive.ObjectID) error
+
+	CountPromotionRedemptions(ctx context.Context, promotionID, userID primitive.ObjectID) (int, error)
+	// RedeemPromotion soma um uso e grava o registro, sem passar de MaxUses
+	// nem de MaxUsesPerCustomer (ErrPromotionLimitReached). Promoção
+	// excluída é ErrNotFound.
+	RedeemPromotion(ctx context.Context, redemption models.PromotionRedemption) error
+	// ReleasePromotionRedemptions devolve os usos de um pedido cancelado/expirado
+	ReleasePromotionRedemptions(ctx context.Context, orderID primitive.ObjectID) error
+}
+
+// ShippingRepository: formas de entrega e as tabelas de frete de cada uma
+type ShippingRepository interface {
+	// GetShippingMethods: na ordem em que foram criadas
+	GetShippingMethods(ctx context.Context) ([]models.ShippingMethod, error)
+	CreateShippingMethod(ctx context.Context, method models.ShippingMethod) error
+	SetShippingMethodActive(ctx context.Context, id primitive.ObjectID, active bool) error
+	DeleteShippingMethod(ctx context.Context, id primitive.ObjectID) error
+}
+
+type OrderRepository interface {
+	CreateOrder(ctx context.Context, order models.Order) error
+	GetOrderByID(ctx context.Context, orderID primitive.ObjectID) (*models.Order, error)
+	GetAllOrders(ctx context.Context) ([]models.Order, error)
+	GetOrderByPaymentID(ctx context.Context, paymentID string) (*models.Order, error)
+	GetExpiredReservations(ctx context.Context, now time.Time) ([]models.Order, error)
+
+	// TransitionOrderStatus só troca se o status atual for change.From;
+	// senão devolve ErrOrderStatusChanged.
+	TransitionOrderStatus(ctx context.Context, orderID primitive.ObjectID, change models.OrderStatusChange) error
+}
+
+// PaymentEventRepository guarda os webhooks já processados (idempotência)
+type PaymentEventRepository interface {
+	PaymentEventExists(ctx context.Context, eventID string) (bool, error)
+	SavePaymentEvent(ctx context.Context, event models.PaymentEvent) error
+}
+
+// Transactor executa fn de forma atômica: se fn falhar, nada do que foi
+// feito com o ctx recebido fica gravado.
+type Transactor interface {
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// StoreRepository é tudo o que o StoreService usa
+type StoreRepository interface {
+	ProductRepository
+	CatalogRepository
+	CartRepository
+	GuestCartRepository
+	PromotionRepository
+	ShippingRepository
+	OrderRepository
+	PaymentEventRepository
+	Transactor
+}
+
+// SessionRepository guarda as sessões de login pelo hash do token
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session models.Session) error
+	// GetSessionByTokenHash devolve ErrNotFound se não houver sessão ativa (não revogada)
+	GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error)
+	TouchSession(ctx context.Context, tokenHash string, seenAt time.Time) error
+	RevokeSession(ctx context.Context, tokenHash string) error
+}
+
+type UserRepository interface {
+	// CreateUser devolve ErrDuplicateEmail se o e-mail já estiver em uso
+	CreateUser(ctx context.Context, user models.User) error
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	SetAdmin(ctx context.Context, id primitive.ObjectID, isAdmin bool) error
+	GetOrdersByEmail(ctx context.Context, email string) ([]models.Order, error)
+	// GetOrdersByUserID lista os pedidos da conta (o e-mail do pedido é digitado no checkout)
+	GetOrdersByUserID(ctx context.Context, userID primitive.ObjectID) ([]models.Order, error)
+}
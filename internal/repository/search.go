@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SearchSort é a ordenação dos resultados da busca
+type SearchSort string
+
+const (
+	SortRelevance SearchSort = "relevance" // <--- sem texto, cai em SortNewest
+	SortPriceAsc  SearchSort = "price_asc"
+	SortPriceDesc SearchSort = "price_desc"
+	SortNewest    SearchSort = "newest"
+)
+
+// ProductSearch são os filtros da busca; valor zero = sem aquele filtro.
+// Text segue a sintaxe do $text do Mongo: termos separados por espaço
+// (basta um bater) e "-termo" para excluir.
+type ProductSearch struct {
+	Text        string
+	MinPrice    int64 // centavos
+	MaxPrice    int64
+	InStockOnly bool
+	Size        string // tamanho legado (Sizes) ou valor de opção de variante
+	CategoryIDs []primitive.ObjectID
+	Sort        SearchSort
+
+	Skip  int
+	Limit int
+}
+
+// SearchProducts: busca textual (índice products_text, migração 5) com filtros.
+// Devolve a página pedida e o total de produtos encontrados.
+func (r *MongoStoreRepository) SearchProducts(ctx context.Context, q ProductSearch) ([]models.Product, int, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "SearchProducts")
+	defer cancel()
+
+	coll := r.db.Collection("products")
+	filter := searchFilter(q)
+
+	total, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSort(searchSort(q)).SetSkip(int64(q.Skip))
+	if q.Limit > 0 {
+		opts.SetLimit(int64(q.Limit))
+	}
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var products []models.Product
+	err = cursor.All(ctx, &products)
+	return products, int(total), err
+}
+
+func searchFilter(q ProductSearch) bson.D {
+	filter := bson.D{}
+	if strings.TrimSpace(q.Text) != "" {
+		filter = append(filter, bson.E{Key: "$text", Value: bson.M{"$search": q.Text}})
+	}
+
+	price := bson.M{}
+	if q.MinPrice > 0 {
+		price["$gte"] = q.MinPrice
+	}
+	if q.MaxPrice > 0 {
+		price["$lte"] = q.MaxPrice
+	}
+	if len(price) > 0 {
+		filter = append(filter, bson.E{Key: "price", Value: price})
+	}
+
+	if q.InStockOnly {
+		filter = append(filter, bson.E{Key: "stock", Value: bson.M{"$gt": 0}})
+	}
+
+	if q.Size != "" {
+		// Sem diferenciar maiúsculas: "m" acha o tamanho "M"
+		exact := bson.M{"$regex": "^" + regexp.QuoteMeta(q.Size) + "$", "$options": "i"}
+		variant := bson.M{"options.value": exact}
+		if q.InStockOnly {
+			variant["stock"] = bson.M{"$gt": 0} // <--- a variante daquele tamanho precisa ter estoque
+		}
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.M{"sizes": exact},
+			bson.M{"variants": bson.M{"$elemMatch": variant}},
+		}})
+	}
+
+	if len(q.CategoryIDs) > 0 {
+		filter = append(filter, bson.E{Key: "category_ids", Value: bson.M{"$in": q.CategoryIDs}})
+	}
+	return filter
+}
+
+// searchSort desempata sempre pelo _id para a paginação ser estável
+func searchSort(q ProductSearch) bson.D {
+	switch q.Sort {
+	case SortPriceAsc:
+		return bson.D{{Key: "price", Value: 1}, {Key: "_id", Value: 1}}
+	case SortPriceDesc:
+		return bson.D{{Key: "price", Value: -1}, {Key: "_id", Value: 1}}
+	case SortRelevance:
+		if strings.TrimSpace(q.Text) != "" {
+			return bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "_id", Value: 1}}
+		}
+	}
+	return bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+}
+
+var foldAccents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// FoldText deixa o texto em minúsculas e sem acentos, como o índice de
+// texto do Mongo compara ("Calção" bate com "calcao")
+func FoldText(s string) string {
+	return foldAccents.Replace(strings.ToLower(s))
+}
+
+// SearchTerms separa a busca em termos já normalizados; os precedidos
+// de "-" vão para excluded
+func SearchTerms(text string) (terms, excluded []string) {
+	for _, field := range strings.FieldsFunc(FoldText(text), isSearchSeparator) {
+		if neg, ok := strings.CutPrefix(field, "-"); ok {
+			if neg != "" {
+				excluded = append(excluded, neg)
+			}
+			continue
+		}
+		if term := strings.Trim(field, "-"); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms, excluded
+}
+
+func isSearchSeparator(r rune) bool {
+	return !(r == '-' || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r > 127)
+}
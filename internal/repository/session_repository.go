@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoSessionRepository struct {
+	db *mongo.Database
+
+	Timeouts TimeoutPolicy
+}
+
+var _ SessionRepository = (*MongoSessionRepository)(nil)
+
+func NewSessionRepository(db *mongo.Database) *MongoSessionRepository {
+	return &MongoSessionRepository{db: db, Timeouts: DefaultTimeoutPolicy()}
+}
+
+// CreateSession grava uma nova sessão
+func (sr *MongoSessionRepository) CreateSession(ctx context.Context, session models.Session) error {
+	ctx, cancel := sr.Timeouts.write(ctx, "CreateSession")
+	defer cancel()
+
+	coll := sr.db.Collection("sessions")
+	_, err := coll.InsertOne(ctx, session)
+	return err
+}
+
+// GetSessionByTokenHash busca uma sessão ainda não revogada pelo hash do token
+func (sr *MongoSessionRepository) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	ctx, cancel := sr.Timeouts.read(ctx, "GetSessionByTokenHash")
+	defer cancel()
+
+	coll := sr.db.Collection("sessions")
+	filter := bson.M{"token_hash": tokenHash, "revoked_at": bson.M{"$exists": false}}
+
+	var session models.Session
+	err := coll.FindOne(ctx, filter).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// TouchSession atualiza o último acesso (usado pelo idle timeout)
+func (sr *MongoSessionRepository) TouchSession(ctx context.Context, tokenHash string, seenAt time.Time) error {
+	ctx, cancel := sr.Timeouts.write(ctx, "TouchSession")
+	defer cancel()
+
+	coll := sr.db.Collection("sessions")
+	filter := bson.M{"token_hash": tokenHash}
+	update := bson.M{"$set": bson.M{"last_seen_at": seenAt}}
+
+	_, err := coll.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// RevokeSession marca a sessão como revogada (logout ou expiração)
+func (sr *MongoSessionRepository) RevokeSession(ctx context.Context, tokenHash string) error {
+	ctx, cancel := sr.Timeouts.write(ctx, "RevokeSession")
+	defer cancel()
+
+	coll := sr.db.Collection("sessions")
+	filter := bson.M{"token_hash": tokenHash, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+
+	_, err := coll.UpdateOne(ctx, filter, update)
+	return err
+}
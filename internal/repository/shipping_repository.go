@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ---------------------------------------------------------
+// FRETE
+// ---------------------------------------------------------
+
+// GetShippingMethods: são poucas; o service filtra as ativas e cota o CEP
+func (r *MongoStoreRepository) GetShippingMethods(ctx context.Context) ([]models.ShippingMethod, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetShippingMethods")
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.db.Collection("shipping_methods").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var methods []models.ShippingMethod
+	err = cursor.All(ctx, &methods)
+	return methods, err
+}
+
+func (r *MongoStoreRepository) CreateShippingMethod(ctx context.Context, method models.ShippingMethod) error {
+	ctx, cancel := r.Timeouts.write(ctx, "CreateShippingMethod")
+	defer cancel()
+
+	_, err := r.db.Collection("shipping_methods").InsertOne(ctx, method)
+	return err
+}
+
+func (r *MongoStoreRepository) SetShippingMethodActive(ctx context.Context, id primitive.ObjectID, active bool) error {
+	ctx, cancel := r.Timeouts.write(ctx, "SetShippingMethodActive")
+	defer cancel()
+
+	_, err := r.db.Collection("shipping_methods").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"active": active}})
+	return err
+}
+
+func (r *MongoStoreRepository) DeleteShippingMethod(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := r.Timeouts.write(ctx, "DeleteShippingMethod")
+	defer cancel()
+
+	_, err := r.db.Collection("shipping_methods").DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
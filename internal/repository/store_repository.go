@@ -3,22 +3,41 @@ package repository
 import (
 	"context"
 	"errors"
+	"maps"
 	"time"
 
 	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInsufficientStock  = errors.New("estoque insuficiente para realizar a compra")
+	ErrOrderStatusChanged = errors.New("o status do pedido mudou antes da atualização")
 )
 
 // Struct que segura a conexão com o banco
-type StoreRepository struct {
+type MongoStoreRepository struct {
 	db *mongo.Database
+
+	Timeouts TimeoutPolicy
 }
 
+var _ StoreRepository = (*MongoStoreRepository)(nil)
+
 // Construtor para injetar a dependência do banco
-func NewStoreRepository(db *mongo.Database) *StoreRepository {
-	return &StoreRepository{db: db}
+func NewStoreRepository(db *mongo.Database) *MongoStoreRepository {
+	return &MongoStoreRepository{db: db, Timeouts: DefaultTimeoutPolicy()}
+}
+
+// notFound traduz o "nenhum documento" do driver para o erro do pacote
+func notFound(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
 }
 
 // ---------------------------------------------------------
@@ -26,165 +45,266 @@ func NewStoreRepository(db *mongo.Database) *StoreRepository {
 // ---------------------------------------------------------
 
 // GetAllProducts: Lista todos os produtos para a Home e Admin
-func (r *StoreRepository) GetAllProducts() ([]models.Product, error) {
+func (r *MongoStoreRepository) GetAllProducts(ctx context.Context) ([]models.Product, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetAllProducts")
+	defer cancel()
+
 	coll := r.db.Collection("products")
 
 	// Busca sem filtro (todos)
-	cursor, err := coll.Find(context.Background(), bson.M{})
+	cursor, err := coll.Find(ctx, bson.M{})
 	if err != nil {
 		return nil, err
 	}
 
 	var products []models.Product
-	err = cursor.All(context.Background(), &products)
+	err = cursor.All(ctx, &products)
 	return products, err
 }
 
 // GetProductByID: Busca detalhes de um produto específico
-func (r *StoreRepository) GetProductByID(id primitive.ObjectID) (*models.Product, error) {
+func (r *MongoStoreRepository) GetProductByID(ctx context.Context, id primitive.ObjectID) (*models.Product, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetProductByID")
+	defer cancel()
+
 	coll := r.db.Collection("products")
 
 	var product models.Product
-	err := coll.FindOne(context.Background(), bson.M{"_id": id}).Decode(&product)
+	err := coll.FindOne(ctx, bson.M{"_id": id}).Decode(&product)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 	return &product, nil
 }
 
 // CreateProduct: Usado pelo Admin para cadastrar novos itens
-func (r *StoreRepository) CreateProduct(product models.Product) error {
+func (r *MongoStoreRepository) CreateProduct(ctx context.Context, product models.Product) error {
+	ctx, cancel := r.Timeouts.write(ctx, "CreateProduct")
+	defer cancel()
+
 	coll := r.db.Collection("products")
-	_, err := coll.InsertOne(context.Background(), product)
+	_, err := coll.InsertOne(ctx, product)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateSKU // índice products.variants_sku_unique (migração 3)
+	}
 	return err
 }
 
 // EditProduct
-func (r *StoreRepository) EditProduct(ID primitive.ObjectID, product models.Product) error {
+func (r *MongoStoreRepository) EditProduct(ctx context.Context, ID primitive.ObjectID, product models.Product) error {
+	ctx, cancel := r.Timeouts.write(ctx, "EditProduct")
+	defer cancel()
+
 	coll := r.db.Collection("products")
 	filter := bson.M{"_id": ID}
-	_, err := coll.ReplaceOne(context.Background(), filter, product)
+	_, err := coll.ReplaceOne(ctx, filter, product)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateSKU
+	}
 	return err
 }
 
-// DecrementStock: Baixa o estoque de forma atômica e segura
-func (r *StoreRepository) DecrementStock(id primitive.ObjectID) error {
+// DecrementStock: Baixa o estoque de forma atômica e segura, em uma única
+// operação por item ($inc pela quantidade comprada).
+// Recebe o ctx da transação do checkout (ver RunInTransaction).
+func (r *MongoStoreRepository) DecrementStock(ctx context.Context, id primitive.ObjectID, sku string, quantity int) error {
+	ctx, cancel := r.Timeouts.write(ctx, "DecrementStock")
+	defer cancel()
+
 	coll := r.db.Collection("products")
 
-	// O filtro é o segredo: Só atualiza SE o ID bater E se o estoque for maior que 0.
+	// O filtro é o segredo: Só atualiza SE o ID bater E se houver estoque para a quantidade toda.
 	// Isso impede que o estoque fique negativo se dois clientes comprarem ao mesmo tempo.
-	filter := bson.M{"_id": id, "stock": bson.M{"$gt": 0}}
-	update := bson.M{"$inc": bson.M{"stock": -1}}
+	filter := bson.M{"_id": id, "stock": bson.M{"$gte": quantity}}
+	update := bson.M{"$inc": bson.M{"stock": -quantity}}
 
-	result, err := coll.UpdateOne(context.Background(), filter, update)
+	// Variante: o estoque que vale é o dela; o total do produto acompanha
+	if sku != "" {
+		filter = bson.M{"_id": id, "variants": bson.M{"$elemMatch": bson.M{"sku": sku, "stock": bson.M{"$gte": quantity}}}}
+		update = bson.M{"$inc": bson.M{"variants.$.stock": -quantity, "stock": -quantity}}
+	}
+
+	result, err := coll.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
 	}
 
-	// Se nenhum documento foi modificado, significa que o estoque já era 0
-	// ou o produto não existe.
+	// Se nenhum documento foi modificado, significa que o estoque não é suficiente
+	// ou o produto (ou a variante) não existe.
 	if result.ModifiedCount == 0 {
-		return errors.New("estoque insuficiente para realizar a compra")
+		return ErrInsufficientStock
 	}
 
 	return nil
 }
 
+// IncrementStock: Devolve unidades ao estoque (ex: reserva PIX expirada)
+func (r *MongoStoreRepository) IncrementStock(ctx context.Context, id primitive.ObjectID, sku string, quantity int) error {
+	ctx, cancel := r.Timeouts.write(ctx, "IncrementStock")
+	defer cancel()
+
+	coll := r.db.Collection("products")
+	filter := bson.M{"_id": id}
+	update := bson.M{"$inc": bson.M{"stock": quantity}}
+	if sku != "" {
+		filter["variants.sku"] = sku
+		update = bson.M{"$inc": bson.M{"variants.$.stock": quantity, "stock": quantity}}
+	}
+	_, err := coll.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// RunInTransaction executa fn dentro de uma transação multi-documento do MongoDB.
+// Se fn retornar erro, tudo o que foi feito com o ctx recebido é desfeito.
+// Atenção: transações exigem que o MongoDB rode como replica set.
+func (r *MongoStoreRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := r.Timeouts.transaction(ctx)
+	defer cancel()
+
+	session, err := r.db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	// Encerra a sessão mesmo que o ctx já tenha sido cancelado
+	defer session.EndSession(context.WithoutCancel(ctx))
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
 // ---------------------------------------------------------
 // MÉTODOS DE PEDIDOS
 // ---------------------------------------------------------
 
-// CreateOrder: Salva o pedido finalizado no banco
-func (r *StoreRepository) CreateOrder(order models.Order) error {
+// CreateOrder: Salva o pedido finalizado no banco (dentro da transação do checkout)
+func (r *MongoStoreRepository) CreateOrder(ctx context.Context, order models.Order) error {
+	ctx, cancel := r.Timeouts.write(ctx, "CreateOrder")
+	defer cancel()
+
 	coll := r.db.Collection("orders")
-	_, err := coll.InsertOne(context.Background(), order)
+	_, err := coll.InsertOne(ctx, order)
 	return err
 }
 
-func (r *StoreRepository) AddItemToCart(userID primitive.ObjectID, item models.OrderItem) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// RemoveItemsFromCart: Tira do carrinho, numa única operação, os itens comprados
+func (r *MongoStoreRepository) RemoveItemsFromCart(ctx context.Context, userID primitive.ObjectID, items []models.OrderItem) error {
+	ctx, cancel := r.Timeouts.write(ctx, "RemoveItemsFromCart")
 	defer cancel()
 
+	if len(items) == 0 {
+		return nil
+	}
+
 	userColl := r.db.Collection("users")
 
+	var match []bson.M
+	for _, item := range items {
+		match = append(match, cartLineMatch(item.ProductID, item.SKU, item.Size))
+	}
+
 	filter := bson.M{"_id": userID}
-	update := bson.M{"$push": bson.M{"cart": item}}
+	update := bson.M{"$pull": bson.M{"cart": bson.M{"$or": match}}}
 
 	_, err := userColl.UpdateOne(ctx, filter, update)
 	return err
 }
 
-func (r *StoreRepository) RemoveItemFromCart(userID primitive.ObjectID, productID primitive.ObjectID, size string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoStoreRepository) AddItemToCart(ctx context.Context, userID primitive.ObjectID, item models.OrderItem, maxQuantity int) error {
+	ctx, cancel := r.Timeouts.write(ctx, "AddItemToCart")
 	defer cancel()
 
-	userColl := r.db.Collection("users")
-
-	// Remove o item do array 'cart' onde o product_id bate E o tamanho bate (se tiver tamanho)
-	// Se size for vazio, removemos onde product_id bate e size é vazio ou não existe?
-	// Simplificação: Removemos exatamente o que veio.
+	// Usuário inexistente: nenhum passo casa e nada é gravado
+	return addCartLine(ctx, r.db.Collection("users"), userID, item, maxQuantity)
+}
 
-	filter := bson.M{"_id": userID}
+// addCartLine soma item à linha do carrinho (campo "cart") do documento
+// ownerID, ou cria a linha, sem passar de maxQuantity. Vale para users e carts.
+func addCartLine(ctx context.Context, coll *mongo.Collection, ownerID primitive.ObjectID, item models.OrderItem, maxQuantity int) error {
+	if maxQuantity <= 0 {
+		return ErrInsufficientStock
+	}
+	item.Quantity = min(item.Quantity, maxQuantity)
+	line := cartLineMatch(item.ProductID, item.SKU, item.Size)
+
+	// Cada passo é atômico. Se outra requisição criar a linha entre o $inc
+	// e o $push, o $push não casa e a segunda volta encontra a linha.
+	for range 2 {
+		// 1. A linha existe e a soma cabe no estoque: incrementa
+		withRoom := maps.Clone(line)
+		withRoom["quantity"] = bson.M{"$lte": maxQuantity - item.Quantity}
+		res, err := coll.UpdateOne(ctx,
+			bson.M{"_id": ownerID, "cart": bson.M{"$elemMatch": withRoom}},
+			bson.M{"$inc": bson.M{"cart.$.quantity": item.Quantity}})
+		if err != nil || res.MatchedCount > 0 {
+			return err
+		}
+
+		// 2. A linha existe, mas a soma passaria do estoque: fica no máximo
+		res, err = coll.UpdateOne(ctx,
+			bson.M{"_id": ownerID, "cart": bson.M{"$elemMatch": line}},
+			bson.M{"$set": bson.M{"cart.$.quantity": maxQuantity}})
+		if err != nil || res.MatchedCount > 0 {
+			return err
+		}
+
+		// 3. Linha nova
+		res, err = coll.UpdateOne(ctx,
+			bson.M{"_id": ownerID, "cart": bson.M{"$not": bson.M{"$elemMatch": line}}},
+			bson.M{"$push": bson.M{"cart": item}})
+		if err != nil || res.MatchedCount > 0 {
+			return err
+		}
+	}
+	return nil
+}
 
-	// $pull remove todos os itens que dão match no critério
-	pullFilter := bson.M{"product_id": productID}
-	if size != "" {
-		pullFilter["size"] = size
-	} else {
-		// Se não tem tamanho, talvez devêssemos remover itens sem tamanho?
-		// Ou remover qualquer um desse produto?
-		// Vamos assumir que se size é vazio, removemos itens onde size é vazio ou null
-		// Mas o $pull simples já funciona se passarmos o objeto exato.
-		// Vamos usar o filtro composto.
-	}
-
-	// Se size for vazio, o pullFilter fica só com product_id, o que removeria TODOS os tamanhos desse produto.
-	// Isso é perigoso se o usuário tiver P e M e clicar remover no que não tem tamanho (se existir).
-	// Mas se o produto tem tamanho, o link sempre manda o tamanho.
-	// Se o produto NÃO tem tamanho, size vem vazio.
-	// Então se size == "", removemos itens onde size == "" ou não existe.
-
-	if size == "" {
-		// Remove itens onde product_id bate E (size não existe OU size é vazio)
-		// MongoDB query complexa para array element match.
-		// Vamos simplificar: Se size veio vazio, removemos pelo ID.
-		// Se isso apagar todos, é o comportamento esperado para produtos sem variação.
-		// O problema é se tivermos um produto com variação e o request vier sem size.
-	} else {
-		// Se tem size, removemos só aquele size.
+// cartLineMatch identifica uma linha do carrinho: pelo SKU quando o item é
+// uma variante, senão pelo tamanho
+func cartLineMatch(productID primitive.ObjectID, sku, size string) bson.M {
+	if sku != "" {
+		return bson.M{"product_id": productID, "sku": sku}
 	}
+	return bson.M{"product_id": productID, "size": size}
+}
 
-	// Melhor abordagem: Sempre tentar dar match no size se ele foi fornecido.
-	// Se não foi fornecido, removemos pelo ID (todos).
+func (r *MongoStoreRepository) RemoveItemFromCart(ctx context.Context, userID primitive.ObjectID, productID primitive.ObjectID, sku, size string) error {
+	ctx, cancel := r.Timeouts.write(ctx, "RemoveItemFromCart")
+	defer cancel()
+
+	return removeCartLine(ctx, r.db.Collection("users"), userID, productID, sku, size)
+}
 
+func removeCartLine(ctx context.Context, coll *mongo.Collection, ownerID, productID primitive.ObjectID, sku, size string) error {
+	filter := bson.M{"_id": ownerID}
+
+	// Com SKU ou tamanho, removemos só aquela linha.
+	// Sem nenhum dos dois, removemos pelo ID (todas as linhas do produto):
+	// é o comportamento esperado para produtos sem variação, e o cart.html
+	// sempre manda o SKU/tamanho quando o item tem.
 	var update bson.M
-	if size != "" {
-		update = bson.M{"$pull": bson.M{"cart": bson.M{"product_id": productID, "size": size}}}
+	if sku != "" || size != "" {
+		update = bson.M{"$pull": bson.M{"cart": cartLineMatch(productID, sku, size)}}
 	} else {
-		// Se não tem size, removemos onde product_id bate.
-		// CUIDADO: Isso remove P e M se o request vier sem size.
-		// Mas o request do cart.html sempre manda o size se ele existir no item.
 		update = bson.M{"$pull": bson.M{"cart": bson.M{"product_id": productID}}}
 	}
 
-	_, err := userColl.UpdateOne(ctx, filter, update)
+	_, err := coll.UpdateOne(ctx, filter, update)
 	return err
 }
 
-func (r *StoreRepository) UpdateCartItemQuantity(userID primitive.ObjectID, productID primitive.ObjectID, quantity int, size string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoStoreRepository) UpdateCartItemQuantity(ctx context.Context, userID primitive.ObjectID, productID primitive.ObjectID, quantity int, sku, size string) error {
+	ctx, cancel := r.Timeouts.write(ctx, "UpdateCartItemQuantity")
 	defer cancel()
 
-	userColl := r.db.Collection("users")
+	return setCartLineQuantity(ctx, r.db.Collection("users"), userID, productID, quantity, sku, size)
+}
 
+func setCartLineQuantity(ctx context.Context, coll *mongo.Collection, ownerID, productID primitive.ObjectID, quantity int, sku, size string) error {
 	filter := bson.M{
-		"_id": userID,
-		"cart": bson.M{
-			"$elemMatch": bson.M{
-				"product_id": productID,
-				"size":       size,
-			},
-		},
+		"_id":  ownerID,
+		"cart": bson.M{"$elemMatch": cartLineMatch(productID, sku, size)},
 	}
 
 	update := bson.M{
@@ -193,39 +313,175 @@ func (r *StoreRepository) UpdateCartItemQuantity(userID primitive.ObjectID, prod
 		},
 	}
 
-	_, err := userColl.UpdateOne(ctx, filter, update)
+	_, err := coll.UpdateOne(ctx, filter, update)
 	return err
 }
 
-func (r *StoreRepository) UpdateOrderStatus(orderID primitive.ObjectID, newStatus string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoStoreRepository) RepriceCartItem(ctx context.Context, userID primitive.ObjectID, item models.OrderItem) error {
+	ctx, cancel := r.Timeouts.write(ctx, "RepriceCartItem")
 	defer cancel()
 
-	coll := r.db.Collection("orders")
-	filter := bson.M{"_id": orderID}
-	update := bson.M{"$set": bson.M{"status": newStatus}}
+	return repriceCartLine(ctx, r.db.Collection("users"), userID, item)
+}
+
+func repriceCartLine(ctx context.Context, coll *mongo.Collection, ownerID primitive.ObjectID, item models.OrderItem) error {
+	filter := bson.M{
+		"_id":  ownerID,
+		"cart": bson.M{"$elemMatch": cartLineMatch(item.ProductID, item.SKU, item.Size)},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"cart.$.price":        item.Price,
+			"cart.$.product_name": item.ProductName,
+			"cart.$.image_url":    item.ImageURL,
+		},
+	}
 
 	_, err := coll.UpdateOne(ctx, filter, update)
 	return err
 }
 
-func (r *StoreRepository) GetUserWithCart(userID primitive.ObjectID) (*models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// GetOrderByID: Busca um pedido específico
+func (r *MongoStoreRepository) GetOrderByID(ctx context.Context, orderID primitive.ObjectID) (*models.Order, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetOrderByID")
+	defer cancel()
+
+	coll := r.db.Collection("orders")
+
+	var order models.Order
+	err := coll.FindOne(ctx, bson.M{"_id": orderID}).Decode(&order)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &order, nil
+}
+
+// GetAllOrders: Lista os pedidos mais recentes primeiro (painel admin)
+func (r *MongoStoreRepository) GetAllOrders(ctx context.Context) ([]models.Order, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetAllOrders")
+	defer cancel()
+
+	coll := r.db.Collection("orders")
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+
+	cursor, err := coll.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	err = cursor.All(ctx, &orders)
+	return orders, err
+}
+
+// GetOrderByPaymentID: Busca o pedido pela cobrança no provedor (webhooks)
+func (r *MongoStoreRepository) GetOrderByPaymentID(ctx context.Context, paymentID string) (*models.Order, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetOrderByPaymentID")
+	defer cancel()
+
+	coll := r.db.Collection("orders")
+
+	var order models.Order
+	err := coll.FindOne(ctx, bson.M{"payment_id": paymentID}).Decode(&order)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &order, nil
+}
+
+// TransitionOrderStatus: Troca o status só se o pedido ainda estiver em change.From
+// e registra a mudança no histórico, na mesma operação.
+// Evita, por exemplo, confirmar um PIX que o worker acabou de expirar.
+func (r *MongoStoreRepository) TransitionOrderStatus(ctx context.Context, orderID primitive.ObjectID, change models.OrderStatusChange) error {
+	ctx, cancel := r.Timeouts.write(ctx, "TransitionOrderStatus")
+	defer cancel()
+
+	coll := r.db.Collection("orders")
+	filter := bson.M{"_id": orderID, "status": change.From}
+	update := bson.M{
+		"$set":  bson.M{"status": change.To},
+		"$push": bson.M{"status_history": change},
+	}
+
+	result, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return ErrOrderStatusChanged
+	}
+	return nil
+}
+
+// GetExpiredReservations: Pedidos aguardando pagamento cuja reserva já venceu
+func (r *MongoStoreRepository) GetExpiredReservations(ctx context.Context, now time.Time) ([]models.Order, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetExpiredReservations")
+	defer cancel()
+
+	coll := r.db.Collection("orders")
+	filter := bson.M{
+		"status":         models.OrderStatusPending,
+		"reserved_until": bson.M{"$lte": now},
+	}
+
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	err = cursor.All(ctx, &orders)
+	return orders, err
+}
+
+func (r *MongoStoreRepository) GetUserWithCart(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "GetUserWithCart")
 	defer cancel()
 
 	userColl := r.db.Collection("users")
 
 	var user models.User
 	err := userColl.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
-	return &user, err
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
 }
 
 // DeleteProduct remove um produto pelo ID
-func (r *StoreRepository) DeleteProduct(id primitive.ObjectID) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoStoreRepository) DeleteProduct(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := r.Timeouts.write(ctx, "DeleteProduct")
 	defer cancel()
 
 	coll := r.db.Collection("products")
 	_, err := coll.DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
+
+// ---------------------------------------------------------
+// MÉTODOS DE EVENTOS DE PAGAMENTO (WEBHOOKS)
+// ---------------------------------------------------------
+
+// PaymentEventExists: Diz se o evento do provedor já foi processado
+func (r *MongoStoreRepository) PaymentEventExists(ctx context.Context, eventID string) (bool, error) {
+	ctx, cancel := r.Timeouts.read(ctx, "PaymentEventExists")
+	defer cancel()
+
+	coll := r.db.Collection("payment_events")
+	n, err := coll.CountDocuments(ctx, bson.M{"_id": eventID})
+	return n > 0, err
+}
+
+// SavePaymentEvent: Registra o evento processado (entregas repetidas são ignoradas)
+func (r *MongoStoreRepository) SavePaymentEvent(ctx context.Context, event models.PaymentEvent) error {
+	ctx, cancel := r.Timeouts.write(ctx, "SavePaymentEvent")
+	defer cancel()
+
+	coll := r.db.Collection("payment_events")
+	_, err := coll.InsertOne(ctx, event)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// TimeoutPolicy define quanto cada operação no banco pode durar. O prazo é
+// aplicado sobre o ctx recebido (normalmente o r.Context() do request), então
+// o que vencer primeiro — cliente desconectar ou timeout — cancela a query.
+type TimeoutPolicy struct {
+	Read        time.Duration
+	Write       time.Duration
+	Transaction time.Duration
+
+	// Overrides por nome de operação (ex: "GetAllOrders": 10s)
+	Overrides map[string]time.Duration
+}
+
+func DefaultTimeoutPolicy() TimeoutPolicy {
+	return TimeoutPolicy{
+		Read:        5 * time.Second,
+		Write:       5 * time.Second,
+		Transaction: 15 * time.Second,
+	}
+}
+
+func (p TimeoutPolicy) timeout(op string, fallback time.Duration) time.Duration {
+	if d, ok := p.Overrides[op]; ok {
+		return d
+	}
+	return fallback
+}
+
+// read/write/transaction devolvem o ctx com o prazo da operação.
+// Prazo zero significa "sem timeout próprio" (só o do ctx pai).
+func (p TimeoutPolicy) read(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, p.timeout(op, p.Read))
+}
+
+func (p TimeoutPolicy) write(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, p.timeout(op, p.Write))
+}
+
+func (p TimeoutPolicy) transaction(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, p.timeout("RunInTransaction", p.Transaction))
+}
+
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
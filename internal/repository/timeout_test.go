@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTimeoutPolicy(t *testing.T) {
+	p := TimeoutPolicy{Read: time.Second, Overrides: map[string]time.Duration{"GetAllOrders": time.Hour}}
+
+	ctx, cancel := p.read(context.Background(), "GetOrderByID")
+	defer cancel()
+	if d, ok := ctx.Deadline(); !ok || time.Until(d) > time.Second {
+		t.Errorf("leitura deveria usar o prazo de Read")
+	}
+
+	ctx, cancel = p.read(context.Background(), "GetAllOrders")
+	defer cancel()
+	if d, _ := ctx.Deadline(); time.Until(d) < 59*time.Minute {
+		t.Errorf("override por operação não aplicado")
+	}
+
+	// Write zerado: só vale o prazo do ctx pai
+	ctx, cancel = p.write(context.Background(), "CreateOrder")
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Errorf("Write zero não deveria impor prazo")
+	}
+
+	// O pai cancelado cancela a operação
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel = p.read(parent, "GetOrderByID")
+	defer cancel()
+	cancelParent()
+	if ctx.Err() == nil {
+		t.Error("cancelar o request deveria cancelar a operação")
+	}
+}
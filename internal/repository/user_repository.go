@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"errors"
 
 	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,32 +10,46 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type UserRepository struct {
+type MongoUserRepository struct {
 	db *mongo.Database
+
+	Timeouts TimeoutPolicy
 }
 
-func NewUserRepository(db *mongo.Database) *UserRepository {
-	return &UserRepository{
-		db: db,
+var _ UserRepository = (*MongoUserRepository)(nil)
+
+func NewUserRepository(db *mongo.Database) *MongoUserRepository {
+	return &MongoUserRepository{
+		db:       db,
+		Timeouts: DefaultTimeoutPolicy(),
 	}
 }
 
 // Salva um novo usuário no banco
-func (ur *UserRepository) CreateUser(user models.User) error {
+func (ur *MongoUserRepository) CreateUser(ctx context.Context, user models.User) error {
+	ctx, cancel := ur.Timeouts.write(ctx, "CreateUser")
+	defer cancel()
+
 	coll := ur.db.Collection("users")
-	_, err := coll.InsertOne(context.Background(), user)
+	_, err := coll.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicateEmail // índice users.email_unique (migração 1)
+	}
 	return err
 }
 
 // Busca usuário por Email (Usado no Login)
-func (ur *UserRepository) GetUserByEmail(email string) (*models.User, error) {
+func (ur *MongoUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := ur.Timeouts.read(ctx, "GetUserByEmail")
+	defer cancel()
+
 	coll := ur.db.Collection("users")
 	var user models.User
 
-	err := coll.FindOne(context.TODO(), bson.M{"email": email}).Decode(&user)
+	err := coll.FindOne(ctx, bson.M{"email": email}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("usuário não encontrado")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -44,33 +57,68 @@ func (ur *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 }
 
 // Busca usuário por ID
-func (ur *UserRepository) GetUserByID(id primitive.ObjectID) (*models.User, error) {
+func (ur *MongoUserRepository) GetUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	ctx, cancel := ur.Timeouts.read(ctx, "GetUserByID")
+	defer cancel()
+
 	coll := ur.db.Collection("users")
 	var user models.User
 
-	err := coll.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&user)
+	err := coll.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("usuário não encontrado")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
 	return &user, nil
 }
 
+// Promove um usuário existente a admin (usado no bootstrap pela CLI)
+func (ur *MongoUserRepository) SetAdmin(ctx context.Context, id primitive.ObjectID, isAdmin bool) error {
+	ctx, cancel := ur.Timeouts.write(ctx, "SetAdmin")
+	defer cancel()
+
+	coll := ur.db.Collection("users")
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"is_admin": isAdmin}}
+
+	_, err := coll.UpdateOne(ctx, filter, update)
+	return err
+}
+
 // Busca todos os pedidos de um email específico (Para o Dashboard)
-func (ur *UserRepository) GetOrdersByEmail(email string) ([]models.Order, error) {
+func (ur *MongoUserRepository) GetOrdersByEmail(ctx context.Context, email string) ([]models.Order, error) {
+	ctx, cancel := ur.Timeouts.read(ctx, "GetOrdersByEmail")
+	defer cancel()
+
 	coll := ur.db.Collection("orders")
 
 	// Filtra onde customer_email é igual ao email do usuário
 	// Ordena por data decrescente (mais recentes primeiro)
 	opts := options.Find().SetSort(bson.M{"created_at": -1})
-	cursor, err := coll.Find(context.Background(), bson.M{"customer_email": email}, opts)
+	cursor, err := coll.Find(ctx, bson.M{"customer_email": email}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	err = cursor.All(ctx, &orders)
+	return orders, err
+}
+
+// Busca os pedidos feitos pela conta, mais recentes primeiro
+func (ur *MongoUserRepository) GetOrdersByUserID(ctx context.Context, userID primitive.ObjectID) ([]models.Order, error) {
+	ctx, cancel := ur.Timeouts.read(ctx, "GetOrdersByUserID")
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cursor, err := ur.db.Collection("orders").Find(ctx, bson.M{"user_id": userID}, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	var orders []models.Order
-	err = cursor.All(context.Background(), &orders)
+	err = cursor.All(ctx, &orders)
 	return orders, err
 }
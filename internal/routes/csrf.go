@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/handlers"
+)
+
+const (
+	csrfFormField  = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// CSRFMiddleware emite o token CSRF (cookie HttpOnly) e o disponibiliza no
+// contexto para o RenderTemplate. Visitante recebe um token aleatório; quem
+// está logado (SessionMiddleware antes deste) tem o token derivado da sessão,
+// e um cookie diferente disso é trocado. Em POST/PUT/PATCH/DELETE o token
+// precisa voltar no campo csrf_token do formulário ou no header X-CSRF-Token
+// (usado pelos fetch em JSON, como o /update-cart).
+func CSRFMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookieToken := ""
+		if cookie, err := r.Cookie(handlers.CSRFCookieName); err == nil {
+			cookieToken = cookie.Value
+		}
+
+		token := cookieToken
+		if session, err := r.Cookie(handlers.SessionCookieName); err == nil && handlers.CurrentUser(r) != nil {
+			token = handlers.SessionCSRFToken(session.Value)
+		} else if token == "" {
+			token = handlers.NewCSRFToken()
+		}
+		if token != cookieToken {
+			handlers.SetCSRFCookie(w, token)
+		}
+
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			sent := r.Header.Get(csrfHeaderName)
+			if sent == "" {
+				sent = r.PostFormValue(csrfFormField)
+			}
+			if sent == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				http.Error(w, "Token CSRF inválido ou ausente", http.StatusForbidden)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(handlers.WithCSRFToken(r.Context(), token)))
+	})
+}
@@ -0,0 +1,100 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/handlers"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+)
+
+// csrfRequest passa pelo CSRFMiddleware como se o SessionMiddleware já
+// tivesse resolvido (ou não) o usuário do cookie de sessão
+func csrfRequest(method, session, cookie, sent string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/", nil)
+	if session != "" {
+		r.AddCookie(&http.Cookie{Name: handlers.SessionCookieName, Value: session})
+		r = r.WithContext(handlers.WithUser(r.Context(), &models.User{Name: "Cliente"}))
+	}
+	if cookie != "" {
+		r.AddCookie(&http.Cookie{Name: handlers.CSRFCookieName, Value: cookie})
+	}
+	if sent != "" {
+		r.Header.Set(csrfHeaderName, sent)
+	}
+
+	w := httptest.NewRecorder()
+	CSRFMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(handlers.CSRFToken(r)))
+	})).ServeHTTP(w, r)
+	return w
+}
+
+func issuedCSRFCookie(w *httptest.ResponseRecorder) string {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == handlers.CSRFCookieName {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	const session = "token-de-sessao"
+	bound := handlers.SessionCSRFToken(session)
+	visitor := handlers.NewCSRFToken()
+
+	cases := []struct {
+		name                  string
+		method                string
+		session, cookie, sent string
+		status                int
+		issued                string // cookie novo esperado ("" = nenhum)
+	}{
+		{"visitante sem cookie ganha um", http.MethodGet, "", "", "", http.StatusOK, "*"},
+		{"visitante reenvia o token", http.MethodPost, "", visitor, visitor, http.StatusOK, ""},
+		{"visitante sem token", http.MethodPost, "", visitor, "", http.StatusForbidden, ""},
+		{"visitante com token de outro", http.MethodPost, "", visitor, handlers.NewCSRFToken(), http.StatusForbidden, ""},
+		{"logado com o token da sessão", http.MethodPost, session, bound, bound, http.StatusOK, ""},
+		{"logado: token da sessão mesmo sem cookie", http.MethodPost, session, "", bound, http.StatusOK, bound},
+		{"logado: cookie de antes do login é trocado", http.MethodGet, session, visitor, "", http.StatusOK, bound},
+		{"logado: cookie de antes do login não vale", http.MethodPost, session, visitor, visitor, http.StatusForbidden, bound},
+		{"logado: token de outra sessão não vale", http.MethodPost, session, bound, handlers.SessionCSRFToken("outra"), http.StatusForbidden, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := csrfRequest(tc.method, tc.session, tc.cookie, tc.sent)
+			if w.Code != tc.status {
+				t.Fatalf("status = %d, quer %d", w.Code, tc.status)
+			}
+			issued := issuedCSRFCookie(w)
+			switch {
+			case tc.issued == "*" && issued == "":
+				t.Error("deveria emitir um cookie csrf_token")
+			case tc.issued != "*" && issued != tc.issued:
+				t.Errorf("cookie emitido = %q, quer %q", issued, tc.issued)
+			}
+			if w.Code == http.StatusOK && tc.issued != "" && tc.issued != "*" && w.Body.String() != tc.issued {
+				t.Errorf("token no contexto = %q, quer %q", w.Body.String(), tc.issued)
+			}
+		})
+	}
+}
+
+func TestSessionCSRFToken(t *testing.T) {
+	if handlers.SessionCSRFToken("a") != handlers.SessionCSRFToken("a") {
+		t.Error("o token da sessão deveria ser estável")
+	}
+	if handlers.SessionCSRFToken("a") == handlers.SessionCSRFToken("b") {
+		t.Error("sessões diferentes deveriam ter tokens diferentes")
+	}
+
+	key := handlers.CSRFKey
+	defer func() { handlers.CSRFKey = key }()
+	before := handlers.SessionCSRFToken("a")
+	handlers.CSRFKey = []byte("outra-chave-com-pelo-menos-32-bytes!")
+	if handlers.SessionCSRFToken("a") == before {
+		t.Error("o token deveria depender da chave do servidor")
+	}
+}
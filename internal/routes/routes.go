@@ -1,72 +1,242 @@
 package routes
 
 import (
+	"errors"
 	"net/http"
 	"net/url" // <--- Import added
 
 	"github.com/MarcosAndradeV/go-ecommerce/internal/handlers"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
 	"github.com/MarcosAndradeV/go-ecommerce/internal/service" // Import necessário
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-// Middleware Simplificado: Apenas verifica se tem o cookie
+// SessionMiddleware resolve o token do cookie para o usuário e o coloca no contexto.
+// Não bloqueia nada: rotas públicas também precisam saber se há alguém logado.
+func SessionMiddleware(authS *service.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := r.Cookie(handlers.SessionCookieName)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, err := authS.ResolveSession(r.Context(), cookie.Value)
+			if errors.Is(err, service.ErrInvalidSession) {
+				// Token forjado, revogado ou expirado: limpa o cookie
+				handlers.ClearSessionCookie(w)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				// Banco fora do ar: o cookie continua valendo para depois
+				http.Error(w, "Erro ao validar a sessão", http.StatusServiceUnavailable)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(handlers.WithUser(r.Context(), user)))
+		})
+	}
+}
+
+// BearerAuthMiddleware autentica a API pelo cabeçalho Authorization.
+// O token é o mesmo de uma sessão; cookies não valem aqui (a API não tem CSRF).
+func BearerAuthMiddleware(authS *service.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := handlers.BearerToken(r)
+			if token == "" {
+				handlers.WriteJSONError(w, http.StatusUnauthorized, "unauthorized", "token de acesso ausente")
+				return
+			}
+
+			user, err := authS.ResolveSession(r.Context(), token)
+			if errors.Is(err, service.ErrInvalidSession) {
+				handlers.WriteJSONError(w, http.StatusUnauthorized, "unauthorized", err.Error())
+				return
+			}
+			if err != nil {
+				handlers.WriteJSONError(w, http.StatusServiceUnavailable, "unavailable", "erro ao validar a sessão")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(handlers.WithUser(r.Context(), user)))
+		})
+	}
+}
+
+// loginNext decide para onde voltar depois do login. Um POST (ex: add-to-cart)
+// não pode ser repetido via redirect, então voltamos para a página de origem.
+func loginNext(r *http.Request) string {
+	if r.Method == http.MethodGet {
+		return r.URL.RequestURI()
+	}
+	if ref, err := url.Parse(r.Referer()); err == nil && ref.Host == r.Host && ref.Path != "" {
+		return ref.RequestURI()
+	}
+	return "/"
+}
+
+// AuthMiddleware exige um usuário resolvido pelo SessionMiddleware
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		_, err := r.Cookie("sessao_loja")
-		if err != nil {
-			// Sem cookie = Redireciona para login com next
-			nextURL := r.URL.RequestURI()
-			http.Redirect(w, r, "/login?msg=faca_login&next="+url.QueryEscape(nextURL), http.StatusSeeOther)
+		if handlers.CurrentUser(r) == nil {
+			// Sem sessão = Redireciona para login com next
+			http.Redirect(w, r, "/login?msg=faca_login&next="+url.QueryEscape(loginNext(r)), http.StatusSeeOther)
 			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-func NewRouter(authH *handlers.AuthHandler, storeH *handlers.StoreHandler, authS *service.AuthService) *chi.Mux {
+// RequireRole exige um usuário logado com pelo menos um dos papéis informados.
+// Admins (IsAdmin) passam sempre.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := handlers.CurrentUser(r)
+			if user == nil {
+				http.Redirect(w, r, "/login?msg=faca_login&next="+url.QueryEscape(loginNext(r)), http.StatusSeeOther)
+				return
+			}
+
+			for _, role := range roles {
+				if user.HasRole(role) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "Acesso negado", http.StatusForbidden)
+		})
+	}
+}
+
+func NewRouter(authH *handlers.AuthHandler, storeH *handlers.StoreHandler, webhookH *handlers.WebhookHandler, apiH *handlers.APIHandler, healthH *handlers.HealthHandler, authS *service.AuthService) *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
+	// Probes do orquestrador (fora da sessão: não tocam em cookie nem CSRF)
+	r.Get("/healthz", healthH.LivenessHandler)
+	r.Get("/readyz", healthH.ReadinessHandler)
+
 	fileServer := http.FileServer(http.Dir("./static"))
-	r.Handle("/static/*", http.StripPrefix("/static", fileServer))
+	r.Get("/static/*", http.StripPrefix("/static", fileServer).ServeHTTP)
+
+	// Documento OpenAPI (descrito em handlers.OpenAPISpec)
+	r.Get("/api/openapi.json", handlers.OpenAPIHandler)
 
-	// --- ROTAS PÚBLICAS ---
-	r.Get("/", storeH.HomeHandler)
-	r.Get("/product/{id}", storeH.ProductDetailHandler)
+	// --- WEBHOOKS (autenticados por assinatura HMAC, sem CSRF) ---
+	r.Post("/webhooks/payments", webhookH.PaymentWebhookHandler)
 
-	// --- AUTH ---
-	r.Get("/register", authH.RegisterPageHandler)
-	r.Post("/register", authH.RegisterPostHandler)
-	r.Get("/login", authH.LoginPageHandler)
-	r.Post("/do-login", authH.LoginPostHandler)
-	r.Get("/logout", authH.LogoutHandler)
+	// --- API JSON (token Bearer, sem cookie nem CSRF) ---
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Get("/products", apiH.ListProductsHandler)
+		r.Get("/products/{id}", apiH.GetProductHandler)
+		r.Get("/search", apiH.SearchProductsHandler)
+		r.Post("/auth/login", apiH.LoginHandler)
+		r.Post("/auth/register", apiH.RegisterHandler)
 
-	// --- ROTAS PROTEGIDAS (Usa o Middleware) ---
+		r.Group(func(r chi.Router) {
+			r.Use(BearerAuthMiddleware(authS))
+
+			r.Post("/auth/logout", apiH.LogoutHandler)
+			r.Get("/account", apiH.AccountHandler)
+			r.Get("/cart", apiH.GetCartHandler)
+			r.Post("/cart/items", apiH.AddCartItemHandler)
+			r.Put("/cart/items/{product_id}", apiH.UpdateCartItemHandler)
+			r.Delete("/cart/items/{product_id}", apiH.RemoveCartItemHandler)
+			r.Post("/shipping/quote", apiH.ShippingQuoteHandler)
+			r.Post("/checkout", apiH.CheckoutHandler)
+			r.Get("/orders", apiH.ListOrdersHandler)
+			r.Get("/orders/{id}", apiH.GetOrderHandler)
+			r.Get("/orders/{id}/pix.png", apiH.OrderPixQRCodeHandler)
+		})
+	})
+
+	// --- PÁGINAS (sessão por cookie; todo POST exige token CSRF) ---
 	r.Group(func(r chi.Router) {
-		r.Use(AuthMiddleware)
+		r.Use(SessionMiddleware(authS))
+		r.Use(CSRFMiddleware)
+
+		// --- ROTAS PÚBLICAS ---
+		r.Get("/", storeH.HomeHandler)
+		r.Get("/product/{id}", storeH.ProductDetailHandler)
+		r.Get("/category/{slug}", storeH.CategoryHandler)
+		r.Get("/collection/{slug}", storeH.CollectionHandler)
+		r.Get("/search", storeH.SearchHandler)
 
-		r.Get("/dashboard", authH.DashboardHandler)
+		// --- CARRINHO (visitante ou logado; o de visitante vai para a conta no login) ---
 		r.Get("/cart", storeH.ViewCartHandler)
-		r.Get("/add-to-cart", storeH.AddToCartHandler)
-		r.Get("/remove-from-cart", storeH.RemoveFromCartHandler) // <--- Nova rota
-		r.Post("/update-cart", storeH.UpdateCartHandler)         // <--- Rota para atualizar quantidade
-		r.Get("/checkout", storeH.CheckoutPageHandler)
-		r.Post("/checkout", storeH.CheckoutPageHandler) // <--- Permitir POST para seleção
-		r.Post("/payment", storeH.PaymentPageHandler)   // <--- Nova rota de pagamento
-		r.Post("/purchase", storeH.PurchaseHandler)
-		r.Post("/purchase/simulate/{id}", storeH.SimulatePaymentHandler)
-	})
+		r.Post("/add-to-cart", storeH.AddToCartHandler)
+		r.Post("/remove-from-cart", storeH.RemoveFromCartHandler)
+		r.Post("/update-cart", storeH.UpdateCartHandler) // <--- Rota para atualizar quantidade
+
+		// --- AUTH ---
+		r.Get("/register", authH.RegisterPageHandler)
+		r.Post("/register", authH.RegisterPostHandler)
+		r.Get("/login", authH.LoginPageHandler)
+		r.Post("/do-login", authH.LoginPostHandler)
+		r.Post("/logout", authH.LogoutHandler)
+
+		// --- ROTAS PROTEGIDAS (Usa o Middleware) ---
+		r.Group(func(r chi.Router) {
+			r.Use(AuthMiddleware)
+
+			r.Get("/dashboard", authH.DashboardHandler)
+			r.Get("/checkout", storeH.CheckoutPageHandler)
+			r.Post("/checkout", storeH.CheckoutPageHandler) // <--- Permitir POST para seleção
+			r.Post("/payment", storeH.PaymentPageHandler)   // <--- Nova rota de pagamento
+			r.Post("/shipping/quote", storeH.ShippingQuoteHandler)
+			r.Post("/purchase", storeH.PurchaseHandler)
+			r.Post("/purchase/simulate/{id}", storeH.SimulatePaymentHandler)
+			r.Get("/pix/{id}.png", storeH.PixQRCodeHandler)
+		})
+
+		// --- ADMIN ---
+		r.Route("/admin", func(r chi.Router) {
+			// Gestão de catálogo
+			r.Group(func(r chi.Router) {
+				r.Use(RequireRole(models.RoleCatalogEditor))
+
+				r.Get("/dashboard", storeH.AdminDashboardHandler)
+				r.Post("/create", storeH.AdminCreateProductHandler)
+				r.Get("/edit/product/{product_id}", storeH.EditProductFormHandler)
+				r.Post("/edit/product", storeH.EditProductHandler)
+				r.Post("/delete/product/{id}", storeH.AdminDeleteProductHandler)
+
+				// Categorias e coleções
+				r.Get("/categories", storeH.AdminCatalogHandler)
+				r.Post("/categories", storeH.AdminCreateCategoryHandler)
+				r.Post("/delete/category/{id}", storeH.AdminDeleteCategoryHandler)
+				r.Post("/collections", storeH.AdminCreateCollectionHandler)
+				r.Post("/edit/collection", storeH.AdminEditCollectionHandler)
+				r.Post("/delete/collection/{id}", storeH.AdminDeleteCollectionHandler)
+
+				// Promoções e cupons
+				r.Get("/promotions", storeH.AdminPromotionsHandler)
+				r.Post("/promotions", storeH.AdminCreatePromotionHandler)
+				r.Post("/promotions/{id}/active", storeH.AdminSetPromotionActiveHandler)
+				r.Post("/delete/promotion/{id}", storeH.AdminDeletePromotionHandler)
+
+				// Formas de entrega e tabelas de frete
+				r.Get("/shipping", storeH.AdminShippingHandler)
+				r.Post("/shipping", storeH.AdminCreateShippingMethodHandler)
+				r.Post("/shipping/{id}/active", storeH.AdminSetShippingMethodActiveHandler)
+				r.Post("/delete/shipping/{id}", storeH.AdminDeleteShippingMethodHandler)
+			})
+
+			// Pedidos (atendimento e financeiro)
+			r.Group(func(r chi.Router) {
+				r.Use(RequireRole(models.RoleSupport, models.RoleFinance))
 
-	// --- ADMIN ---
-	r.Route("/admin", func(r chi.Router) {
-		r.Get("/dashboard", storeH.AdminDashboardHandler)
-		r.Post("/create", storeH.AdminCreateProductHandler)
-		r.Get("/edit/product/{product_id}", storeH.EditProductFormHandler)
-		r.Post("/edit/product", storeH.EditProductHandler)
-		r.Post("/delete/product/{id}", storeH.AdminDeleteProductHandler)
+				r.Get("/orders", storeH.AdminOrdersHandler)
+				r.Post("/orders/{id}/status", storeH.AdminUpdateOrderStatusHandler)
+			})
+		})
 	})
 
 	return r
@@ -0,0 +1,238 @@
+package routes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/handlers"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/service"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// openAPIPath converte o padrão do chi para o formato de path do OpenAPI
+// ("/static/*" vira "/static/{path}")
+func openAPIPath(route string) string {
+	if strings.HasSuffix(route, "/*") {
+		return strings.TrimSuffix(route, "*") + "{path}"
+	}
+	return route
+}
+
+func newTestRouter() *chi.Mux {
+	return NewRouter(
+		&handlers.AuthHandler{},
+		&handlers.StoreHandler{},
+		&handlers.WebhookHandler{},
+		handlers.NewAPIHandler(nil, nil),
+		handlers.NewHealthHandler(nil),
+		&service.AuthService{},
+	)
+}
+
+func TestEveryRouteIsInOpenAPISpec(t *testing.T) {
+	spec := handlers.OpenAPISpec()
+	registered := map[string]bool{}
+
+	err := chi.Walk(newTestRouter(), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		path := openAPIPath(route)
+		registered[method+" "+path] = true
+
+		if spec.Operation(method, path) == nil {
+			t.Errorf("rota %s %s sem entrada no OpenAPI (handlers.OpenAPISpec)", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// O contrário também: o documento não pode prometer rota que não existe
+	for path, item := range spec.Paths {
+		for method := range item {
+			if !registered[strings.ToUpper(method)+" "+path] {
+				t.Errorf("OpenAPI descreve %s %s, mas a rota não está registrada", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+// brokenSessions simula o banco de sessões fora do ar
+type brokenSessions struct{ repository.SessionRepository }
+
+func (brokenSessions) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*models.Session, error) {
+	return nil, errors.New("timeout do banco")
+}
+
+// sessionHash é como o AuthService guarda o token (SHA-256 em hex)
+func sessionHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// serveSession passa o cookie pelo SessionMiddleware; o corpo diz quem ficou no contexto
+func serveSession(authS *service.AuthService, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: handlers.SessionCookieName, Value: token})
+	w := httptest.NewRecorder()
+	SessionMiddleware(authS)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := handlers.CurrentUser(r); user != nil {
+			w.Write([]byte(user.Email))
+		}
+	})).ServeHTTP(w, r)
+	return w
+}
+
+func clearedSessionCookie(w *httptest.ResponseRecorder) bool {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == handlers.SessionCookieName && c.MaxAge < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSessionMiddleware(t *testing.T) {
+	ctx := context.Background()
+	mem := repository.NewMemoryStore()
+	user := models.User{ID: primitive.NewObjectID(), Name: "Cliente", Email: "cliente@example.com", PasswordHash: "x"}
+	if err := mem.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	authS := service.NewAuthService(mem, mem)
+
+	newSession := func() string {
+		t.Helper()
+		token, _, err := authS.CreateSession(ctx, user.ID, "teste")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	expectLoggedOut := func(name string, token string) {
+		t.Helper()
+		w := serveSession(authS, token)
+		if w.Code != http.StatusOK || w.Body.Len() != 0 {
+			t.Errorf("%s: status %d, usuário %q; quer visitante", name, w.Code, w.Body.String())
+		}
+		if !clearedSessionCookie(w) {
+			t.Errorf("%s: o cookie de sessão deveria ser apagado", name)
+		}
+		if _, err := mem.GetSessionByTokenHash(ctx, sessionHash(token)); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("%s: a sessão deveria estar revogada (erro = %v)", name, err)
+		}
+	}
+
+	valid := newSession()
+	if w := serveSession(authS, valid); w.Body.String() != user.Email || clearedSessionCookie(w) {
+		t.Fatalf("sessão válida: usuário %q, cookie apagado = %v", w.Body.String(), clearedSessionCookie(w))
+	}
+
+	// Passou do TTL
+	expired := newSession()
+	session, _ := mem.GetSessionByTokenHash(ctx, sessionHash(expired))
+	session.ExpiresAt = time.Now().Add(-time.Minute)
+	mem.CreateSession(ctx, *session)
+	expectLoggedOut("sessão expirada", expired)
+
+	// Parada há mais que o idle timeout
+	idle := newSession()
+	mem.TouchSession(ctx, sessionHash(idle), time.Now().Add(-authS.SessionIdleTimeout-time.Minute))
+	expectLoggedOut("sessão ociosa", idle)
+
+	// Logout
+	loggedOut := newSession()
+	if err := authS.RevokeSession(ctx, loggedOut); err != nil {
+		t.Fatal(err)
+	}
+	expectLoggedOut("depois do logout", loggedOut)
+
+	if w := serveSession(authS, "token-forjado"); w.Body.Len() != 0 || !clearedSessionCookie(w) {
+		t.Errorf("token forjado: usuário %q, cookie apagado = %v", w.Body.String(), clearedSessionCookie(w))
+	}
+
+	// Banco fora do ar: 503 e o cookie continua no navegador
+	broken := service.NewAuthService(mem, brokenSessions{mem})
+	w := serveSession(broken, valid)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("erro do banco: status %d, quer 503", w.Code)
+	}
+	if clearedSessionCookie(w) {
+		t.Error("erro do banco não pode apagar o cookie de sessão")
+	}
+	if w := serveSession(authS, valid); w.Body.String() != user.Email {
+		t.Error("a sessão deveria continuar valendo depois do erro do banco")
+	}
+}
+
+func TestAdminRoles(t *testing.T) {
+	t.Chdir("../..") // templates/ é relativo à raiz, como no servidor
+
+	ctx := context.Background()
+	mem := repository.NewMemoryStore()
+	authS := service.NewAuthService(mem, mem)
+	storeS := service.NewStoreService(mem, service.NewFakePaymentGateway(service.PixConfig{}))
+	router := NewRouter(
+		handlers.NewAuthHandler(authS),
+		handlers.NewStoreHandler(storeS),
+		&handlers.WebhookHandler{},
+		handlers.NewAPIHandler(authS, storeS),
+		handlers.NewHealthHandler(nil),
+		authS,
+	)
+
+	login := func(isAdmin bool, roles ...string) string {
+		t.Helper()
+		user := models.User{ID: primitive.NewObjectID(), Name: "Equipe", Email: primitive.NewObjectID().Hex() + "@example.com", IsAdmin: isAdmin, Roles: roles}
+		if err := mem.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		token, _, err := authS.CreateSession(ctx, user.ID, "teste")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	const (
+		catalog = "/admin/dashboard"
+		orders  = "/admin/orders"
+	)
+	cases := []struct {
+		name  string
+		token string
+		want  map[string]int
+	}{
+		{"visitante vai para o login", "", map[string]int{catalog: http.StatusSeeOther, orders: http.StatusSeeOther}},
+		{"cliente", login(false), map[string]int{catalog: http.StatusForbidden, orders: http.StatusForbidden}},
+		{"atendimento", login(false, models.RoleSupport), map[string]int{catalog: http.StatusForbidden, orders: http.StatusOK}},
+		{"financeiro", login(false, models.RoleFinance), map[string]int{catalog: http.StatusForbidden, orders: http.StatusOK}},
+		{"catálogo", login(false, models.RoleCatalogEditor), map[string]int{catalog: http.StatusOK, orders: http.StatusForbidden}},
+		{"papel admin", login(false, models.RoleAdmin), map[string]int{catalog: http.StatusOK, orders: http.StatusOK}},
+		{"IsAdmin", login(true), map[string]int{catalog: http.StatusOK, orders: http.StatusOK}},
+	}
+	for _, tc := range cases {
+		for path, status := range tc.want {
+			t.Run(tc.name+" "+path, func(t *testing.T) {
+				r := httptest.NewRequest(http.MethodGet, path, nil)
+				if tc.token != "" {
+					r.AddCookie(&http.Cookie{Name: handlers.SessionCookieName, Value: tc.token})
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, r)
+				if w.Code != status {
+					t.Errorf("status = %d, quer %d", w.Code, status)
+				}
+			})
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -10,20 +15,41 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Padrões da duração máxima de uma sessão e do tempo máximo sem atividade
+const (
+	DefaultSessionTTL         = 24 * time.Hour
+	DefaultSessionIdleTimeout = 2 * time.Hour
+)
+
+var (
+	ErrInvalidSession     = errors.New("sessão inválida ou expirada")
+	ErrEmailTaken         = errors.New("este e-mail já está cadastrado")
+	ErrInvalidCredentials = errors.New("usuário ou senha inválidos")
+)
+
 type AuthService struct {
-	Repo *repository.UserRepository
+	Repo     repository.UserRepository
+	Sessions repository.SessionRepository
+
+	SessionTTL         time.Duration
+	SessionIdleTimeout time.Duration
 }
 
-func NewAuthService(repo *repository.UserRepository) *AuthService {
-	return &AuthService{Repo: repo}
+func NewAuthService(repo repository.UserRepository, sessions repository.SessionRepository) *AuthService {
+	return &AuthService{
+		Repo:               repo,
+		Sessions:           sessions,
+		SessionTTL:         DefaultSessionTTL,
+		SessionIdleTimeout: DefaultSessionIdleTimeout,
+	}
 }
 
 // Registra um cliente novo
-func (as *AuthService) RegisterCustomer(name, email, password string) error {
+func (as *AuthService) RegisterCustomer(ctx context.Context, name, email, password string) error {
 	// 1. Verifica se já existe
-	existing, _ := as.Repo.GetUserByEmail(email)
+	existing, _ := as.Repo.GetUserByEmail(ctx, email)
 	if existing != nil {
-		return errors.New("este e-mail já está cadastrado")
+		return ErrEmailTaken
 	}
 
 	// 2. Hash da senha (Segurança)
@@ -43,40 +69,78 @@ func (as *AuthService) RegisterCustomer(name, email, password string) error {
         Cart:         []models.OrderItem{}, // <--- ADICIONE ISSO AQUI (Inicializa lista vazia)
     }
 
-    // 4. Salva
-    return as.Repo.CreateUser(user)
+    // 4. Salva (o índice único pega o cadastro simultâneo com o mesmo e-mail)
+    err = as.Repo.CreateUser(ctx, user)
+    if errors.Is(err, repository.ErrDuplicateEmail) {
+        return ErrEmailTaken
+    }
+    return err
+}
+
+// BootstrapAdmin cria o primeiro admin (ou promove um usuário já cadastrado)
+func (as *AuthService) BootstrapAdmin(ctx context.Context, name, email, password string) error {
+	if email == "" {
+		return errors.New("e-mail é obrigatório")
+	}
+
+	existing, _ := as.Repo.GetUserByEmail(ctx, email)
+	if existing != nil {
+		return as.Repo.SetAdmin(ctx, existing.ID, true)
+	}
+
+	if len(password) < 8 {
+		return errors.New("a senha do admin deve ter pelo menos 8 caracteres")
+	}
+
+	hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	user := models.User{
+		ID:           primitive.NewObjectID(),
+		Name:         name,
+		Email:        email,
+		PasswordHash: string(hashedPass),
+		IsAdmin:      true,
+		Roles:        []string{models.RoleAdmin},
+		CreatedAt:    time.Now(),
+		Cart:         []models.OrderItem{},
+	}
+	return as.Repo.CreateUser(ctx, user)
 }
 
 // Autentica o usuário (Login)
-func (as *AuthService) AuthenticateUser(email, password string) (*models.User, error) {
+func (as *AuthService) AuthenticateUser(ctx context.Context, email, password string) (*models.User, error) {
 	// 1. Busca usuário
-	user, err := as.Repo.GetUserByEmail(email)
+	user, err := as.Repo.GetUserByEmail(ctx, email)
 	if err != nil {
-		return nil, errors.New("usuário ou senha inválidos")
+		return nil, ErrInvalidCredentials
 	}
 
 	// 2. Compara Hash
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
-		return nil, errors.New("usuário ou senha inválidos")
+		return nil, ErrInvalidCredentials
 	}
 
 	return user, nil
 }
 
 // Dados para o Dashboard
-func (as *AuthService) GetDashboardData(userIDStr string) (*models.User, []models.Order, error) {
+func (as *AuthService) GetDashboardData(ctx context.Context, userIDStr string) (*models.User, []models.Order, error) {
 	userID, err := primitive.ObjectIDFromHex(userIDStr)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	user, err := as.Repo.GetUserByID(userID)
+	user, err := as.Repo.GetUserByID(ctx, userID)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	orders, err := as.Repo.GetOrdersByEmail(user.Email)
+	// Pelo dono do pedido: o e-mail do pedido é o que o cliente digitou no checkout
+	orders, err := as.Repo.GetOrdersByUserID(ctx, user.ID)
 	if err != nil {
 		// Se der erro ao buscar pedidos, retorna lista vazia, mas não trava o user
 		orders = []models.Order{}
@@ -84,3 +148,87 @@ func (as *AuthService) GetDashboardData(userIDStr string) (*models.User, []model
 
 	return user, orders, nil
 }
+
+// --- SESSÕES ---
+
+// hashToken: no banco guardamos só o SHA-256 do token, nunca o valor do cookie
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSession gera um token aleatório para o usuário e persiste a sessão.
+// Retorna o token (vai para o cookie) e a data de expiração.
+func (as *AuthService) CreateSession(ctx context.Context, userID primitive.ObjectID, userAgent string) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	now := time.Now()
+	session := models.Session{
+		ID:         primitive.NewObjectID(),
+		TokenHash:  hashToken(token),
+		UserID:     userID,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(as.SessionTTL),
+	}
+
+	if err := as.Sessions.CreateSession(ctx, session); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, session.ExpiresAt, nil
+}
+
+// ResolveSession valida o token do cookie e devolve o usuário dono da sessão.
+// Sessões expiradas ou ociosas demais são revogadas na hora.
+// ErrInvalidSession só quando a sessão (ou o usuário) de fato não vale mais;
+// falha do banco volta como está, para não deslogar todo mundo num timeout.
+func (as *AuthService) ResolveSession(ctx context.Context, token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrInvalidSession
+	}
+
+	tokenHash := hashToken(token)
+	session, err := as.Sessions.GetSessionByTokenHash(ctx, tokenHash)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.After(session.ExpiresAt) || now.Sub(session.LastSeenAt) > as.SessionIdleTimeout {
+		as.Sessions.RevokeSession(ctx, tokenHash)
+		return nil, ErrInvalidSession
+	}
+
+	user, err := as.Repo.GetUserByID(ctx, session.UserID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		// Usuário excluído: a sessão não serve mais
+		as.Sessions.RevokeSession(ctx, tokenHash)
+		return nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Evita uma escrita por request: só atualiza se passou mais de 1 minuto
+	if now.Sub(session.LastSeenAt) > time.Minute {
+		as.Sessions.TouchSession(ctx, tokenHash, now)
+	}
+
+	return user, nil
+}
+
+// RevokeSession encerra a sessão (logout)
+func (as *AuthService) RevokeSession(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	return as.Sessions.RevokeSession(ctx, hashToken(token))
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+)
+
+// Rodar o bootstrap de novo (ex: a cada deploy) não cria outro admin nem troca a senha
+func TestBootstrapAdminIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	mem := repository.NewMemoryStore()
+	as := NewAuthService(mem, mem)
+
+	if err := as.BootstrapAdmin(ctx, "Admin", "admin@example.com", "senha-forte-1"); err != nil {
+		t.Fatal(err)
+	}
+	first, err := mem.GetUserByEmail(ctx, "admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := as.BootstrapAdmin(ctx, "Outro Nome", "admin@example.com", "outra-senha-2"); err != nil {
+		t.Fatalf("segunda execução: %v", err)
+	}
+	again, _ := mem.GetUserByEmail(ctx, "admin@example.com")
+	if again.ID != first.ID || again.PasswordHash != first.PasswordHash || again.Name != "Admin" {
+		t.Errorf("segunda execução mudou o admin: %+v", again)
+	}
+	if !again.IsAdmin || !again.HasRole(models.RoleSupport) {
+		t.Errorf("admin perdeu acesso: %+v", again)
+	}
+	if _, err := as.AuthenticateUser(ctx, "admin@example.com", "senha-forte-1"); err != nil {
+		t.Errorf("a senha original deveria continuar valendo: %v", err)
+	}
+
+	// Usuário já cadastrado é promovido, sem mexer no resto
+	if err := as.RegisterCustomer(ctx, "Cliente", "cliente@example.com", "senha-cliente"); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := as.BootstrapAdmin(ctx, "", "cliente@example.com", ""); err != nil {
+			t.Fatalf("promoção: %v", err)
+		}
+	}
+	promoted, _ := mem.GetUserByEmail(ctx, "cliente@example.com")
+	if !promoted.IsAdmin || promoted.Name != "Cliente" {
+		t.Errorf("usuário promovido = %+v", promoted)
+	}
+
+	if err := as.BootstrapAdmin(ctx, "Novo", "novo@example.com", "curta"); err == nil {
+		t.Error("admin novo com senha curta deveria falhar")
+	}
+}
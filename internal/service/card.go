@@ -0,0 +1,234 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrCardNumberInvalid = errors.New("número do cartão inválido")
+	ErrCardBrandUnknown  = errors.New("bandeira do cartão não aceita")
+	ErrCardExpiryInvalid = errors.New("validade do cartão inválida")
+	ErrCardExpired       = errors.New("cartão vencido")
+	ErrCardCVVInvalid    = errors.New("CVV inválido")
+)
+
+// CardBrand é a bandeira detectada pelo BIN (primeiros dígitos)
+type CardBrand string
+
+const (
+	CardBrandVisa       CardBrand = "visa"
+	CardBrandMastercard CardBrand = "mastercard"
+	CardBrandAmex       CardBrand = "amex"
+	CardBrandElo        CardBrand = "elo"
+	CardBrandHipercard  CardBrand = "hipercard"
+	CardBrandDiners     CardBrand = "diners"
+	CardBrandDiscover   CardBrand = "discover"
+	CardBrandJCB        CardBrand = "jcb"
+)
+
+// CardDetails são os dados crus digitados pelo cliente. Só existem entre o
+// handler e o tokenizador do provedor; nunca chegam ao StoreService.
+type CardDetails struct {
+	Number     string `json:"number"`
+	HolderName string `json:"holder_name"`
+	CVV        string `json:"cvv"`
+	ExpMonth   int    `json:"exp_month"`
+	ExpYear    int    `json:"exp_year"`
+}
+
+// CardToken substitui o cartão depois da tokenização
+type CardToken struct {
+	Token string    `json:"token"`
+	Brand CardBrand `json:"brand"`
+	Last4 string    `json:"last4"`
+}
+
+type binRange struct {
+	brand    CardBrand
+	digits   int // quantos dígitos do início comparar
+	from, to int
+}
+
+// binRanges: a ordem importa. Elo e Hipercard vêm antes porque parte dos
+// BINs deles cai dentro das faixas de Visa e Discover.
+var binRanges = []binRange{
+	{CardBrandElo, 6, 401178, 401179},
+	{CardBrandElo, 6, 431274, 431274},
+	{CardBrandElo, 6, 438935, 438935},
+	{CardBrandElo, 6, 451416, 451416},
+	{CardBrandElo, 6, 457393, 457393},
+	{CardBrandElo, 6, 457631, 457632},
+	{CardBrandElo, 6, 504175, 504175},
+	{CardBrandElo, 6, 506699, 506778},
+	{CardBrandElo, 6, 509000, 509999},
+	{CardBrandElo, 6, 627780, 627780},
+	{CardBrandElo, 6, 636297, 636297},
+	{CardBrandElo, 6, 636368, 636368},
+	{CardBrandElo, 6, 650031, 650033},
+	{CardBrandElo, 6, 650035, 650051},
+	{CardBrandElo, 6, 650405, 650439},
+	{CardBrandElo, 6, 650485, 650538},
+	{CardBrandElo, 6, 650541, 650598},
+	{CardBrandElo, 6, 650700, 650718},
+	{CardBrandElo, 6, 650720, 650727},
+	{CardBrandElo, 6, 650901, 650978},
+	{CardBrandElo, 6, 651652, 651679},
+	{CardBrandElo, 6, 655000, 655019},
+	{CardBrandElo, 6, 655021, 655058},
+	{CardBrandHipercard, 6, 606282, 606282},
+	{CardBrandHipercard, 4, 3841, 3841},
+	{CardBrandAmex, 2, 34, 34},
+	{CardBrandAmex, 2, 37, 37},
+	{CardBrandDiners, 3, 300, 305},
+	{CardBrandDiners, 2, 36, 36},
+	{CardBrandDiners, 2, 38, 38},
+	{CardBrandJCB, 4, 3528, 3589},
+	{CardBrandDiscover, 4, 6011, 6011},
+	{CardBrandDiscover, 3, 644, 649},
+	{CardBrandDiscover, 2, 65, 65},
+	{CardBrandMastercard, 2, 51, 55},
+	{CardBrandMastercard, 4, 2221, 2720},
+	{CardBrandVisa, 1, 4, 4},
+}
+
+// Tamanhos válidos do número por bandeira
+var cardLengths = map[CardBrand][]int{
+	CardBrandVisa:       {13, 16, 19},
+	CardBrandMastercard: {16},
+	CardBrandAmex:       {15},
+	CardBrandElo:        {16},
+	CardBrandHipercard:  {13, 16, 19},
+	CardBrandDiners:     {14, 16},
+	CardBrandDiscover:   {16, 19},
+	CardBrandJCB:        {16},
+}
+
+// NormalizeCardNumber tira espaços e traços do número digitado
+func NormalizeCardNumber(number string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, number)
+}
+
+// DetectCardBrand identifica a bandeira pelo BIN ("" se desconhecida)
+func DetectCardBrand(number string) CardBrand {
+	for _, br := range binRanges {
+		if len(number) < br.digits {
+			continue
+		}
+		prefix, err := strconv.Atoi(number[:br.digits])
+		if err != nil {
+			return ""
+		}
+		if prefix >= br.from && prefix <= br.to {
+			return br.brand
+		}
+	}
+	return ""
+}
+
+// LuhnValid confere o dígito verificador (mod 10)
+func LuhnValid(number string) bool {
+	if len(number) < 2 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ParseCardExpiry aceita "MM/AA" ou "MM/AAAA"
+func ParseCardExpiry(s string) (month, year int, err error) {
+	mm, yy, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return 0, 0, ErrCardExpiryInvalid
+	}
+	month, err = strconv.Atoi(strings.TrimSpace(mm))
+	if err != nil || month < 1 || month > 12 {
+		return 0, 0, ErrCardExpiryInvalid
+	}
+	yy = strings.TrimSpace(yy)
+	year, err = strconv.Atoi(yy)
+	if err != nil {
+		return 0, 0, ErrCardExpiryInvalid
+	}
+	switch len(yy) {
+	case 2:
+		year += 2000
+	case 4:
+	default:
+		return 0, 0, ErrCardExpiryInvalid
+	}
+	return month, year, nil
+}
+
+// ValidateCard faz todas as checagens locais antes de tokenizar
+func ValidateCard(card CardDetails, now time.Time) (CardBrand, error) {
+	number := NormalizeCardNumber(card.Number)
+	for _, r := range number {
+		if !unicode.IsDigit(r) {
+			return "", ErrCardNumberInvalid
+		}
+	}
+
+	brand := DetectCardBrand(number)
+	if brand == "" {
+		return "", ErrCardBrandUnknown
+	}
+
+	validLength := false
+	for _, n := range cardLengths[brand] {
+		if len(number) == n {
+			validLength = true
+			break
+		}
+	}
+	if !validLength || !LuhnValid(number) {
+		return "", ErrCardNumberInvalid
+	}
+
+	if card.ExpMonth < 1 || card.ExpMonth > 12 || card.ExpYear < 2000 {
+		return "", ErrCardExpiryInvalid
+	}
+	// O cartão vale até o último dia do mês de validade
+	expiresAt := time.Date(card.ExpYear, time.Month(card.ExpMonth)+1, 1, 0, 0, 0, 0, now.Location())
+	if !now.Before(expiresAt) {
+		return "", ErrCardExpired
+	}
+
+	cvvLength := 3
+	if brand == CardBrandAmex {
+		cvvLength = 4
+	}
+	if len(card.CVV) != cvvLength {
+		return "", ErrCardCVVInvalid
+	}
+	for _, r := range card.CVV {
+		if !unicode.IsDigit(r) {
+			return "", ErrCardCVVInvalid
+		}
+	}
+
+	return brand, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLuhnValid(t *testing.T) {
+	cases := map[string]bool{
+		"4111111111111111": true,
+		"5555555555554444": true,
+		"378282246310005":  true,
+		"79927398713":      true,
+		"4111111111111112": false,
+		"5555555555554440": false,
+		"79927398710":      false,
+		"4111a11111111111": false,
+		"0":                false,
+		"":                 false,
+	}
+	for number, want := range cases {
+		if got := LuhnValid(number); got != want {
+			t.Errorf("LuhnValid(%q) = %v, quer %v", number, got, want)
+		}
+	}
+}
+
+func TestValidateCard(t *testing.T) {
+	now := time.Date(2026, time.March, 15, 12, 0, 0, 0, time.UTC)
+	card := func(number, cvv string, month, year int) CardDetails {
+		return CardDetails{Number: number, HolderName: "Cliente", CVV: cvv, ExpMonth: month, ExpYear: year}
+	}
+
+	cases := []struct {
+		name  string
+		card  CardDetails
+		brand CardBrand
+		err   error
+	}{
+		{"visa", card("4111 1111 1111 1111", "123", 12, 2030), CardBrandVisa, nil},
+		{"mastercard série 2", card("2223-0000-4840-0011", "123", 1, 2027), CardBrandMastercard, nil},
+		{"amex com CVV de 4", card("378282246310005", "1234", 1, 2027), CardBrandAmex, nil},
+		{"elo antes de visa/discover", card("6362970000457013", "123", 1, 2027), CardBrandElo, nil},
+		{"hipercard", card("6062825624254001", "123", 1, 2027), CardBrandHipercard, nil},
+		{"diners", card("30569309025904", "123", 1, 2027), CardBrandDiners, nil},
+		{"discover", card("6011111111111117", "123", 1, 2027), CardBrandDiscover, nil},
+		{"jcb", card("3530111333300000", "123", 1, 2027), CardBrandJCB, nil},
+
+		{"luhn inválido", card("4111111111111112", "123", 12, 2030), "", ErrCardNumberInvalid},
+		{"tamanho errado para a bandeira", card("411111111111111", "123", 12, 2030), "", ErrCardNumberInvalid},
+		{"letras no número", card("4111x11111111111", "123", 12, 2030), "", ErrCardNumberInvalid},
+		{"bandeira desconhecida", card("9111111111111111", "123", 12, 2030), "", ErrCardBrandUnknown},
+
+		{"vence no fim do mês atual", card("4111111111111111", "123", 3, 2026), CardBrandVisa, nil},
+		{"venceu no mês passado", card("4111111111111111", "123", 2, 2026), "", ErrCardExpired},
+		{"venceu no ano passado", card("4111111111111111", "123", 12, 2025), "", ErrCardExpired},
+		{"mês 13", card("4111111111111111", "123", 13, 2030), "", ErrCardExpiryInvalid},
+		{"mês 0", card("4111111111111111", "123", 0, 2030), "", ErrCardExpiryInvalid},
+		{"ano com 2 dígitos", card("4111111111111111", "123", 12, 30), "", ErrCardExpiryInvalid},
+
+		{"CVV curto", card("4111111111111111", "12", 12, 2030), "", ErrCardCVVInvalid},
+		{"amex com CVV de 3", card("378282246310005", "123", 12, 2030), "", ErrCardCVVInvalid},
+		{"CVV com letra", card("4111111111111111", "12a", 12, 2030), "", ErrCardCVVInvalid},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			brand, err := ValidateCard(tc.card, now)
+			if !errors.Is(err, tc.err) || (tc.err == nil && err != nil) {
+				t.Fatalf("erro = %v, quer %v", err, tc.err)
+			}
+			if brand != tc.brand {
+				t.Errorf("bandeira = %q, quer %q", brand, tc.brand)
+			}
+		})
+	}
+}
+
+func TestParseCardExpiry(t *testing.T) {
+	valid := map[string][2]int{"03/27": {3, 2027}, "12/2030": {12, 2030}, " 1 / 28 ": {1, 2028}}
+	for in, want := range valid {
+		month, year, err := ParseCardExpiry(in)
+		if err != nil || month != want[0] || year != want[1] {
+			t.Errorf("ParseCardExpiry(%q) = %d/%d, %v", in, month, year, err)
+		}
+	}
+	for _, in := range []string{"", "0327", "13/27", "00/27", "03/7", "03/027", "ab/cd"} {
+		if _, _, err := ParseCardExpiry(in); !errors.Is(err, ErrCardExpiryInvalid) {
+			t.Errorf("ParseCardExpiry(%q) deveria falhar, veio %v", in, err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrCartChanged: o preço (ou o produto) mudou depois que o cliente viu o
+// carrinho; ele precisa conferir de novo antes de pagar
+var ErrCartChanged = errors.New("o carrinho mudou: confira os itens antes de pagar")
+
+// CartReview é o carrinho conferido com o catálogo atual. Os preços já são os
+// de agora; Changed avisa que alguma linha mudou desde a última conferência.
+type CartReview struct {
+	Items   []models.OrderItemWithStock
+	Changed bool
+}
+
+// Total em centavos das linhas que ainda podem ser compradas
+func (c *CartReview) Total() int64 {
+	_, total := c.Selected(nil)
+	return total
+}
+
+// Selected devolve as linhas que podem ir para o checkout, com o preço atual.
+// A seleção é por linha (LineKey); sem seleção, pega todas (como o GET /checkout).
+func (c *CartReview) Selected(selectedItems []string) ([]models.OrderItem, int64) {
+	var items []models.OrderItem
+	var total int64
+	for _, line := range c.Items {
+		if line.Unavailable() {
+			continue
+		}
+		if len(selectedItems) > 0 && !slices.Contains(selectedItems, line.LineKey) {
+			continue
+		}
+		items = append(items, models.OrderItem{
+			ProductID:   line.ProductID,
+			ProductName: line.ProductName,
+			Price:       line.Price,
+			Quantity:    line.Quantity,
+			Size:        line.Size,
+			SKU:         line.SKU,
+			ImageURL:    line.ImageURL,
+		})
+		total += line.Price * int64(line.Quantity)
+	}
+	return items, total
+}
+
+// ReviewUserCart confere o carrinho do usuário e grava os preços novos, para
+// que o checkout cobre exatamente o que ele está vendo
+func (s *StoreService) ReviewUserCart(ctx context.Context, userIDStr string) (*models.User, *CartReview, error) {
+	userID, _ := primitive.ObjectIDFromHex(userIDStr)
+
+	user, err := s.Repo.GetUserWithCart(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	review, err := s.reviewCart(ctx, user.Cart, func(item models.OrderItem) error {
+		return s.Repo.RepriceCartItem(ctx, userID, item)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, review, nil
+}
+
+// ReviewGuestCart é o ReviewUserCart do visitante. Carrinho expirado ou
+// inexistente é um carrinho vazio.
+func (s *StoreService) ReviewGuestCart(ctx context.Context, cartIDStr string) (*CartReview, error) {
+	cartID, err := guestCartID(cartIDStr)
+	if err != nil {
+		return &CartReview{}, nil
+	}
+
+	cart, err := s.Repo.GetGuestCart(ctx, cartID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &CartReview{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.reviewCart(ctx, cart.Cart, func(item models.OrderItem) error {
+		return s.Repo.RepriceGuestCartItem(ctx, cartID, item)
+	})
+}
+
+// reviewCart compara cada linha com o produto atual. Preço, nome e imagem
+// novos são gravados com reprice; só a mudança de preço (ou o produto/tamanho
+// que sumiu) conta como mudança para o cliente.
+func (s *StoreService) reviewCart(ctx context.Context, cart []models.OrderItem, reprice func(models.OrderItem) error) (*CartReview, error) {
+	review := &CartReview{}
+
+	for _, item := range cart {
+		line := models.OrderItemWithStock{
+			ProductID:   item.ProductID,
+			ProductName: item.ProductName,
+			Price:       item.Price,
+			Quantity:    item.Quantity,
+			Size:        item.Size,
+			SKU:         item.SKU,
+			ImageURL:    item.ImageURL,
+			LineKey:     item.LineKey(),
+		}
+
+		product, err := s.Repo.GetProductByID(ctx, item.ProductID)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			line.Change = models.CartChangeRemoved
+		case err != nil:
+			return nil, err
+		case !stillSold(product, item):
+			line.Change = models.CartChangeSizeRemoved
+		}
+		if line.Change != "" {
+			line.IsOutOfStock = true
+			review.Items = append(review.Items, line)
+			review.Changed = true
+			continue
+		}
+
+		current := currentLine(product, item)
+		if current != item {
+			if err := reprice(current); err != nil {
+				return nil, err
+			}
+		}
+
+		line.ProductName = current.ProductName
+		line.ImageURL = current.ImageURL
+		line.Price = current.Price
+		if current.Price != item.Price {
+			line.PreviousPrice = item.Price
+			line.Change = models.CartChangePriceDown
+			if current.Price > item.Price {
+				line.Change = models.CartChangePriceUp
+			}
+			review.Changed = true
+		}
+		line.Stock = product.StockFor(item.SKU)
+		line.IsOutOfStock = line.Stock == 0
+
+		review.Items = append(review.Items, line)
+	}
+
+	return review, nil
+}
+
+// stillSold: a variante (ou o tamanho) da linha ainda existe no produto
+func stillSold(product *models.Product, item models.OrderItem) bool {
+	if product.HasVariants() {
+		_, ok := product.Variant(item.SKU)
+		return ok
+	}
+	if item.SKU != "" {
+		return false // o produto deixou de ter variantes
+	}
+	return item.Size == "" || slices.Contains(product.Sizes, item.Size)
+}
+
+// currentLine é a linha com preço, nome e imagem de agora (mesma regra do cartItem)
+func currentLine(product *models.Product, item models.OrderItem) models.OrderItem {
+	item.ProductName = product.Name
+	item.Price = product.PriceFor(item.SKU)
+	item.ImageURL = product.ImageURL
+	if v, ok := product.Variant(item.SKU); ok && v.ImageURL != "" {
+		item.ImageURL = v.ImageURL
+	}
+	return item
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Selecionar uma variante não pode levar junto as outras do mesmo produto
+func TestSelectedItemsAreLines(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryStore()
+	s := NewStoreService(repo, NewFakePaymentGateway(PixConfig{Key: "loja@exemplo.com", MerchantName: "Loja", MerchantCity: "Sao Paulo"}))
+
+	user := models.User{ID: primitive.NewObjectID(), Name: "Cliente", Email: "cliente@exemplo.com"}
+	product := models.Product{
+		ID: primitive.NewObjectID(), Name: "Camiseta", Price: 5000, Stock: 20,
+		Variants: []models.Variant{
+			{SKU: "CAM-P", Options: []models.VariantOption{{Name: "Tamanho", Value: "P"}}, Stock: 10},
+			{SKU: "CAM-G", Options: []models.VariantOption{{Name: "Tamanho", Value: "G"}}, Stock: 10},
+		},
+	}
+	if err := repo.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateProduct(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	small := models.OrderItem{ProductID: product.ID, ProductName: product.Name, Price: 5000, Quantity: 1, Size: "P", SKU: "CAM-P"}
+	large := models.OrderItem{ProductID: product.ID, ProductName: product.Name, Price: 5000, Quantity: 2, Size: "G", SKU: "CAM-G"}
+	for _, item := range []models.OrderItem{small, large} {
+		if err := repo.AddItemToCart(ctx, user.ID, item, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, review, err := s.ReviewUserCart(ctx, user.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, total := review.Selected([]string{small.LineKey()})
+	if len(items) != 1 || items[0].SKU != "CAM-P" || total != 5000 {
+		t.Fatalf("seleção da variante P trouxe %+v (total %d)", items, total)
+	}
+	if items, _ := review.Selected([]string{product.ID.Hex()}); len(items) != 0 {
+		t.Errorf("só o ID do produto não identifica linha de variante: %+v", items)
+	}
+
+	order, _, _, err := s.ProcessCartPurchase(ctx, user.ID.Hex(), "Cliente", "cliente@exemplo.com", "Rua A, 1",
+		models.PaymentMethodPix, nil, []string{large.LineKey()}, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order.Items) != 1 || order.Items[0].SKU != "CAM-G" || order.Total != 10000 {
+		t.Fatalf("pedido deveria ter só a variante G: %+v (total %d)", order.Items, order.Total)
+	}
+
+	user2, err := repo.GetUserWithCart(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(user2.Cart) != 1 || user2.Cart[0].SKU != "CAM-P" {
+		t.Errorf("a variante P deveria continuar no carrinho: %+v", user2.Cart)
+	}
+}
+
+func TestLineKey(t *testing.T) {
+	id := primitive.NewObjectID()
+	keys := map[string]models.OrderItem{
+		id.Hex():                 {ProductID: id},
+		id.Hex() + ":size:M":     {ProductID: id, Size: "M"},
+		id.Hex() + ":sku:CAM-M":  {ProductID: id, Size: "M", SKU: "CAM-M"},
+		id.Hex() + ":sku:CAM-GG": {ProductID: id, SKU: "CAM-GG"},
+	}
+	for want, item := range keys {
+		if got := item.LineKey(); got != want {
+			t.Errorf("LineKey(%+v) = %q, quer %q", item, got, want)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrCategoryNotFound    = errors.New("categoria não encontrada")
+	ErrCollectionNotFound  = errors.New("coleção não encontrada")
+	ErrCategoryHasChildren = errors.New("a categoria tem subcategorias; exclua-as antes")
+	ErrInvalidCatalogEntry = errors.New("categoria/coleção inválida")
+)
+
+// CategoryEntry é uma linha da árvore já achatada (pais antes dos filhos),
+// com a profundidade para indentar no menu e no admin
+type CategoryEntry struct {
+	models.Category
+	Depth int
+}
+
+// CategoryPage é o que a página /category/{slug} mostra
+type CategoryPage struct {
+	Category    models.Category
+	Breadcrumbs []models.Category // <--- da raiz até o pai (sem a própria)
+	Children    []models.Category
+	Products    []models.Product // <--- da categoria e de todas as descendentes
+}
+
+type CollectionPage struct {
+	Collection models.Collection
+	Products   []models.Product // <--- na ordem da coleção
+}
+
+// CatalogMenu alimenta a navegação da vitrine
+type CatalogMenu struct {
+	Categories  []models.Category // <--- só as raízes
+	Collections []models.Collection
+}
+
+// categoryTree indexa as categorias por ID e por pai
+type categoryTree struct {
+	byID     map[primitive.ObjectID]models.Category
+	children map[primitive.ObjectID][]models.Category
+	roots    []models.Category
+}
+
+func (s *StoreService) loadCategoryTree(ctx context.Context) (*categoryTree, error) {
+	categories, err := s.Repo.GetAllCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &categoryTree{
+		byID:     map[primitive.ObjectID]models.Category{},
+		children: map[primitive.ObjectID][]models.Category{},
+	}
+	for _, c := range categories {
+		tree.byID[c.ID] = c
+	}
+	// Já vêm ordenadas por nome, então filhas e raízes também ficam
+	for _, c := range categories {
+		if c.ParentID != nil {
+			if _, ok := tree.byID[*c.ParentID]; ok {
+				tree.children[*c.ParentID] = append(tree.children[*c.ParentID], c)
+				continue
+			}
+		}
+		tree.roots = append(tree.roots, c) // pai excluído: vira raiz
+	}
+	return tree, nil
+}
+
+func (t *categoryTree) flatten() []CategoryEntry {
+	var entries []CategoryEntry
+	var walk func(c models.Category, depth int)
+	walk = func(c models.Category, depth int) {
+		entries = append(entries, CategoryEntry{Category: c, Depth: depth})
+		for _, child := range t.children[c.ID] {
+			walk(child, depth+1)
+		}
+	}
+	for _, root := range t.roots {
+		walk(root, 0)
+	}
+	return entries
+}
+
+// descendants devolve o ID da categoria e de todas as abaixo dela
+func (t *categoryTree) descendants(id primitive.ObjectID) []primitive.ObjectID {
+	ids := []primitive.ObjectID{id}
+	for _, child := range t.children[id] {
+		ids = append(ids, t.descendants(child.ID)...)
+	}
+	return ids
+}
+
+// ancestors devolve os pais da raiz até o imediato
+func (t *categoryTree) ancestors(c models.Category) []models.Category {
+	var chain []models.Category
+	for c.ParentID != nil && len(chain) < len(t.byID) { // <--- o limite evita loop se houver ciclo
+		parent, ok := t.byID[*c.ParentID]
+		if !ok {
+			break
+		}
+		chain = append([]models.Category{parent}, chain...)
+		c = parent
+	}
+	return chain
+}
+
+func (s *StoreService) GetCategoryTree(ctx context.Context) ([]CategoryEntry, error) {
+	tree, err := s.loadCategoryTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tree.flatten(), nil
+}
+
+func (s *StoreService) GetCatalogMenu(ctx context.Context) (*CatalogMenu, error) {
+	tree, err := s.loadCategoryTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collections, err := s.Repo.GetAllCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &CatalogMenu{Categories: tree.roots, Collections: collections}, nil
+}
+
+func (s *StoreService) GetCategoryPage(ctx context.Context, slug string) (*CategoryPage, error) {
+	tree, err := s.loadCategoryTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var category *models.Category
+	for _, c := range tree.byID {
+		if c.Slug == slug {
+			category = &c
+			break
+		}
+	}
+	if category == nil {
+		return nil, ErrCategoryNotFound
+	}
+
+	products, err := s.Repo.GetProductsByCategories(ctx, tree.descendants(category.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &CategoryPage{
+		Category:    *category,
+		Breadcrumbs: tree.ancestors(*category),
+		Children:    tree.children[category.ID],
+		Products:    products,
+	}, nil
+}
+
+func (s *StoreService) GetCollectionPage(ctx context.Context, slug string) (*CollectionPage, error) {
+	collection, err := s.Repo.GetCollectionBySlug(ctx, slug)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrCollectionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := s.Repo.GetProductsByIDs(ctx, collection.ProductIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reordena na ordem curada (o banco devolve na ordem de inserção)
+	byID := map[primitive.ObjectID]models.Product{}
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+	products := make([]models.Product, 0, len(found))
+	for _, id := range collection.ProductIDs {
+		if p, ok := byID[id]; ok {
+			products = append(products, p)
+		}
+	}
+
+	return &CollectionPage{Collection: *collection, Products: products}, nil
+}
+
+func (s *StoreService) GetCollections(ctx context.Context) ([]models.Collection, error) {
+	return s.Repo.GetAllCollections(ctx)
+}
+
+// ---------------------------------------------------------
+// ADMIN
+// ---------------------------------------------------------
+
+func (s *StoreService) CreateCategory(ctx context.Context, name, slug, parentIDStr string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("%w: nome vazio", ErrInvalidCatalogEntry)
+	}
+	slug, err := normalizeSlug(slug, name)
+	if err != nil {
+		return err
+	}
+
+	category := models.Category{
+		ID:        primitive.NewObjectID(),
+		Name:      name,
+		Slug:      slug,
+		CreatedAt: time.Now(),
+	}
+
+	if parentIDStr != "" {
+		tree, err := s.loadCategoryTree(ctx)
+		if err != nil {
+			return err
+		}
+		parentID, err := primitive.ObjectIDFromHex(parentIDStr)
+		if _, ok := tree.byID[parentID]; err != nil || !ok {
+			return ErrCategoryNotFound
+		}
+		category.ParentID = &parentID
+	}
+
+	return catalogError(s.Repo.CreateCategory(ctx, category))
+}
+
+// DeleteCategory só exclui folhas, para não deixar subcategorias órfãs
+func (s *StoreService) DeleteCategory(ctx context.Context, idStr string) error {
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return ErrCategoryNotFound
+	}
+	tree, err := s.loadCategoryTree(ctx)
+	if err != nil {
+		return err
+	}
+	if len(tree.children[id]) > 0 {
+		return ErrCategoryHasChildren
+	}
+	return s.Repo.DeleteCategory(ctx, id)
+}
+
+func (s *StoreService) CreateCollection(ctx context.Context, name, slug, desc string, productIDs []string) error {
+	collection, err := s.collectionFromForm(name, slug, desc, productIDs)
+	if err != nil {
+		return err
+	}
+	collection.ID = primitive.NewObjectID()
+	collection.CreatedAt = time.Now()
+	return catalogError(s.Repo.CreateCollection(ctx, *collection))
+}
+
+func (s *StoreService) EditCollection(ctx context.Context, idStr, name, slug, desc string, productIDs []string) error {
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return ErrCollectionNotFound
+	}
+	collection, err := s.collectionFromForm(name, slug, desc, productIDs)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.Repo.GetAllCollections(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range existing {
+		if c.ID == id {
+			collection.ID = id
+			collection.CreatedAt = c.CreatedAt // Mantém a data original
+			collection.UpdatedAt = time.Now()
+			return catalogError(s.Repo.EditCollection(ctx, id, *collection))
+		}
+	}
+	return ErrCollectionNotFound
+}
+
+func (s *StoreService) DeleteCollection(ctx context.Context, idStr string) error {
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return ErrCollectionNotFound
+	}
+	return s.Repo.DeleteCollection(ctx, id)
+}
+
+func (s *StoreService) collectionFromForm(name, slug, desc string, productIDs []string) (*models.Collection, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: nome vazio", ErrInvalidCatalogEntry)
+	}
+	slug, err := normalizeSlug(slug, name)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := &models.Collection{Name: name, Slug: slug, Description: strings.TrimSpace(desc), ProductIDs: []primitive.ObjectID{}}
+	for _, idStr := range productIDs {
+		id, err := primitive.ObjectIDFromHex(idStr)
+		if err != nil {
+			return nil, ErrProductNotFound
+		}
+		if !collection.HasProduct(id) {
+			collection.ProductIDs = append(collection.ProductIDs, id)
+		}
+	}
+	return collection, nil
+}
+
+// productCategories valida os IDs vindos do formulário de produto
+func (s *StoreService) productCategories(ctx context.Context, idStrs []string) ([]primitive.ObjectID, error) {
+	if len(idStrs) == 0 {
+		return nil, nil
+	}
+	tree, err := s.loadCategoryTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []primitive.ObjectID
+	for _, idStr := range idStrs {
+		id, err := primitive.ObjectIDFromHex(idStr)
+		if _, ok := tree.byID[id]; err != nil || !ok {
+			return nil, ErrCategoryNotFound
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// catalogError mostra o slug repetido como erro de validação
+func catalogError(err error) error {
+	if errors.Is(err, repository.ErrDuplicateSlug) {
+		return fmt.Errorf("%w: %w", ErrInvalidCatalogEntry, err)
+	}
+	return err
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// normalizeSlug usa o slug informado ou gera um a partir do nome
+func normalizeSlug(slug, name string) (string, error) {
+	slug = strings.TrimSpace(slug)
+	if slug == "" {
+		slug = Slugify(name)
+	}
+	if !slugPattern.MatchString(slug) {
+		return "", fmt.Errorf("%w: slug %q (use letras minúsculas, números e hífens)", ErrInvalidCatalogEntry, slug)
+	}
+	return slug, nil
+}
+
+// Slugify: "Camisetas & Regatas" -> "camisetas-regatas"
+func Slugify(name string) string {
+	name = repository.FoldText(name)
+
+	var b strings.Builder
+	dash := false
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tempo padrão que um carrinho de visitante sem mexer fica guardado
+const DefaultGuestCartTTL = 30 * 24 * time.Hour
+
+var ErrInvalidGuestCart = errors.New("carrinho de visitante inválido")
+
+// NewGuestCartID gera o ID de um carrinho de visitante (vai no cookie assinado)
+func NewGuestCartID() string {
+	return primitive.NewObjectID().Hex()
+}
+
+func guestCartID(cartIDStr string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(cartIDStr)
+	if err != nil {
+		return primitive.NilObjectID, ErrInvalidGuestCart
+	}
+	return id, nil
+}
+
+// AddProductToGuestCart funciona como o AddProductToCart e devolve até
+// quando o carrinho fica guardado (a validade do cookie)
+func (s *StoreService) AddProductToGuestCart(ctx context.Context, cartIDStr, productIDStr string, quantity int, sku, size string) (time.Time, error) {
+	cartID, err := guestCartID(cartIDStr)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	item, stock, err := s.cartItem(ctx, productIDStr, quantity, sku, size)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(s.GuestCartTTL)
+	return expiresAt, s.Repo.AddItemToGuestCart(ctx, cartID, item, stock, expiresAt)
+}
+
+func (s *StoreService) RemoveProductFromGuestCart(ctx context.Context, cartIDStr, productIDStr, sku, size string) error {
+	cartID, err := guestCartID(cartIDStr)
+	if err != nil {
+		return err
+	}
+	productID, _ := primitive.ObjectIDFromHex(productIDStr)
+
+	return s.Repo.RemoveItemFromGuestCart(ctx, cartID, productID, sku, size)
+}
+
+func (s *StoreService) UpdateGuestCartItemQuantity(ctx context.Context, cartIDStr, productIDStr string, quantity int, sku, size string) error {
+	if quantity <= 0 {
+		return errors.New("quantidade deve ser maior que zero")
+	}
+	cartID, err := guestCartID(cartIDStr)
+	if err != nil {
+		return err
+	}
+	productID, _ := primitive.ObjectIDFromHex(productIDStr)
+
+	return s.Repo.UpdateGuestCartItemQuantity(ctx, cartID, productID, quantity, sku, size)
+}
+
+// MergeGuestCart leva o carrinho de visitante para a conta (login/cadastro).
+// Linhas iguais somam, sempre limitadas ao estoque atual; itens de produto
+// excluído, variante que sumiu ou sem estoque ficam de fora.
+func (s *StoreService) MergeGuestCart(ctx context.Context, userIDStr, cartIDStr string) error {
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return fmt.Errorf("usuário inválido: %w", err)
+	}
+	cartID, err := guestCartID(cartIDStr)
+	if err != nil {
+		return err
+	}
+
+	cart, err := s.Repo.GetGuestCart(ctx, cartID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Tudo ou nada: sem isso, repetir o login depois de uma falha somaria
+	// de novo os itens que já tinham passado
+	return s.Repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		for _, item := range cart.Cart {
+			product, err := s.Repo.GetProductByID(ctx, item.ProductID)
+			if errors.Is(err, repository.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if product.HasVariants() {
+				if _, ok := product.Variant(item.SKU); !ok {
+					continue
+				}
+			}
+
+			stock := product.StockFor(item.SKU)
+			if stock <= 0 {
+				continue
+			}
+			if err := s.Repo.AddItemToCart(ctx, userID, item, stock); err != nil {
+				return err
+			}
+		}
+		return s.Repo.DeleteGuestCart(ctx, cartID)
+	})
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidTransition = errors.New("transição de status não permitida")
+
+// orderTransitions: para cada status, os próximos status permitidos.
+// Status sem entrada (cancelado, reembolsado, expirado) são finais.
+var orderTransitions = map[models.OrderStatus][]models.OrderStatus{
+	models.OrderStatusPending:   {models.OrderStatusPaid, models.OrderStatusCancelled, models.OrderStatusExpired},
+	models.OrderStatusPaid:      {models.OrderStatusPicking, models.OrderStatusRefunded},
+	models.OrderStatusPicking:   {models.OrderStatusShipped, models.OrderStatusRefunded},
+	models.OrderStatusShipped:   {models.OrderStatusDelivered},
+	models.OrderStatusDelivered: {models.OrderStatusRefunded},
+}
+
+// NextStatuses lista para onde o pedido pode ir a partir de "from"
+func NextStatuses(from models.OrderStatus) []models.OrderStatus {
+	return orderTransitions[from]
+}
+
+// CanTransition diz se a mudança from -> to está na tabela de transições
+func CanTransition(from, to models.OrderStatus) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeOrderStatus valida e aplica a mudança de status, registrando quem fez
+func (s *StoreService) ChangeOrderStatus(ctx context.Context, orderIDStr string, to models.OrderStatus, changedBy string) error {
+	objID, err := primitive.ObjectIDFromHex(orderIDStr)
+	if err != nil {
+		return err
+	}
+
+	order, err := s.Repo.GetOrderByID(ctx, objID)
+	if err != nil {
+		return err
+	}
+
+	// Reembolso pelo painel: estorna no provedor antes de mudar o status
+	if to == models.OrderStatusRefunded && CanTransition(order.Status, to) && order.PaymentID != "" {
+		if err := s.Payment.Refund(ctx, order.PaymentID, order.Total); err != nil {
+			return fmt.Errorf("falha ao estornar pagamento: %w", err)
+		}
+		// O dinheiro já voltou: grava o status mesmo se o cliente desconectar
+		err := s.transitionOrder(context.WithoutCancel(ctx), order, to, changedBy)
+		if err != nil {
+			log.Printf("ERRO CRÍTICO: pagamento %s estornado mas o pedido %s não mudou de status: %v", order.PaymentID, order.ID.Hex(), err)
+		}
+		return err
+	}
+
+	// Cancelamento de pedido aguardando pagamento: o PIX em aberto não pode
+	// mais ser pago depois que o estoque voltar
+	if to == models.OrderStatusCancelled && order.Status == models.OrderStatusPending && order.PaymentID != "" {
+		if err := s.Payment.Void(ctx, order.PaymentID); err != nil {
+			return fmt.Errorf("falha ao cancelar cobrança: %w", err)
+		}
+	}
+
+	return s.transitionOrder(ctx, order, to, changedBy)
+}
+
+// transitionOrder valida e grava a transição. Cancelar/expirar um pedido
+// aguardando pagamento devolve a reserva de estoque na mesma transação.
+// Não fala com o provedor de pagamento: quem chama decide (painel ou webhook).
+func (s *StoreService) transitionOrder(ctx context.Context, order *models.Order, to models.OrderStatus, changedBy string) error {
+	if !CanTransition(order.Status, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, order.Status, to)
+	}
+
+	change := models.OrderStatusChange{
+		From:      order.Status,
+		To:        to,
+		ChangedBy: changedBy,
+		ChangedAt: time.Now(),
+	}
+
+	releaseStock := order.Status == models.OrderStatusPending &&
+		(to == models.OrderStatusCancelled || to == models.OrderStatusExpired)
+
+	return s.Repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := s.Repo.TransitionOrderStatus(ctx, order.ID, change); err != nil {
+			return err
+		}
+		if releaseStock {
+			for _, item := range order.Items {
+				if err := s.Repo.IncrementStock(ctx, item.ProductID, item.SKU, item.Quantity); err != nil {
+					return err
+				}
+			}
+			// Os cupons/promoções usados voltam a valer para o cliente
+			if len(order.Discounts) > 0 {
+				if err := s.Repo.ReleasePromotionRedemptions(ctx, order.ID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
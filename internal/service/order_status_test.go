@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// A matriz inteira: tudo que não está em allowed tem que ser negado
+func TestCanTransition(t *testing.T) {
+	statuses := []models.OrderStatus{
+		models.OrderStatusPending, models.OrderStatusPaid, models.OrderStatusPicking, models.OrderStatusShipped,
+		models.OrderStatusDelivered, models.OrderStatusCancelled, models.OrderStatusRefunded, models.OrderStatusExpired,
+	}
+	type transition struct{ from, to models.OrderStatus }
+	allowed := map[transition]bool{
+		{models.OrderStatusPending, models.OrderStatusPaid}:       true,
+		{models.OrderStatusPending, models.OrderStatusCancelled}:  true,
+		{models.OrderStatusPending, models.OrderStatusExpired}:    true,
+		{models.OrderStatusPaid, models.OrderStatusPicking}:       true,
+		{models.OrderStatusPaid, models.OrderStatusRefunded}:      true,
+		{models.OrderStatusPicking, models.OrderStatusShipped}:    true,
+		{models.OrderStatusPicking, models.OrderStatusRefunded}:   true,
+		{models.OrderStatusShipped, models.OrderStatusDelivered}:  true,
+		{models.OrderStatusDelivered, models.OrderStatusRefunded}: true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[transition{from, to}]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, quer %v", from, to, got, want)
+			}
+		}
+	}
+
+	// Status finais não vão para lugar nenhum
+	for _, final := range []models.OrderStatus{models.OrderStatusCancelled, models.OrderStatusRefunded, models.OrderStatusExpired} {
+		if next := NextStatuses(final); len(next) != 0 {
+			t.Errorf("%s deveria ser final, vai para %v", final, next)
+		}
+	}
+	if CanTransition("DESCONHECIDO", models.OrderStatusPaid) {
+		t.Error("status desconhecido não pode transicionar")
+	}
+}
+
+// Cancelar pedido aguardando pagamento cancela o PIX; se o provedor recusar
+// (ex: já foi pago), o pedido continua como estava
+func TestCancelPendingOrderVoidsPix(t *testing.T) {
+	ctx := context.Background()
+	mem := repository.NewMemoryStore()
+	gateway := NewFakePaymentGateway(PixConfig{Key: "loja@exemplo.com", MerchantName: "Loja", MerchantCity: "Sao Paulo"})
+	s := NewStoreService(mem, gateway)
+
+	newOrder := func() (models.Order, string) {
+		t.Helper()
+		charge, err := gateway.CreatePixCharge(ctx, PixChargeRequest{TxID: "pedido", Amount: 3000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		order := models.Order{
+			ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Status: models.OrderStatusPending,
+			PaymentMethod: models.PaymentMethodPix, PaymentID: charge.ID, Total: 3000, CreatedAt: time.Now(),
+		}
+		if err := mem.CreateOrder(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+		return order, charge.ID
+	}
+
+	order, chargeID := newOrder()
+	if err := s.ChangeOrderStatus(ctx, order.ID.Hex(), models.OrderStatusCancelled, "suporte"); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := gateway.QueryStatus(ctx, chargeID); st != PaymentStatusVoided {
+		t.Errorf("cobrança ficou %s, quer %s", st, PaymentStatusVoided)
+	}
+	if got, _ := mem.GetOrderByID(ctx, order.ID); got.Status != models.OrderStatusCancelled {
+		t.Errorf("pedido ficou %s", got.Status)
+	}
+
+	paid, chargeID := newOrder()
+	if err := gateway.MarkPixPaid(chargeID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ChangeOrderStatus(ctx, paid.ID.Hex(), models.OrderStatusCancelled, "suporte"); err == nil {
+		t.Fatal("cancelar com o PIX já pago deveria falhar")
+	}
+	if got, _ := mem.GetOrderByID(ctx, paid.ID); got.Status != models.OrderStatusPending {
+		t.Errorf("pedido ficou %s, quer %s", got.Status, models.OrderStatusPending)
+	}
+}
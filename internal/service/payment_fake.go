@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Cartões de teste do provedor fake. Qualquer outro número é aprovado.
+const (
+	FakeCardDeclined    = "4000000000000002"
+	FakeCardRequires3DS = "4000000000003220"
+	FakeCardTimeout     = "4000000000000119"
+)
+
+type fakePayment struct {
+	status PaymentStatus
+	amount int64
+}
+
+// FakePaymentGateway é um provedor em memória e determinístico, para
+// desenvolvimento local e testes.
+type FakePaymentGateway struct {
+	pix PixConfig
+
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+	cards    map[string]CardDetails // token -> cartão (uso único)
+}
+
+func NewFakePaymentGateway(pix PixConfig) *FakePaymentGateway {
+	return &FakePaymentGateway{
+		pix:      pix,
+		payments: make(map[string]*fakePayment),
+		cards:    make(map[string]CardDetails),
+	}
+}
+
+// newID não usa contador: o orders.payment_id é único no banco e os IDs
+// precisam continuar únicos depois de reiniciar o processo
+func newID(prefix string) string {
+	return prefix + "_" + primitive.NewObjectID().Hex()
+}
+
+func (g *FakePaymentGateway) TokenizeCard(ctx context.Context, card CardDetails) (*CardToken, error) {
+	if _, err := ValidateCard(card, time.Now()); err != nil {
+		return nil, err
+	}
+	number := NormalizeCardNumber(card.Number)
+	card.Number = number
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	token := newID("fake_tok")
+	g.cards[token] = card
+	return &CardToken{Token: token, Brand: DetectCardBrand(number), Last4: number[len(number)-4:]}, nil
+}
+
+func (g *FakePaymentGateway) Authorize(ctx context.Context, req CardAuthorization) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	card, ok := g.cards[req.CardToken]
+	if !ok {
+		return "", ErrPaymentNotFound
+	}
+	delete(g.cards, req.CardToken)
+
+	switch card.Number {
+	case FakeCardDeclined:
+		return "", ErrPaymentDeclined
+	case FakeCardRequires3DS:
+		return "", ErrPaymentRequires3DS
+	case FakeCardTimeout:
+		return "", ErrPaymentTimeout
+	}
+
+	id := newID("fake_card")
+	g.payments[id] = &fakePayment{status: PaymentStatusAuthorized, amount: req.Amount}
+	return id, nil
+}
+
+// transition muda o status se o pagamento estiver em um dos estados "from"
+func (g *FakePaymentGateway) transition(paymentID string, to PaymentStatus, from ...PaymentStatus) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, ok := g.payments[paymentID]
+	if !ok {
+		return ErrPaymentNotFound
+	}
+	for _, f := range from {
+		if p.status == f {
+			p.status = to
+			return nil
+		}
+	}
+	return ErrPaymentInvalidState
+}
+
+func (g *FakePaymentGateway) Capture(ctx context.Context, paymentID string, amount int64) error {
+	return g.transition(paymentID, PaymentStatusCaptured, PaymentStatusAuthorized)
+}
+
+func (g *FakePaymentGateway) Void(ctx context.Context, paymentID string) error {
+	return g.transition(paymentID, PaymentStatusVoided, PaymentStatusAuthorized, PaymentStatusPending)
+}
+
+func (g *FakePaymentGateway) Refund(ctx context.Context, paymentID string, amount int64) error {
+	return g.transition(paymentID, PaymentStatusRefunded, PaymentStatusCaptured, PaymentStatusPaid)
+}
+
+func (g *FakePaymentGateway) CreatePixCharge(ctx context.Context, req PixChargeRequest) (*PixCharge, error) {
+	payload, err := BuildPixPayload(g.pix, req.Amount, req.TxID)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := newID("fake_pix")
+	g.payments[id] = &fakePayment{status: PaymentStatusPending, amount: req.Amount}
+	return &PixCharge{ID: id, Payload: payload}, nil
+}
+
+// MarkPixPaid simula o cliente pagando o PIX no app do banco
+func (g *FakePaymentGateway) MarkPixPaid(paymentID string) error {
+	return g.transition(paymentID, PaymentStatusPaid, PaymentStatusPending)
+}
+
+func (g *FakePaymentGateway) QueryStatus(ctx context.Context, paymentID string) (PaymentStatus, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, ok := g.payments[paymentID]
+	if !ok {
+		return "", ErrPaymentNotFound
+	}
+	return p.status, nil
+}
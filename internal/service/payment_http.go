@@ -0,0 +1,268 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Códigos de erro trocados no JSON entre o adapter HTTP e o provedor
+var paymentErrorCodes = map[string]error{
+	"declined":      ErrPaymentDeclined,
+	"requires_3ds":  ErrPaymentRequires3DS,
+	"timeout":       ErrPaymentTimeout,
+	"not_found":     ErrPaymentNotFound,
+	"invalid_state": ErrPaymentInvalidState,
+
+	"card_number_invalid": ErrCardNumberInvalid,
+	"card_brand_unknown":  ErrCardBrandUnknown,
+	"card_expiry_invalid": ErrCardExpiryInvalid,
+	"card_expired":        ErrCardExpired,
+	"card_cvv_invalid":    ErrCardCVVInvalid,
+}
+
+func paymentErrorCode(err error) string {
+	for code, e := range paymentErrorCodes {
+		if errors.Is(err, e) {
+			return code
+		}
+	}
+	return "provider_failure"
+}
+
+// HTTPPaymentGateway fala JSON com um provedor externo. O mesmo protocolo é
+// servido por NewPaymentStandInHandler, para testes de integração locais.
+type HTTPPaymentGateway struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+func NewHTTPPaymentGateway(baseURL, apiKey string) *HTTPPaymentGateway {
+	return &HTTPPaymentGateway{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type paymentResponse struct {
+	ID     string        `json:"id"`
+	Status PaymentStatus `json:"status"`
+}
+
+type paymentErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// do envia o request e decodifica a resposta em out (se não for nil)
+func (g *HTTPPaymentGateway) do(ctx context.Context, method, path string, body, out any) error {
+	var reader *bytes.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(buf)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+g.apiKey)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &urlErr) && urlErr.Timeout()) {
+			return fmt.Errorf("%w: %v", ErrPaymentTimeout, err)
+		}
+		return fmt.Errorf("%w: %v", ErrPaymentProviderFailure, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e paymentErrorResponse
+		json.NewDecoder(resp.Body).Decode(&e)
+		if known, ok := paymentErrorCodes[e.Error]; ok {
+			return known
+		}
+		return fmt.Errorf("%w: status %d", ErrPaymentProviderFailure, resp.StatusCode)
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+func (g *HTTPPaymentGateway) TokenizeCard(ctx context.Context, card CardDetails) (*CardToken, error) {
+	var token CardToken
+	if err := g.do(ctx, http.MethodPost, "/tokens", card, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (g *HTTPPaymentGateway) Authorize(ctx context.Context, req CardAuthorization) (string, error) {
+	var resp paymentResponse
+	if err := g.do(ctx, http.MethodPost, "/authorizations", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+func (g *HTTPPaymentGateway) Capture(ctx context.Context, paymentID string, amount int64) error {
+	return g.do(ctx, http.MethodPost, "/payments/"+url.PathEscape(paymentID)+"/capture", map[string]int64{"amount": amount}, nil)
+}
+
+func (g *HTTPPaymentGateway) Void(ctx context.Context, paymentID string) error {
+	return g.do(ctx, http.MethodPost, "/payments/"+url.PathEscape(paymentID)+"/void", nil, nil)
+}
+
+func (g *HTTPPaymentGateway) Refund(ctx context.Context, paymentID string, amount int64) error {
+	return g.do(ctx, http.MethodPost, "/payments/"+url.PathEscape(paymentID)+"/refund", map[string]int64{"amount": amount}, nil)
+}
+
+func (g *HTTPPaymentGateway) CreatePixCharge(ctx context.Context, req PixChargeRequest) (*PixCharge, error) {
+	var charge PixCharge
+	if err := g.do(ctx, http.MethodPost, "/pix/charges", req, &charge); err != nil {
+		return nil, err
+	}
+	return &charge, nil
+}
+
+func (g *HTTPPaymentGateway) QueryStatus(ctx context.Context, paymentID string) (PaymentStatus, error) {
+	var resp paymentResponse
+	if err := g.do(ctx, http.MethodGet, "/payments/"+url.PathEscape(paymentID), nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.Status, nil
+}
+
+// NewPaymentStandInHandler expõe um FakePaymentGateway no protocolo do
+// HTTPPaymentGateway. Serve como provedor local para testes de integração.
+func NewPaymentStandInHandler(fake *FakePaymentGateway) http.Handler {
+	r := chi.NewRouter()
+
+	writeJSON := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+	writeErr := func(w http.ResponseWriter, err error) {
+		status := http.StatusUnprocessableEntity
+		switch {
+		case errors.Is(err, ErrPaymentNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrPaymentTimeout):
+			status = http.StatusGatewayTimeout
+		}
+		writeJSON(w, status, paymentErrorResponse{Error: paymentErrorCode(err)})
+	}
+	status := func(w http.ResponseWriter, r *http.Request, id string) {
+		st, err := fake.QueryStatus(r.Context(), id)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, paymentResponse{ID: id, Status: st})
+	}
+
+	r.Post("/tokens", func(w http.ResponseWriter, r *http.Request) {
+		var card CardDetails
+		if err := json.NewDecoder(r.Body).Decode(&card); err != nil {
+			writeJSON(w, http.StatusBadRequest, paymentErrorResponse{Error: "bad_request"})
+			return
+		}
+		token, err := fake.TokenizeCard(r.Context(), card)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, token)
+	})
+
+	r.Post("/authorizations", func(w http.ResponseWriter, r *http.Request) {
+		var req CardAuthorization
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, paymentErrorResponse{Error: "bad_request"})
+			return
+		}
+		id, err := fake.Authorize(r.Context(), req)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, paymentResponse{ID: id, Status: PaymentStatusAuthorized})
+	})
+
+	r.Post("/payments/{id}/{action}", func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		var body struct {
+			Amount int64 `json:"amount"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		var err error
+		switch chi.URLParam(r, "action") {
+		case "capture":
+			err = fake.Capture(r.Context(), id, body.Amount)
+		case "void":
+			err = fake.Void(r.Context(), id)
+		case "refund":
+			err = fake.Refund(r.Context(), id, body.Amount)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		status(w, r, id)
+	})
+
+	r.Get("/payments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		status(w, r, chi.URLParam(r, "id"))
+	})
+
+	r.Post("/pix/charges", func(w http.ResponseWriter, r *http.Request) {
+		var req PixChargeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, paymentErrorResponse{Error: "bad_request"})
+			return
+		}
+		charge, err := fake.CreatePixCharge(r.Context(), req)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, charge)
+	})
+
+	// Simula o cliente pagando o PIX no app do banco
+	r.Post("/pix/charges/{id}/pay", func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		if err := fake.MarkPixPaid(id); err != nil {
+			writeErr(w, err)
+			return
+		}
+		status(w, r, id)
+	})
+
+	return r
+}
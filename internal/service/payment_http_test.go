@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newStandIn sobe o provedor local (NewPaymentStandInHandler) e devolve o
+// adapter HTTP apontando para ele
+func newStandIn(t *testing.T) (*HTTPPaymentGateway, *httptest.Server) {
+	t.Helper()
+	fake := NewFakePaymentGateway(PixConfig{Key: "loja@exemplo.com", MerchantName: "Loja", MerchantCity: "Sao Paulo"})
+	srv := httptest.NewServer(NewPaymentStandInHandler(fake))
+	t.Cleanup(srv.Close)
+	return NewHTTPPaymentGateway(srv.URL, "chave-de-teste"), srv
+}
+
+func testCard(number string) CardDetails {
+	return CardDetails{Number: number, HolderName: "Cliente", CVV: "123", ExpMonth: 12, ExpYear: time.Now().Year() + 2}
+}
+
+func TestHTTPGatewayCardErrors(t *testing.T) {
+	ctx := context.Background()
+	gw, _ := newStandIn(t)
+
+	cases := []struct {
+		name   string
+		number string
+		want   error
+	}{
+		{"recusado", FakeCardDeclined, ErrPaymentDeclined},
+		{"3DS", FakeCardRequires3DS, ErrPaymentRequires3DS},
+		{"timeout do provedor", FakeCardTimeout, ErrPaymentTimeout},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := gw.TokenizeCard(ctx, testCard(tc.number))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := gw.Authorize(ctx, CardAuthorization{CardToken: token.Token, Amount: 1000}); !errors.Is(err, tc.want) {
+				t.Errorf("Authorize = %v, quer %v", err, tc.want)
+			}
+		})
+	}
+
+	if _, err := gw.TokenizeCard(ctx, testCard("4111111111111112")); !errors.Is(err, ErrCardNumberInvalid) {
+		t.Errorf("cartão inválido na tokenização = %v", err)
+	}
+	if _, err := gw.QueryStatus(ctx, "nao_existe"); !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("QueryStatus de cobrança desconhecida = %v", err)
+	}
+}
+
+func TestHTTPGatewayCardLifecycle(t *testing.T) {
+	ctx := context.Background()
+	gw, _ := newStandIn(t)
+
+	authorize := func() string {
+		t.Helper()
+		token, err := gw.TokenizeCard(ctx, testCard("4111111111111111"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Brand != CardBrandVisa || token.Last4 != "1111" {
+			t.Errorf("token = %+v", token)
+		}
+		id, err := gw.Authorize(ctx, CardAuthorization{CardToken: token.Token, Amount: 2500})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	expect := func(id string, want PaymentStatus) {
+		t.Helper()
+		if got, err := gw.QueryStatus(ctx, id); err != nil || got != want {
+			t.Errorf("status de %s = %s (%v), quer %s", id, got, err, want)
+		}
+	}
+
+	// Captura e estorno
+	captured := authorize()
+	expect(captured, PaymentStatusAuthorized)
+	if err := gw.Capture(ctx, captured, 2500); err != nil {
+		t.Fatal(err)
+	}
+	expect(captured, PaymentStatusCaptured)
+	if err := gw.Refund(ctx, captured, 2500); err != nil {
+		t.Fatal(err)
+	}
+	expect(captured, PaymentStatusRefunded)
+	if err := gw.Refund(ctx, captured, 2500); !errors.Is(err, ErrPaymentInvalidState) {
+		t.Errorf("segundo estorno = %v", err)
+	}
+
+	// Cancelamento da autorização
+	voided := authorize()
+	if err := gw.Void(ctx, voided); err != nil {
+		t.Fatal(err)
+	}
+	expect(voided, PaymentStatusVoided)
+	if err := gw.Capture(ctx, voided, 2500); !errors.Is(err, ErrPaymentInvalidState) {
+		t.Errorf("captura depois do cancelamento = %v", err)
+	}
+}
+
+func TestHTTPGatewayPix(t *testing.T) {
+	ctx := context.Background()
+	gw, srv := newStandIn(t)
+
+	charge, err := gw.CreatePixCharge(ctx, PixChargeRequest{TxID: "pedido1", Amount: 1990})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if charge.ID == "" || charge.Payload == "" {
+		t.Fatalf("cobrança = %+v", charge)
+	}
+	if st, _ := gw.QueryStatus(ctx, charge.ID); st != PaymentStatusPending {
+		t.Errorf("PIX novo = %s", st)
+	}
+
+	resp, err := http.Post(srv.URL+"/pix/charges/"+charge.ID+"/pay", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if st, _ := gw.QueryStatus(ctx, charge.ID); st != PaymentStatusPaid {
+		t.Errorf("PIX pago = %s", st)
+	}
+	if err := gw.Refund(ctx, charge.ID, 1990); err != nil {
+		t.Errorf("estorno do PIX pago: %v", err)
+	}
+
+	// PIX em aberto pode ser cancelado (checkout que falhou)
+	open, err := gw.CreatePixCharge(ctx, PixChargeRequest{TxID: "pedido2", Amount: 1990})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Void(ctx, open.ID); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := gw.QueryStatus(ctx, open.ID); st != PaymentStatusVoided {
+		t.Errorf("PIX cancelado = %s", st)
+	}
+}
+
+// Provedor lento demais: o adapter devolve ErrPaymentTimeout
+func TestHTTPGatewayClientTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	gw := NewHTTPPaymentGateway(srv.URL, "")
+	gw.client.Timeout = 50 * time.Millisecond
+	if _, err := gw.QueryStatus(context.Background(), "qualquer"); !errors.Is(err, ErrPaymentTimeout) {
+		t.Errorf("QueryStatus = %v, quer ErrPaymentTimeout", err)
+	}
+}
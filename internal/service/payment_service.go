@@ -1,36 +1,108 @@
 package service
 
 import (
-	// "errors"
-	// "strings"
+	"context"
+	"errors"
+	"fmt"
+	"log"
 
+	"github.com/skip2/go-qrcode"
 )
 
-type PaymentService struct{}
+// Erros padronizados que qualquer provedor de pagamento deve devolver
+var (
+	ErrPaymentDeclined        = errors.New("pagamento recusado pela operadora")
+	ErrPaymentRequires3DS     = errors.New("pagamento exige autenticação 3DS")
+	ErrPaymentTimeout         = errors.New("tempo esgotado ao falar com o provedor de pagamento")
+	ErrPaymentNotFound        = errors.New("pagamento não encontrado no provedor")
+	ErrPaymentInvalidState    = errors.New("operação não permitida no estado atual do pagamento")
+	ErrPaymentProviderFailure = errors.New("falha no provedor de pagamento")
+)
+
+// PaymentStatus é o estado de uma cobrança do lado do provedor
+type PaymentStatus string
+
+const (
+	PaymentStatusAuthorized PaymentStatus = "authorized"
+	PaymentStatusCaptured   PaymentStatus = "captured"
+	PaymentStatusVoided     PaymentStatus = "voided"
+	PaymentStatusRefunded   PaymentStatus = "refunded"
+	PaymentStatusPending    PaymentStatus = "pending" // PIX gerado, aguardando pagamento
+	PaymentStatusPaid       PaymentStatus = "paid"    // PIX pago
+)
+
+// CardAuthorization autoriza uma cobrança a partir do token do cartão
+type CardAuthorization struct {
+	CardToken string `json:"card_token"`
+	Amount    int64  `json:"amount"`
+}
 
-func NewPaymentService() *PaymentService {
-	return &PaymentService{}
+// PixChargeRequest pede um PIX para um pedido (txid = ID do pedido)
+type PixChargeRequest struct {
+	TxID   string `json:"txid"`
+	Amount int64  `json:"amount"`
 }
 
-// ProcessPaymentCard simula cartão
-func (s *PaymentService) ProcessPaymentCard(cardNumber, cardName, cvv string, amount int64) error {
-	// cleanNum := strings.ReplaceAll(cardNumber, " ", "")
-	// if len(cleanNum) < 16 {
-	// 	return errors.New("número de cartão inválido")
-	// }
-	// if strings.HasSuffix(cleanNum, "0000") {
-	// 	return errors.New("transação recusada pela operadora (Saldo Insuficiente)")
-	// }
-	return nil
+// PixCharge é a cobrança PIX criada no provedor
+type PixCharge struct {
+	ID      string `json:"id"`
+	Payload string `json:"payload"` // BR Code "Copia e Cola"
 }
 
-// GeneratePix gera o código e a imagem QR Code em Base64
-func (s *PaymentService) GeneratePix(amount int64) (string, string, error) {
-	// 1. Código "Copia e Cola" Simulado (Formato padrão EMV)
-	// Num cenário real, isso viria da API do Banco (PSP)
-	pixPayload := "00020126330014BR.GOV.BCB.PIX011112345678900520400005303986540510.005802BR5913Olecram Shop6008Sao Paulo62070503***6304B6CD"
+// PaymentGateway é o contrato com o provedor de pagamento (fake, HTTP, ...).
+// Todos os valores são em centavos.
+type PaymentGateway interface {
+	TokenizeCard(ctx context.Context, card CardDetails) (*CardToken, error)
+	Authorize(ctx context.Context, req CardAuthorization) (string, error)
+	Capture(ctx context.Context, paymentID string, amount int64) error
+	// Void cancela uma autorização de cartão ou um PIX ainda não pago
+	Void(ctx context.Context, paymentID string) error
+	Refund(ctx context.Context, paymentID string, amount int64) error
+	CreatePixCharge(ctx context.Context, req PixChargeRequest) (*PixCharge, error)
+	QueryStatus(ctx context.Context, paymentID string) (PaymentStatus, error)
+}
+
+// PaymentConfig escolhe e configura o provedor
+type PaymentConfig struct {
+	Provider string // "fake" (padrão) ou "http"
+	HTTPURL  string
+	APIKey   string
+	Pix      PixConfig
+}
 
-	imagePath := "/static/pix-qrcode.png"
+// NewPaymentGateway monta o provedor configurado
+func NewPaymentGateway(cfg PaymentConfig) (PaymentGateway, error) {
+	switch cfg.Provider {
+	case "", "fake":
+		return NewFakePaymentGateway(cfg.Pix), nil
+	case "http":
+		if cfg.HTTPURL == "" {
+			return nil, errors.New("PAYMENT_HTTP_URL é obrigatório para o provedor http")
+		}
+		return NewHTTPPaymentGateway(cfg.HTTPURL, cfg.APIKey), nil
+	default:
+		return nil, fmt.Errorf("provedor de pagamento desconhecido: %q", cfg.Provider)
+	}
+}
+
+// chargeCard autoriza e captura na sequência. Se a captura falhar, a
+// autorização é cancelada para não prender o limite do cliente.
+func chargeCard(ctx context.Context, gw PaymentGateway, req CardAuthorization) (string, error) {
+	paymentID, err := gw.Authorize(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if err := gw.Capture(ctx, paymentID, req.Amount); err != nil {
+		// Também quando o cliente desconectou: a autorização não pode ficar presa
+		if voidErr := gw.Void(context.WithoutCancel(ctx), paymentID); voidErr != nil {
+			log.Printf("ERRO CRÍTICO: captura de %s falhou e a autorização não foi cancelada: %v", paymentID, voidErr)
+		}
+		return "", err
+	}
+	return paymentID, nil
+}
 
-	return pixPayload, imagePath, nil
+// PixQRCode renderiza o BR Code como PNG
+func PixQRCode(payload string) ([]byte, error) {
+	return qrcode.Encode(payload, qrcode.Medium, 256)
 }
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// captureFails derruba o request durante a captura (cliente desconectou) e
+// registra o contexto recebido pelo cancelamento
+type captureFails struct {
+	*FakePaymentGateway
+	disconnect context.CancelFunc
+	voidCtxErr error
+	voided     bool
+}
+
+func (g *captureFails) Capture(ctx context.Context, paymentID string, amount int64) error {
+	g.disconnect()
+	return ErrPaymentTimeout
+}
+
+func (g *captureFails) Void(ctx context.Context, paymentID string) error {
+	g.voided, g.voidCtxErr = true, ctx.Err()
+	return g.FakePaymentGateway.Void(ctx, paymentID)
+}
+
+// Captura falhou com o cliente já desconectado: a autorização é cancelada assim mesmo
+func TestChargeCardVoidsWhenCaptureFails(t *testing.T) {
+	gw := &captureFails{FakePaymentGateway: NewFakePaymentGateway(PixConfig{})}
+	token, err := gw.TokenizeCard(context.Background(), testCard("4111111111111111"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	gw.disconnect = cancel
+	_, err = chargeCard(ctx, gw, CardAuthorization{CardToken: token.Token, Amount: 1000})
+	if !errors.Is(err, ErrPaymentTimeout) {
+		t.Fatalf("erro = %v, quer o da captura", err)
+	}
+	if !gw.voided || gw.voidCtxErr != nil {
+		t.Errorf("Void chamado = %v com ctx %v; quer chamado e sem cancelamento", gw.voided, gw.voidCtxErr)
+	}
+}
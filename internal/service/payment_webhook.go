@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+)
+
+// Tipos de evento aceitos no webhook de pagamentos
+const (
+	PaymentEventPaid     = "payment.paid"
+	PaymentEventRefunded = "payment.refunded"
+)
+
+// Diferença máxima entre o timestamp assinado e o relógio do servidor
+const WebhookTolerance = 5 * time.Minute
+
+var ErrInvalidSignature = errors.New("assinatura do webhook inválida")
+
+// SignWebhook gera o header X-Signature ("t=<unix>,v1=<hex>") para um corpo.
+// O HMAC-SHA256 cobre "<timestamp>.<corpo>", o que impede replay com outro horário.
+func SignWebhook(secret string, body []byte, at time.Time) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + ts + ",v1=" + webhookMAC(secret, ts, body)
+}
+
+func webhookMAC(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature confere o header X-Signature e a janela de tempo
+func VerifyWebhookSignature(secret, header string, body []byte, now time.Time) error {
+	if secret == "" {
+		return fmt.Errorf("%w: segredo não configurado", ErrInvalidSignature)
+	}
+
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	if ts == "" || sig == "" {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if diff := now.Sub(time.Unix(unix, 0)); diff > WebhookTolerance || diff < -WebhookTolerance {
+		return fmt.Errorf("%w: timestamp fora da janela", ErrInvalidSignature)
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(webhookMAC(secret, ts, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// HandlePaymentEvent aplica um evento do provedor ao pedido. Eventos já vistos
+// são ignorados (duplicate = true). Reentregas concorrentes não mudam o pedido
+// duas vezes porque a transição de status é condicional.
+func (s *StoreService) HandlePaymentEvent(ctx context.Context, event models.PaymentEvent) (bool, error) {
+	if event.ID == "" || event.PaymentID == "" {
+		return false, errors.New("evento sem id ou payment_id")
+	}
+
+	seen, err := s.Repo.PaymentEventExists(ctx, event.ID)
+	if err != nil {
+		return false, err
+	}
+	if seen {
+		return true, nil
+	}
+
+	order, err := s.Repo.GetOrderByPaymentID(ctx, event.PaymentID)
+	if err != nil {
+		return false, fmt.Errorf("pedido da cobrança %s: %w", event.PaymentID, err)
+	}
+
+	var target models.OrderStatus
+	switch event.Type {
+	case PaymentEventPaid:
+		target = models.OrderStatusPaid
+	case PaymentEventRefunded:
+		target = models.OrderStatusRefunded
+	default:
+		return false, fmt.Errorf("tipo de evento desconhecido: %s", event.Type)
+	}
+
+	switch {
+	case order.Status == target:
+		// Já aplicado (ex: outra entrega do mesmo evento com ID diferente)
+	case event.Type == PaymentEventPaid && (order.Status == models.OrderStatusExpired || order.Status == models.OrderStatusCancelled):
+		// PIX pago depois que a reserva expirou ou o pedido foi cancelado:
+		// o estoque já voltou, devolvemos o dinheiro
+		log.Printf("PIX %s pago com o pedido %s já %s; estornando", event.PaymentID, order.ID.Hex(), order.Status)
+		if err := s.refundOnce(ctx, event.PaymentID, order.Total); err != nil {
+			return false, err
+		}
+	default:
+		err := s.transitionOrder(ctx, order, target, "webhook:"+event.ID)
+		if err != nil && !errors.Is(err, repository.ErrOrderStatusChanged) {
+			return false, err
+		}
+	}
+
+	event.ProcessedAt = time.Now()
+	return false, s.Repo.SavePaymentEvent(ctx, event)
+}
+
+// refundOnce só estorna se a cobrança ainda não foi estornada. O evento é
+// gravado depois do estorno; se a gravação falhar, o provedor reentrega e o
+// dinheiro não pode ser devolvido duas vezes.
+func (s *StoreService) refundOnce(ctx context.Context, paymentID string, amount int64) error {
+	status, err := s.Payment.QueryStatus(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+	if status == PaymentStatusRefunded {
+		return nil
+	}
+	return s.Payment.Refund(ctx, paymentID, amount)
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// flakyEvents falha a primeira gravação de evento, como um timeout do banco
+type flakyEvents struct {
+	repository.StoreRepository
+	failed bool
+}
+
+func (r *flakyEvents) SavePaymentEvent(ctx context.Context, event models.PaymentEvent) error {
+	if !r.failed {
+		r.failed = true
+		return errors.New("timeout ao gravar o evento")
+	}
+	return r.StoreRepository.SavePaymentEvent(ctx, event)
+}
+
+// countingRefunds conta os estornos pedidos ao provedor
+type countingRefunds struct {
+	*FakePaymentGateway
+	refunds int
+}
+
+func (g *countingRefunds) Refund(ctx context.Context, paymentID string, amount int64) error {
+	g.refunds++
+	return g.FakePaymentGateway.Refund(ctx, paymentID, amount)
+}
+
+// PIX pago depois de expirar (ou de o pedido ser cancelado) é estornado uma
+// vez só, mesmo que o evento não seja gravado e o provedor reentregue
+func TestPaidAfterExpiryRefundsOnce(t *testing.T) {
+	for _, status := range []models.OrderStatus{models.OrderStatusExpired, models.OrderStatusCancelled} {
+		t.Run(string(status), func(t *testing.T) { testLatePixPaymentRefundsOnce(t, status) })
+	}
+}
+
+func testLatePixPaymentRefundsOnce(t *testing.T, status models.OrderStatus) {
+	ctx := context.Background()
+	mem := repository.NewMemoryStore()
+	gateway := &countingRefunds{FakePaymentGateway: NewFakePaymentGateway(PixConfig{Key: "loja@exemplo.com", MerchantName: "Loja", MerchantCity: "Sao Paulo"})}
+	s := NewStoreService(&flakyEvents{StoreRepository: mem}, gateway)
+
+	charge, err := gateway.CreatePixCharge(ctx, PixChargeRequest{TxID: "pedido1", Amount: 4500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gateway.MarkPixPaid(charge.ID); err != nil {
+		t.Fatal(err)
+	}
+	order := models.Order{
+		ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Status: status,
+		PaymentMethod: models.PaymentMethodPix, PaymentID: charge.ID, Total: 4500, CreatedAt: time.Now(),
+	}
+	if err := mem.CreateOrder(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+
+	event := models.PaymentEvent{ID: "evt_1", Type: PaymentEventPaid, PaymentID: charge.ID}
+	if _, err := s.HandlePaymentEvent(ctx, event); err == nil {
+		t.Fatal("a primeira entrega deveria falhar ao gravar o evento")
+	}
+	if dup, err := s.HandlePaymentEvent(ctx, event); err != nil || dup {
+		t.Fatalf("reentrega: duplicate=%v err=%v", dup, err)
+	}
+	if dup, err := s.HandlePaymentEvent(ctx, event); err != nil || !dup {
+		t.Fatalf("terceira entrega deveria ser ignorada: duplicate=%v err=%v", dup, err)
+	}
+
+	if gateway.refunds != 1 {
+		t.Errorf("estornos pedidos = %d, quer 1", gateway.refunds)
+	}
+	if st, _ := gateway.QueryStatus(ctx, charge.ID); st != PaymentStatusRefunded {
+		t.Errorf("cobrança ficou %s", st)
+	}
+	if got, _ := mem.GetOrderByID(ctx, order.ID); got.Status != status {
+		t.Errorf("pedido mudou para %s", got.Status)
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	const secret = "segredo-do-webhook"
+	now := time.Date(2026, time.March, 15, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"id":"evt_1","type":"payment.paid","payment_id":"pay_1"}`)
+
+	cases := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		valid  bool
+	}{
+		{"assinatura válida", secret, SignWebhook(secret, body, now), body, true},
+		{"dentro da tolerância", secret, SignWebhook(secret, body, now.Add(-WebhookTolerance)), body, true},
+		{"corpo adulterado", secret, SignWebhook(secret, body, now), []byte(`{"id":"evt_1","type":"payment.paid","payment_id":"pay_2"}`), false},
+		{"segredo errado", secret, SignWebhook("outro", body, now), body, false},
+		{"timestamp antigo", secret, SignWebhook(secret, body, now.Add(-WebhookTolerance-time.Second)), body, false},
+		{"timestamp no futuro", secret, SignWebhook(secret, body, now.Add(WebhookTolerance+time.Second)), body, false},
+		{"sem cabeçalho", secret, "", body, false},
+		{"sem v1", secret, "t=" + strconv.FormatInt(now.Unix(), 10), body, false},
+		{"timestamp inválido", secret, "t=abc,v1=00", body, false},
+		{"segredo não configurado", "", SignWebhook("", body, now), body, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := VerifyWebhookSignature(tc.secret, tc.header, tc.body, now)
+			if tc.valid && err != nil {
+				t.Errorf("deveria aceitar: %v", err)
+			}
+			if !tc.valid && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("erro = %v, quer ErrInvalidSignature", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// PixConfig identifica o recebedor no BR Code (dados do DICT)
+type PixConfig struct {
+	Key          string // Chave PIX (CPF/CNPJ, e-mail, telefone ou aleatória)
+	MerchantName string // Até 25 caracteres
+	MerchantCity string // Até 15 caracteres
+}
+
+// IDs dos campos EMV-MPM usados pelo BR Code (Manual de Padrões para Iniciação do Pix)
+const (
+	pixPayloadFormat     = "00"
+	pixInitiationMethod  = "01"
+	pixMerchantAccount   = "26"
+	pixMerchantCategory  = "52"
+	pixCurrency          = "53"
+	pixAmount            = "54"
+	pixCountryCode       = "58"
+	pixMerchantName      = "59"
+	pixMerchantCity      = "60"
+	pixAdditionalData    = "62"
+	pixCRC16             = "63"
+	pixGUI               = "00"
+	pixKey               = "01"
+	pixAdditionalTxID    = "05"
+	pixGUIValue          = "br.gov.bcb.pix"
+	pixCurrencyBRL       = "986"
+	pixMaxTxIDLength     = 25
+	pixMaxNameLength     = 25
+	pixMaxCityLength     = 15
+	pixSingleUseInitCode = "12"
+)
+
+// BuildPixPayload monta o "Copia e Cola" do PIX para um valor (em centavos) e txid.
+// O txid aceita só [A-Za-z0-9], até 25 caracteres; o resto é descartado.
+func BuildPixPayload(cfg PixConfig, amount int64, txid string) (string, error) {
+	if cfg.Key == "" {
+		return "", fmt.Errorf("chave PIX não configurada")
+	}
+
+	txid = sanitizePixTxID(txid)
+	if txid == "" {
+		txid = "***"
+	}
+
+	var b strings.Builder
+	b.WriteString(emvField(pixPayloadFormat, "01"))
+	b.WriteString(emvField(pixInitiationMethod, pixSingleUseInitCode))
+	b.WriteString(emvField(pixMerchantAccount,
+		emvField(pixGUI, pixGUIValue)+emvField(pixKey, cfg.Key)))
+	b.WriteString(emvField(pixMerchantCategory, "0000"))
+	b.WriteString(emvField(pixCurrency, pixCurrencyBRL))
+	if amount > 0 {
+		b.WriteString(emvField(pixAmount, fmt.Sprintf("%d.%02d", amount/100, amount%100)))
+	}
+	b.WriteString(emvField(pixCountryCode, "BR"))
+	b.WriteString(emvField(pixMerchantName, pixText(cfg.MerchantName, pixMaxNameLength)))
+	b.WriteString(emvField(pixMerchantCity, pixText(cfg.MerchantCity, pixMaxCityLength)))
+	b.WriteString(emvField(pixAdditionalData, emvField(pixAdditionalTxID, txid)))
+
+	// O CRC cobre tudo, inclusive o próprio ID + tamanho do campo 63
+	b.WriteString(pixCRC16 + "04")
+	b.WriteString(fmt.Sprintf("%04X", crc16CCITT([]byte(b.String()))))
+
+	return b.String(), nil
+}
+
+// emvField formata um campo ID + tamanho (2 dígitos) + valor
+func emvField(id, value string) string {
+	return fmt.Sprintf("%s%02d%s", id, len(value), value)
+}
+
+// crc16CCITT: polinômio 0x1021, valor inicial 0xFFFF (CRC-16/CCITT-FALSE)
+func crc16CCITT(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// pixText remove acentos e caracteres fora do ASCII e corta no tamanho máximo
+func pixText(s string, max int) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	clean, _, _ := transform.String(t, s)
+
+	var b strings.Builder
+	for _, r := range clean {
+		if r < 128 && unicode.IsPrint(r) {
+			b.WriteRune(r)
+		}
+	}
+
+	out := strings.TrimSpace(b.String())
+	if len(out) > max {
+		out = out[:max]
+	}
+	return out
+}
+
+func sanitizePixTxID(txid string) string {
+	var b strings.Builder
+	for _, r := range txid {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	out := b.String()
+	if len(out) > pixMaxTxIDLength {
+		out = out[:pixMaxTxIDLength]
+	}
+	return out
+}
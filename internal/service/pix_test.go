@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Exemplo do Manual de Padrões para Iniciação do Pix (Banco Central)
+const bcbExampleBRCode = "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-426655440000" +
+	"5204000053039865802BR5913Fulano de Tal6008BRASILIA62070503***63041D3D"
+
+func TestCRC16CCITT(t *testing.T) {
+	// Valor de verificação do CRC-16/CCITT-FALSE
+	if got := crc16CCITT([]byte("123456789")); got != 0x29B1 {
+		t.Errorf("crc(123456789) = %04X, quer 29B1", got)
+	}
+
+	body, crc := bcbExampleBRCode[:len(bcbExampleBRCode)-4], bcbExampleBRCode[len(bcbExampleBRCode)-4:]
+	if got := fmt.Sprintf("%04X", crc16CCITT([]byte(body))); got != crc {
+		t.Errorf("CRC do BR Code do manual = %s, quer %s", got, crc)
+	}
+}
+
+func TestBuildPixPayload(t *testing.T) {
+	cfg := PixConfig{Key: "123e4567-e12b-12d1-a456-426655440000", MerchantName: "Fulano de Tal", MerchantCity: "BRASILIA"}
+
+	payload, err := BuildPixPayload(cfg, 1050, "pedido-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "000201" + "010212" +
+		"26580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-426655440000" +
+		"52040000" + "5303986" + "540510.50" + "5802BR" +
+		"5913Fulano de Tal" + "6008BRASILIA" + "62120508pedido42" + "6304"
+	if !strings.HasPrefix(payload, want) || len(payload) != len(want)+4 {
+		t.Fatalf("payload =\n%s\nquer\n%s????", payload, want)
+	}
+	if crc := fmt.Sprintf("%04X", crc16CCITT([]byte(want))); payload[len(want):] != crc {
+		t.Errorf("CRC = %s, quer %s", payload[len(want):], crc)
+	}
+
+	// Sem valor e sem txid: campo 54 some e o txid vira ***
+	payload, err = BuildPixPayload(cfg, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(payload, "5405") || !strings.Contains(payload, "62070503***") {
+		t.Errorf("payload sem valor = %s", payload)
+	}
+
+	// Acentos saem e o nome é cortado em 25 caracteres
+	payload, _ = BuildPixPayload(PixConfig{Key: "k", MerchantName: "Olecram Comércio de Roupas Ltda", MerchantCity: "São Paulo"}, 100, "x")
+	if !strings.Contains(payload, "5925Olecram Comercio de Roupa") || !strings.Contains(payload, "6009Sao Paulo") {
+		t.Errorf("nome/cidade não normalizados: %s", payload)
+	}
+
+	if _, err := BuildPixPayload(PixConfig{}, 100, "x"); err == nil {
+		t.Error("sem chave deveria falhar")
+	}
+}
@@ -0,0 +1,444 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidPromotion    = errors.New("promoção inválida")
+	ErrPromotionNotFound   = errors.New("promoção não encontrada")
+	ErrCouponNotFound      = errors.New("cupom não encontrado")
+	ErrCouponNotApplicable = errors.New("cupom não vale para este pedido")
+)
+
+// PromotionLine é um item do pedido com as categorias do produto
+type PromotionLine struct {
+	Item        models.OrderItem
+	CategoryIDs []primitive.ObjectID
+}
+
+// Pricing é o pedido com as promoções aplicadas (valores em centavos).
+// Total = Subtotal + Shipping - descontos.
+type Pricing struct {
+	Subtotal     int64
+	Shipping     int64
+	Discounts    []models.OrderDiscount
+	FreeShipping bool
+	Total        int64
+	Coupon       string                // <--- código do cupom aplicado ("" = nenhum)
+	Delivery     *models.ShippingQuote // <--- entrega escolhida (nil = ainda não cotada)
+}
+
+func (p Pricing) FormattedSubtotal() string {
+	return fmt.Sprintf("R$ %.2f", float64(p.Subtotal)/100)
+}
+
+func (p Pricing) DiscountTotal() int64 {
+	var total int64
+	for _, d := range p.Discounts {
+		total += d.Amount
+	}
+	return total
+}
+
+// ApplyPromotions calcula os descontos de um pedido. As promoções já vêm
+// filtradas (ativas, na validade, dentro dos limites de uso) e com
+// CategoryIDs expandidas para as subcategorias; shipping é o valor do frete.
+//
+// Regra de combinação: as combináveis (Stackable) somam entre si; uma não
+// combinável vale sozinha. Fica a opção de maior desconto.
+func ApplyPromotions(lines []PromotionLine, promotions []models.Promotion, shipping int64) Pricing {
+	pricing := Pricing{Shipping: shipping}
+	for _, l := range lines {
+		pricing.Subtotal += l.Item.Price * int64(l.Item.Quantity)
+	}
+
+	var stacked []models.OrderDiscount
+	var stackedTotal int64
+	var best []models.OrderDiscount
+	var bestTotal int64
+	for _, p := range promotions {
+		d, ok := promotionDiscount(p, lines, pricing.Subtotal, shipping)
+		if !ok {
+			continue
+		}
+		if p.Stackable {
+			// Frete grátis só se desconta uma vez
+			if d.FreeShipping && slices.ContainsFunc(stacked, func(s models.OrderDiscount) bool { return s.FreeShipping }) {
+				continue
+			}
+			stacked = append(stacked, d)
+			stackedTotal += d.Amount
+			continue
+		}
+		if best == nil || d.Amount > bestTotal {
+			best, bestTotal = []models.OrderDiscount{d}, d.Amount
+		}
+	}
+	if stacked != nil && (best == nil || stackedTotal >= bestTotal) {
+		best = stacked
+	}
+
+	// Os descontos nos itens não passam do subtotal (nem o frete grátis do frete)
+	remaining := pricing.Subtotal
+	for _, d := range best {
+		if d.FreeShipping {
+			pricing.FreeShipping = true
+		} else {
+			d.Amount = min(d.Amount, remaining)
+			remaining -= d.Amount
+		}
+		pricing.Discounts = append(pricing.Discounts, d)
+		if d.Code != "" {
+			pricing.Coupon = d.Code
+		}
+	}
+
+	pricing.Total = pricing.Subtotal + shipping - pricing.DiscountTotal()
+	return pricing
+}
+
+// promotionDiscount calcula o desconto de uma promoção sozinha; ok = false
+// se ela não se aplica a estes itens
+func promotionDiscount(p models.Promotion, lines []PromotionLine, subtotal, shipping int64) (models.OrderDiscount, bool) {
+	if subtotal < p.MinSubtotal {
+		return models.OrderDiscount{}, false
+	}
+
+	// Itens que participam (todos, se a promoção não tem categorias)
+	var eligible []models.OrderItem
+	var eligibleSubtotal int64
+	for _, l := range lines {
+		if len(p.CategoryIDs) > 0 && !slices.ContainsFunc(l.CategoryIDs, func(id primitive.ObjectID) bool {
+			return slices.Contains(p.CategoryIDs, id)
+		}) {
+			continue
+		}
+		eligible = append(eligible, l.Item)
+		eligibleSubtotal += l.Item.Price * int64(l.Item.Quantity)
+	}
+	if len(eligible) == 0 {
+		return models.OrderDiscount{}, false
+	}
+
+	d := models.OrderDiscount{PromotionID: p.ID, Name: p.Name, Code: p.Code, Kind: p.Kind}
+	switch p.Kind {
+	case models.PromotionPercentage:
+		d.Amount = eligibleSubtotal * int64(p.Percent) / 100
+	case models.PromotionFixedAmount:
+		d.Amount = min(p.Amount, eligibleSubtotal)
+	case models.PromotionFreeShipping:
+		d.Amount = shipping
+		d.FreeShipping = true
+		return d, true // <--- vale mesmo antes de o frete ser calculado
+	case models.PromotionBuyXGetY:
+		d.Amount = buyXGetYDiscount(eligible, p.BuyQuantity, p.GetQuantity)
+	case models.PromotionSpendTiers:
+		tier, ok := reachedTier(p.Tiers, eligibleSubtotal)
+		if !ok {
+			return models.OrderDiscount{}, false
+		}
+		d.Amount = min(tier.Amount+eligibleSubtotal*int64(tier.Percent)/100, eligibleSubtotal)
+	}
+	return d, d.Amount > 0
+}
+
+// buyXGetYDiscount: as unidades vão da mais cara para a mais barata; em cada
+// grupo de buy+get, as get últimas (as mais baratas do grupo) saem de graça
+func buyXGetYDiscount(items []models.OrderItem, buy, get int) int64 {
+	if buy <= 0 || get <= 0 {
+		return 0
+	}
+	var units []int64
+	for _, item := range items {
+		for range item.Quantity {
+			units = append(units, item.Price)
+		}
+	}
+	slices.SortFunc(units, func(a, b int64) int { return cmp.Compare(b, a) })
+
+	group := buy + get
+	var discount int64
+	for i := 0; i+group <= len(units); i += group {
+		for _, price := range units[i+buy : i+group] {
+			discount += price
+		}
+	}
+	return discount
+}
+
+// reachedTier devolve a maior faixa atingida
+func reachedTier(tiers []models.PromotionTier, subtotal int64) (models.PromotionTier, bool) {
+	var best models.PromotionTier
+	found := false
+	for _, t := range tiers {
+		if subtotal >= t.MinSubtotal && (!found || t.MinSubtotal > best.MinSubtotal) {
+			best, found = t, true
+		}
+	}
+	return best, found
+}
+
+// NormalizeCouponCode: os códigos são guardados e comparados em maiúsculas
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// PriceOrder aplica as promoções automáticas e o cupom (opcional) aos itens
+// e soma o frete da entrega escolhida (nil = sem frete).
+// Cupom que não existe, expirou, esgotou ou perde para uma promoção melhor
+// devolve ErrCouponNotFound/ErrCouponNotApplicable com o motivo.
+func (s *StoreService) PriceOrder(ctx context.Context, userIDStr string, items []models.OrderItem, couponCode string, delivery *models.ShippingQuote) (*Pricing, error) {
+	userID, _ := primitive.ObjectIDFromHex(userIDStr)
+	now := time.Now()
+	var shipping int64
+	if delivery != nil {
+		shipping = delivery.Cost
+	}
+
+	all, err := s.Repo.GetAllPromotions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []models.Promotion
+	for _, p := range all {
+		if p.Code != "" {
+			continue
+		}
+		ok, err := s.promotionAvailable(ctx, p, userID, now)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			candidates = append(candidates, p)
+		}
+	}
+
+	var coupon *models.Promotion
+	if code := NormalizeCouponCode(couponCode); code != "" {
+		coupon, err = s.Repo.GetPromotionByCode(ctx, code)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !coupon.ActiveAt(now) {
+			return nil, fmt.Errorf("%w: fora da validade", ErrCouponNotApplicable)
+		}
+		ok, err := s.promotionAvailable(ctx, *coupon, userID, now)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: limite de uso atingido", ErrCouponNotApplicable)
+		}
+		candidates = append(candidates, *coupon)
+	}
+
+	lines, err := s.promotionLines(ctx, items, candidates)
+	if err != nil {
+		return nil, err
+	}
+	// As categorias das promoções passam a incluir as subcategorias
+	if slices.ContainsFunc(candidates, func(p models.Promotion) bool { return len(p.CategoryIDs) > 0 }) {
+		tree, err := s.loadCategoryTree(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i, p := range candidates {
+			var ids []primitive.ObjectID
+			for _, id := range p.CategoryIDs {
+				ids = append(ids, tree.descendants(id)...)
+			}
+			candidates[i].CategoryIDs = ids
+		}
+	}
+
+	pricing := ApplyPromotions(lines, candidates, shipping)
+	pricing.Delivery = delivery
+
+	if coupon != nil && pricing.Coupon == "" {
+		c := candidates[len(candidates)-1] // <--- o cupom, já com as subcategorias
+		_, applies := promotionDiscount(c, lines, pricing.Subtotal, shipping)
+		switch {
+		case pricing.Subtotal < c.MinSubtotal:
+			return nil, fmt.Errorf("%w: pedido mínimo de R$ %.2f", ErrCouponNotApplicable, float64(c.MinSubtotal)/100)
+		case !applies:
+			return nil, fmt.Errorf("%w: nenhum item do carrinho participa", ErrCouponNotApplicable)
+		default:
+			return nil, fmt.Errorf("%w: não combina com uma promoção que já dá mais desconto", ErrCouponNotApplicable)
+		}
+	}
+	return &pricing, nil
+}
+
+// promotionAvailable: ativa, na validade e dentro dos limites de uso
+// (a conferência definitiva é o RedeemPromotion, dentro da transação)
+func (s *StoreService) promotionAvailable(ctx context.Context, p models.Promotion, userID primitive.ObjectID, now time.Time) (bool, error) {
+	if !p.ActiveAt(now) || (p.MaxUses > 0 && p.Uses >= p.MaxUses) {
+		return false, nil
+	}
+	if p.MaxUsesPerCustomer > 0 {
+		n, err := s.Repo.CountPromotionRedemptions(ctx, p.ID, userID)
+		if err != nil {
+			return false, err
+		}
+		return n < p.MaxUsesPerCustomer, nil
+	}
+	return true, nil
+}
+
+// promotionLines junta as categorias dos produtos, só se alguma promoção usa
+func (s *StoreService) promotionLines(ctx context.Context, items []models.OrderItem, promotions []models.Promotion) ([]PromotionLine, error) {
+	lines := make([]PromotionLine, len(items))
+	for i, item := range items {
+		lines[i].Item = item
+	}
+	if !slices.ContainsFunc(promotions, func(p models.Promotion) bool { return len(p.CategoryIDs) > 0 }) {
+		return lines, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+	}
+	products, err := s.Repo.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range lines {
+		if j := slices.IndexFunc(products, func(p models.Product) bool { return p.ID == lines[i].Item.ProductID }); j >= 0 {
+			lines[i].CategoryIDs = products[j].CategoryIDs
+		}
+	}
+	return lines, nil
+}
+
+// redeemPromotions registra os usos das promoções do pedido (na transação do checkout)
+func (s *StoreService) redeemPromotions(ctx context.Context, order models.Order) error {
+	for _, d := range order.Discounts {
+		err := s.Repo.RedeemPromotion(ctx, models.PromotionRedemption{
+			PromotionID: d.PromotionID,
+			UserID:      order.UserID,
+			OrderID:     order.ID,
+			CreatedAt:   order.CreatedAt,
+		})
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%s: %w", d.Name, ErrPromotionNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", d.Name, err)
+		}
+	}
+	return nil
+}
+
+// --- ADMIN ---
+
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+func (s *StoreService) GetPromotions(ctx context.Context) ([]models.Promotion, error) {
+	return s.Repo.GetAllPromotions(ctx)
+}
+
+// CreatePromotion valida e grava um cupom (com Code) ou promoção automática
+func (s *StoreService) CreatePromotion(ctx context.Context, p models.Promotion) error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Code = NormalizeCouponCode(p.Code)
+	if err := validatePromotion(p); err != nil {
+		return err
+	}
+
+	if len(p.CategoryIDs) > 0 {
+		tree, err := s.loadCategoryTree(ctx)
+		if err != nil {
+			return err
+		}
+		for _, id := range p.CategoryIDs {
+			if _, ok := tree.byID[id]; !ok {
+				return ErrCategoryNotFound
+			}
+		}
+	}
+
+	p.ID = primitive.NewObjectID()
+	p.Uses = 0
+	p.CreatedAt = time.Now()
+	err := s.Repo.CreatePromotion(ctx, p)
+	if errors.Is(err, repository.ErrDuplicateCode) {
+		return fmt.Errorf("%w: o código %s já existe", ErrInvalidPromotion, p.Code)
+	}
+	return err
+}
+
+func validatePromotion(p models.Promotion) error {
+	invalid := func(reason string) error { return fmt.Errorf("%w: %s", ErrInvalidPromotion, reason) }
+
+	if p.Name == "" {
+		return invalid("informe o nome")
+	}
+	if p.Code != "" && !couponCodePattern.MatchString(p.Code) {
+		return invalid("o código deve ter de 3 a 32 letras, números, - ou _")
+	}
+	switch p.Kind {
+	case models.PromotionPercentage:
+		if p.Percent < 1 || p.Percent > 100 {
+			return invalid("o percentual deve ser de 1 a 100")
+		}
+	case models.PromotionFixedAmount:
+		if p.Amount <= 0 {
+			return invalid("informe o valor do desconto")
+		}
+	case models.PromotionFreeShipping:
+	case models.PromotionBuyXGetY:
+		if p.BuyQuantity < 1 || p.GetQuantity < 1 {
+			return invalid("informe quantas unidades leva e quantas ganha")
+		}
+	case models.PromotionSpendTiers:
+		if len(p.Tiers) == 0 {
+			return invalid("informe ao menos uma faixa")
+		}
+		for _, t := range p.Tiers {
+			if t.MinSubtotal <= 0 || (t.Percent > 0) == (t.Amount > 0) || t.Percent < 0 || t.Percent > 100 || t.Amount < 0 {
+				return invalid("cada faixa precisa de um valor mínimo e de um percentual (1 a 100) ou valor fixo")
+			}
+		}
+	default:
+		return invalid("tipo desconhecido")
+	}
+	if p.MinSubtotal < 0 || p.MaxUses < 0 || p.MaxUsesPerCustomer < 0 {
+		return invalid("valores negativos")
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return invalid("o fim deve ser depois do início")
+	}
+	return nil
+}
+
+func (s *StoreService) SetPromotionActive(ctx context.Context, idStr string, active bool) error {
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return ErrPromotionNotFound
+	}
+	return s.Repo.SetPromotionActive(ctx, id, active)
+}
+
+func (s *StoreService) DeletePromotion(ctx context.Context, idStr string) error {
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return ErrPromotionNotFound
+	}
+	return s.Repo.DeletePromotion(ctx, id)
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func promoLine(price int64, quantity int, categories ...primitive.ObjectID) PromotionLine {
+	return PromotionLine{
+		Item:        models.OrderItem{ProductID: primitive.NewObjectID(), Price: price, Quantity: quantity},
+		CategoryIDs: categories,
+	}
+}
+
+func TestApplyPromotions(t *testing.T) {
+	shoes := primitive.NewObjectID()
+
+	percent := func(name string, pct int, stackable bool) models.Promotion {
+		return models.Promotion{Name: name, Kind: models.PromotionPercentage, Percent: pct, Stackable: stackable}
+	}
+	fixed := func(name string, amount int64, stackable bool) models.Promotion {
+		return models.Promotion{Name: name, Kind: models.PromotionFixedAmount, Amount: amount, Stackable: stackable}
+	}
+	freeShipping := func(name string, stackable bool) models.Promotion {
+		return models.Promotion{Name: name, Kind: models.PromotionFreeShipping, Stackable: stackable}
+	}
+
+	cases := []struct {
+		name       string
+		lines      []PromotionLine
+		promotions []models.Promotion
+		shipping   int64
+
+		wantNames []string
+		wantTotal int64
+		wantFree  bool
+	}{
+		{
+			name:      "sem promoção",
+			lines:     []PromotionLine{promoLine(10000, 1)},
+			shipping:  1500,
+			wantTotal: 11500,
+		},
+		{
+			name:       "combináveis somam",
+			lines:      []PromotionLine{promoLine(10000, 1)},
+			promotions: []models.Promotion{percent("10%", 10, true), fixed("R$ 5", 500, true)},
+			wantNames:  []string{"10%", "R$ 5"},
+			wantTotal:  8500,
+		},
+		{
+			name:       "não combinável maior vence a soma",
+			lines:      []PromotionLine{promoLine(10000, 1)},
+			promotions: []models.Promotion{percent("10%", 10, true), fixed("R$ 5", 500, true), percent("30%", 30, false)},
+			wantNames:  []string{"30%"},
+			wantTotal:  7000,
+		},
+		{
+			name:       "soma das combináveis vence a não combinável",
+			lines:      []PromotionLine{promoLine(10000, 1)},
+			promotions: []models.Promotion{percent("20%", 20, true), fixed("R$ 15", 1500, true), percent("30%", 30, false)},
+			wantNames:  []string{"20%", "R$ 15"},
+			wantTotal:  6500,
+		},
+		{
+			name:       "duas não combináveis: só a melhor",
+			lines:      []PromotionLine{promoLine(10000, 1)},
+			promotions: []models.Promotion{fixed("R$ 25", 2500, false), percent("15%", 15, false)},
+			wantNames:  []string{"R$ 25"},
+			wantTotal:  7500,
+		},
+		{
+			name:       "fixo maior que o pedido zera os itens, não o frete",
+			lines:      []PromotionLine{promoLine(3000, 1)},
+			promotions: []models.Promotion{fixed("R$ 50", 5000, false)},
+			shipping:   1000,
+			wantNames:  []string{"R$ 50"},
+			wantTotal:  1000,
+		},
+		{
+			name:       "percentual + fixo combinados param em zero",
+			lines:      []PromotionLine{promoLine(10000, 1)},
+			promotions: []models.Promotion{percent("80%", 80, true), fixed("R$ 50", 5000, true)},
+			wantNames:  []string{"80%", "R$ 50"},
+			wantTotal:  0,
+		},
+		{
+			name:       "frete grátis combinável só uma vez",
+			lines:      []PromotionLine{promoLine(10000, 1)},
+			promotions: []models.Promotion{freeShipping("FG1", true), freeShipping("FG2", true), percent("10%", 10, true)},
+			shipping:   2000,
+			wantNames:  []string{"FG1", "10%"},
+			wantTotal:  9000,
+			wantFree:   true,
+		},
+		{
+			name:  "faixas: vale a maior atingida",
+			lines: []PromotionLine{promoLine(25000, 1)},
+			promotions: []models.Promotion{{Name: "Faixas", Kind: models.PromotionSpendTiers, Tiers: []models.PromotionTier{
+				{MinSubtotal: 10000, Percent: 5}, {MinSubtotal: 20000, Percent: 10}, {MinSubtotal: 30000, Amount: 5000},
+			}}},
+			wantNames: []string{"Faixas"},
+			wantTotal: 22500,
+		},
+		{
+			name:       "leve 3 pague 2: a mais barata sai de graça",
+			lines:      []PromotionLine{promoLine(3000, 2), promoLine(1000, 1)},
+			promotions: []models.Promotion{{Name: "3x2", Kind: models.PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1}},
+			wantNames:  []string{"3x2"},
+			wantTotal:  6000,
+		},
+		{
+			name:       "pedido mínimo não atingido",
+			lines:      []PromotionLine{promoLine(5000, 1)},
+			promotions: []models.Promotion{{Name: "10% acima de 100", Kind: models.PromotionPercentage, Percent: 10, MinSubtotal: 10000}},
+			wantTotal:  5000,
+		},
+		{
+			name:       "só os itens da categoria",
+			lines:      []PromotionLine{promoLine(10000, 1, shoes), promoLine(5000, 1)},
+			promotions: []models.Promotion{{Name: "Calçados 20%", Kind: models.PromotionPercentage, Percent: 20, CategoryIDs: []primitive.ObjectID{shoes}}},
+			wantNames:  []string{"Calçados 20%"},
+			wantTotal:  13000,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pricing := ApplyPromotions(tc.lines, tc.promotions, tc.shipping)
+
+			var names []string
+			for _, d := range pricing.Discounts {
+				names = append(names, d.Name)
+			}
+			if !slices.Equal(names, tc.wantNames) {
+				t.Errorf("descontos = %v, quer %v", names, tc.wantNames)
+			}
+			if pricing.Total != tc.wantTotal {
+				t.Errorf("total = %d, quer %d (%+v)", pricing.Total, tc.wantTotal, pricing)
+			}
+			if pricing.FreeShipping != tc.wantFree {
+				t.Errorf("frete grátis = %v", pricing.FreeShipping)
+			}
+			if pricing.Total < 0 || pricing.DiscountTotal() > pricing.Subtotal+pricing.Shipping {
+				t.Errorf("desconto passou do pedido: %+v", pricing)
+			}
+		})
+	}
+}
+
+// Promoções fora da validade, desligadas ou esgotadas não entram no preço;
+// como cupom, devolvem ErrCouponNotApplicable
+func TestPriceOrderAvailability(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	customer := primitive.NewObjectID()
+
+	cases := []struct {
+		name      string
+		promotion models.Promotion
+		// redeemedBy: usos já registrados (um por cliente da lista)
+		redeemedBy []primitive.ObjectID
+		applies    bool
+	}{
+		{name: "ativa", promotion: models.Promotion{Active: true}, applies: true},
+		{name: "desligada", promotion: models.Promotion{Active: false}},
+		{name: "expirada", promotion: models.Promotion{Active: true, EndsAt: &past}},
+		{name: "ainda não começou", promotion: models.Promotion{Active: true, StartsAt: &future}},
+		{name: "dentro da validade", promotion: models.Promotion{Active: true, StartsAt: &past, EndsAt: &future}, applies: true},
+		{
+			name:       "limite total atingido",
+			promotion:  models.Promotion{Active: true, MaxUses: 1},
+			redeemedBy: []primitive.ObjectID{primitive.NewObjectID()},
+		},
+		{
+			name:       "limite por cliente atingido",
+			promotion:  models.Promotion{Active: true, MaxUsesPerCustomer: 1},
+			redeemedBy: []primitive.ObjectID{customer},
+		},
+		{
+			name:       "limite por cliente é de cada cliente",
+			promotion:  models.Promotion{Active: true, MaxUsesPerCustomer: 1},
+			redeemedBy: []primitive.ObjectID{primitive.NewObjectID()},
+			applies:    true,
+		},
+	}
+
+	items := []models.OrderItem{{ProductID: primitive.NewObjectID(), Price: 10000, Quantity: 1}}
+	for _, tc := range cases {
+		for _, code := range []string{"", "CUPOM10"} {
+			name := tc.name + " (automática)"
+			if code != "" {
+				name = tc.name + " (cupom)"
+			}
+			t.Run(name, func(t *testing.T) {
+				ctx := context.Background()
+				repo := repository.NewMemoryStore()
+				s := NewStoreService(repo, NewFakePaymentGateway(PixConfig{}))
+
+				p := tc.promotion
+				p.ID = primitive.NewObjectID()
+				p.Name = "10%"
+				p.Code = code
+				p.Kind = models.PromotionPercentage
+				p.Percent = 10
+				if err := repo.CreatePromotion(ctx, p); err != nil {
+					t.Fatal(err)
+				}
+				for _, userID := range tc.redeemedBy {
+					err := repo.RedeemPromotion(ctx, models.PromotionRedemption{
+						PromotionID: p.ID, UserID: userID, OrderID: primitive.NewObjectID(), CreatedAt: time.Now(),
+					})
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				pricing, err := s.PriceOrder(ctx, customer.Hex(), items, code, nil)
+				switch {
+				case code != "" && !tc.applies:
+					if !errors.Is(err, ErrCouponNotApplicable) {
+						t.Errorf("cupom: erro = %v, quer ErrCouponNotApplicable", err)
+					}
+				case err != nil:
+					t.Fatal(err)
+				case tc.applies && pricing.Total != 9000:
+					t.Errorf("deveria aplicar: total = %d", pricing.Total)
+				case !tc.applies && pricing.Total != 10000:
+					t.Errorf("não deveria aplicar: total = %d (%+v)", pricing.Total, pricing.Discounts)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+)
+
+// ExpireReservations marca como EXPIRADO os pedidos PIX não pagos dentro da
+// janela de reserva e devolve as unidades ao estoque. Retorna quantos expirou.
+func (s *StoreService) ExpireReservations(ctx context.Context) (int, error) {
+	orders, err := s.Repo.GetExpiredReservations(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, order := range orders {
+		// Se o pagamento chegou no meio do caminho, a transição falha e nada é devolvido
+		err := s.transitionOrder(ctx, &order, models.OrderStatusExpired, "sistema:reservas")
+		if errors.Is(err, repository.ErrOrderStatusChanged) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Erro ao expirar reserva do pedido %s: %v", order.ID.Hex(), err)
+			continue
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+// RunReservationWorker roda ExpireReservations a cada interval até o ctx ser cancelado
+func (s *StoreService) RunReservationWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.ExpireReservations(ctx)
+			if err != nil {
+				log.Printf("Worker de reservas: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("Worker de reservas: %d pedido(s) PIX expirado(s)", n)
+			}
+		}
+	}
+}
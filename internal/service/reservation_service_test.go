@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PIX vencido: pedido EXPIRADO, estoque e cupom de volta; rodar de novo não faz nada
+func TestExpireReservations(t *testing.T) {
+	ctx := context.Background()
+	mem := repository.NewMemoryStore()
+	s := NewStoreService(mem, NewFakePaymentGateway(PixConfig{}))
+
+	product := models.Product{ID: primitive.NewObjectID(), Name: "Camiseta", Price: 5000, Stock: 10, CreatedAt: time.Now()}
+	if err := mem.CreateProduct(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	promo := models.Promotion{ID: primitive.NewObjectID(), Name: "CUPOM10", Code: "CUPOM10", Kind: models.PromotionPercentage, Percent: 10, Active: true, MaxUsesPerCustomer: 1}
+	if err := mem.CreatePromotion(ctx, promo); err != nil {
+		t.Fatal(err)
+	}
+
+	customer := primitive.NewObjectID()
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	// Reserva como o checkout faz: baixa o estoque e registra o uso do cupom
+	reserve := func(until *time.Time) models.Order {
+		t.Helper()
+		order := models.Order{
+			ID: primitive.NewObjectID(), UserID: customer, CustomerEmail: "cliente@example.com",
+			Status: models.OrderStatusPending, PaymentMethod: models.PaymentMethodPix,
+			Items:     []models.OrderItem{{ProductID: product.ID, Quantity: 3, Price: product.Price}},
+			Discounts: []models.OrderDiscount{{PromotionID: promo.ID, Name: promo.Name, Code: promo.Code, Kind: promo.Kind, Amount: 1500}},
+			Total:     13500, CreatedAt: time.Now(), ReservedUntil: until,
+		}
+		if err := mem.DecrementStock(ctx, product.ID, "", 3); err != nil {
+			t.Fatal(err)
+		}
+		if err := mem.RedeemPromotion(ctx, models.PromotionRedemption{PromotionID: promo.ID, UserID: primitive.NewObjectID(), OrderID: order.ID, CreatedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		if err := mem.CreateOrder(ctx, order); err != nil {
+			t.Fatal(err)
+		}
+		return order
+	}
+	expired := reserve(&past)
+	valid := reserve(&future)
+
+	expectState := func(run string, stock, uses int) {
+		t.Helper()
+		if p, _ := mem.GetProductByID(ctx, product.ID); p.Stock != stock {
+			t.Errorf("%s: estoque = %d, quer %d", run, p.Stock, stock)
+		}
+		if p, _ := mem.GetPromotionByCode(ctx, promo.Code); p.Uses != uses {
+			t.Errorf("%s: usos do cupom = %d, quer %d", run, p.Uses, uses)
+		}
+	}
+	expectState("antes", 4, 2)
+
+	for i, want := range []int{1, 0} {
+		n, err := s.ExpireReservations(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Errorf("rodada %d: expirou %d, quer %d", i+1, n, want)
+		}
+		// Só as unidades e o uso do pedido vencido voltam, e uma vez só
+		expectState("depois", 7, 1)
+	}
+
+	got, _ := mem.GetOrderByID(ctx, expired.ID)
+	if got.Status != models.OrderStatusExpired || len(got.StatusHistory) != 1 || got.StatusHistory[0].ChangedBy != "sistema:reservas" {
+		t.Errorf("pedido vencido = %s %+v", got.Status, got.StatusHistory)
+	}
+	if got, _ := mem.GetOrderByID(ctx, valid.ID); got.Status != models.OrderStatusPending {
+		t.Errorf("pedido dentro da janela ficou %s", got.Status)
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+)
+
+// Resultados por página da busca
+const SearchPageSize = 24
+
+// Última página aceita: acima disso o skip estoura (e ninguém pagina tanto)
+const MaxSearchPage = 1000
+
+var ErrInvalidSearch = errors.New("busca inválida")
+
+// SearchQuery é a busca como o cliente pediu (página e API usam os mesmos
+// parâmetros de URL)
+type SearchQuery struct {
+	Text     string                `json:"q"`
+	MinPrice int64                 `json:"min_price,omitempty"` // centavos
+	MaxPrice int64                 `json:"max_price,omitempty"`
+	InStock  bool                  `json:"in_stock,omitempty"`
+	Size     string                `json:"size,omitempty"`
+	Category string                `json:"category,omitempty"` // slug; inclui as subcategorias
+	Sort     repository.SearchSort `json:"sort"`
+	Page     int                   `json:"page"`
+}
+
+type SearchResult struct {
+	Query    SearchQuery
+	Products []models.Product
+	Total    int
+	Pages    int
+	Terms    []string // <--- termos normalizados, para destacar
+}
+
+func (s *StoreService) SearchProducts(ctx context.Context, q SearchQuery) (*SearchResult, error) {
+	q.Text = strings.TrimSpace(q.Text)
+	if len(q.Text) > 200 {
+		return nil, fmt.Errorf("%w: texto muito longo", ErrInvalidSearch)
+	}
+	if q.MinPrice < 0 || q.MaxPrice < 0 || (q.MaxPrice > 0 && q.MinPrice > q.MaxPrice) {
+		return nil, fmt.Errorf("%w: faixa de preço", ErrInvalidSearch)
+	}
+	switch q.Sort {
+	case "":
+		q.Sort = repository.SortNewest
+		if q.Text != "" {
+			q.Sort = repository.SortRelevance
+		}
+	case repository.SortRelevance, repository.SortPriceAsc, repository.SortPriceDesc, repository.SortNewest:
+	default:
+		return nil, fmt.Errorf("%w: ordenação %q", ErrInvalidSearch, q.Sort)
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Page > MaxSearchPage {
+		return nil, fmt.Errorf("%w: página acima de %d", ErrInvalidSearch, MaxSearchPage)
+	}
+
+	search := repository.ProductSearch{
+		Text:        q.Text,
+		MinPrice:    q.MinPrice,
+		MaxPrice:    q.MaxPrice,
+		InStockOnly: q.InStock,
+		Size:        strings.TrimSpace(q.Size),
+		Sort:        q.Sort,
+		Skip:        (q.Page - 1) * SearchPageSize,
+		Limit:       SearchPageSize,
+	}
+
+	if q.Category != "" {
+		tree, err := s.loadCategoryTree(ctx)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, c := range tree.byID {
+			if c.Slug == q.Category {
+				search.CategoryIDs = tree.descendants(c.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, ErrCategoryNotFound
+		}
+	}
+
+	products, total, err := s.Repo.SearchProducts(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+
+	terms, _ := repository.SearchTerms(q.Text)
+	return &SearchResult{
+		Query:    q,
+		Products: products,
+		Total:    total,
+		Pages:    (total + SearchPageSize - 1) / SearchPageSize,
+		Terms:    terms,
+	}, nil
+}
+
+// Highlight escapa o texto para HTML e envolve em <mark> as palavras que
+// batem com algum termo (palavra inteira, sem acento/caixa, como a busca)
+func Highlight(text string, terms []string) string {
+	var b strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) {
+			j++
+		}
+		if j == i { // separador
+			b.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+
+		word := string(runes[i:j])
+		if matchesTerm(word, terms) {
+			b.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// Snippet recorta até limit caracteres em volta do primeiro termo encontrado
+// e aplica o Highlight
+func Snippet(text string, terms []string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return Highlight(text, terms)
+	}
+
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		if isWordRune(runes[i]) && (i == 0 || !isWordRune(runes[i-1])) {
+			j := i
+			for j < len(runes) && isWordRune(runes[j]) {
+				j++
+			}
+			if matchesTerm(string(runes[i:j]), terms) {
+				start = i
+				break
+			}
+		}
+	}
+	start = max0(start - limit/3)
+	end := min(start+limit, len(runes))
+	start = max0(end - limit)
+	// não corta palavra no meio
+	for start > 0 && start < end && isWordRune(runes[start-1]) {
+		start++
+	}
+	for end < len(runes) && end > start && isWordRune(runes[end]) {
+		end--
+	}
+
+	out := Highlight(string(runes[start:end]), terms)
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(runes) {
+		out += "…"
+	}
+	return out
+}
+
+func max0(n int) int {
+	return max(n, 0)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func matchesTerm(word string, terms []string) bool {
+	folded := repository.FoldText(word)
+	for _, t := range terms {
+		if folded == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+)
+
+func TestSearchProductsPage(t *testing.T) {
+	s := NewStoreService(repository.NewMemoryStore(), NewFakePaymentGateway(PixConfig{}))
+
+	for _, page := range []int{-5, 0, 1, MaxSearchPage} {
+		if _, err := s.SearchProducts(context.Background(), SearchQuery{Page: page}); err != nil {
+			t.Errorf("página %d: %v", page, err)
+		}
+	}
+	// (page-1)*SearchPageSize estouraria para um skip negativo
+	for _, page := range []int{MaxSearchPage + 1, math.MaxInt/SearchPageSize + 2, math.MaxInt} {
+		if _, err := s.SearchProducts(context.Background(), SearchQuery{Page: page}); !errors.Is(err, ErrInvalidSearch) {
+			t.Errorf("página %d: erro = %v, quer ErrInvalidSearch", page, err)
+		}
+	}
+}
+
+// Destaca pela mesma regra da busca: palavra inteira, sem acento nem caixa
+func TestHighlight(t *testing.T) {
+	terms, _ := repository.SearchTerms("camiseta algodao")
+	cases := map[string]string{
+		"Camiseta de Algodão": "<mark>Camiseta</mark> de <mark>Algodão</mark>",
+		"Camisetas e camisa":  "Camisetas e camisa",
+		"Cami<seta> algodão!": "Cami&lt;seta&gt; <mark>algodão</mark>!",
+	}
+	for in, want := range cases {
+		if got := Highlight(in, terms); got != want {
+			t.Errorf("Highlight(%q) = %q, quer %q", in, got, want)
+		}
+	}
+	if got := Highlight("camiseta", []string{"cam"}); got != "camiseta" {
+		t.Errorf("pedaço de palavra foi destacado: %q", got)
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrInvalidShippingMethod  = errors.New("forma de entrega inválida")
+	ErrShippingMethodNotFound = errors.New("forma de entrega não encontrada")
+	ErrInvalidZip             = errors.New("CEP inválido")
+	ErrShippingUnavailable    = errors.New("nenhuma forma de entrega atende este CEP")
+)
+
+// NormalizeZip deixa só os 8 dígitos do CEP ("01310-100" vira "01310100")
+func NormalizeZip(zip string) (string, error) {
+	digits := strings.NewReplacer("-", "", ".", "", " ", "").Replace(zip)
+	if len(digits) != 8 || strings.Trim(digits, "0123456789") != "" {
+		return "", ErrInvalidZip
+	}
+	return digits, nil
+}
+
+// CartWeight soma o peso cobrado (real ou cúbico) de cada unidade.
+// Produto excluído ou sem medidas não pesa.
+func CartWeight(items []models.OrderItem, products []models.Product) int {
+	total := 0
+	for _, item := range items {
+		if i := slices.IndexFunc(products, func(p models.Product) bool { return p.ID == item.ProductID }); i >= 0 {
+			total += products[i].ShippingWeight() * item.Quantity
+		}
+	}
+	return total
+}
+
+// QuoteShipping cota as formas ativas para o CEP (já normalizado) e o peso.
+// As que não atendem ficam de fora; a mais barata vem primeiro.
+func QuoteShipping(methods []models.ShippingMethod, zip string, weightGrams int) []models.ShippingQuote {
+	var quotes []models.ShippingQuote
+	for _, m := range methods {
+		if !m.Active {
+			continue
+		}
+		quote := models.ShippingQuote{
+			MethodID:      m.ID,
+			Name:          m.Name,
+			Kind:          m.Kind,
+			Zip:           zip,
+			WeightGrams:   weightGrams,
+			Days:          m.HandlingDays,
+			PickupAddress: m.PickupAddress,
+		}
+		// Retirada sem tabela vale em qualquer CEP, sem custo
+		if m.Kind != models.ShippingPickup || len(m.Rates) > 0 {
+			rate, ok := shippingRate(m.Rates, zip, weightGrams)
+			if !ok {
+				continue
+			}
+			quote.Cost = rate.Price
+			quote.Days += rate.Days
+		}
+		quotes = append(quotes, quote)
+	}
+	slices.SortStableFunc(quotes, func(a, b models.ShippingQuote) int {
+		return cmp.Or(cmp.Compare(a.Cost, b.Cost), cmp.Compare(a.Days, b.Days))
+	})
+	return quotes
+}
+
+// shippingRate escolhe a linha mais específica que atende: a de menor peso
+// máximo (sem limite fica por último)
+func shippingRate(rates []models.ShippingRate, zip string, weightGrams int) (models.ShippingRate, bool) {
+	var best models.ShippingRate
+	found := false
+	for _, r := range rates {
+		if !r.Covers(zip, weightGrams) {
+			continue
+		}
+		if !found || (r.MaxWeightGrams > 0 && (best.MaxWeightGrams == 0 || r.MaxWeightGrams < best.MaxWeightGrams)) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+// ShippingQuotes cota a entrega dos itens para o CEP. Loja sem nenhuma forma
+// de entrega ativa não cobra frete: devolve nil, sem erro.
+func (s *StoreService) ShippingQuotes(ctx context.Context, items []models.OrderItem, zipStr string) ([]models.ShippingQuote, error) {
+	methods, err := s.Repo.GetShippingMethods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(methods, func(m models.ShippingMethod) bool { return m.Active }) {
+		return nil, nil
+	}
+
+	zip, err := NormalizeZip(zipStr)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+	}
+	products, err := s.Repo.GetProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := QuoteShipping(methods, zip, CartWeight(items, products))
+	if len(quotes) == 0 {
+		return nil, ErrShippingUnavailable
+	}
+	return quotes, nil
+}
+
+// ChooseShipping cota de novo e devolve a forma escolhida (sem escolha, a
+// mais barata). nil = loja sem frete configurado.
+func (s *StoreService) ChooseShipping(ctx context.Context, items []models.OrderItem, zip, methodIDStr string) (*models.ShippingQuote, error) {
+	quotes, err := s.ShippingQuotes(ctx, items, zip)
+	if err != nil || quotes == nil {
+		return nil, err
+	}
+	if methodIDStr == "" {
+		return &quotes[0], nil
+	}
+	for _, q := range quotes {
+		if q.MethodID.Hex() == methodIDStr {
+			return &q, nil
+		}
+	}
+	return nil, fmt.Errorf("%w para este CEP", ErrShippingMethodNotFound)
+}
+
+// --- ADMIN ---
+
+func (s *StoreService) GetShippingMethods(ctx context.Context) ([]models.ShippingMethod, error) {
+	return s.Repo.GetShippingMethods(ctx)
+}
+
+// CreateShippingMethod valida e grava uma forma de entrega com a tabela de frete
+func (s *StoreService) CreateShippingMethod(ctx context.Context, m models.ShippingMethod) error {
+	m.Name = strings.TrimSpace(m.Name)
+	m.PickupAddress = strings.TrimSpace(m.PickupAddress)
+	if err := validateShippingMethod(m); err != nil {
+		return err
+	}
+
+	m.ID = primitive.NewObjectID()
+	m.CreatedAt = time.Now()
+	return s.Repo.CreateShippingMethod(ctx, m)
+}
+
+func validateShippingMethod(m models.ShippingMethod) error {
+	invalid := func(reason string) error { return fmt.Errorf("%w: %s", ErrInvalidShippingMethod, reason) }
+
+	if m.Name == "" {
+		return invalid("informe o nome")
+	}
+	switch m.Kind {
+	case models.ShippingStandard, models.ShippingExpress:
+		if len(m.Rates) == 0 {
+			return invalid("informe a tabela de frete")
+		}
+	case models.ShippingPickup:
+		if m.PickupAddress == "" {
+			return invalid("informe o endereço de retirada")
+		}
+	default:
+		return invalid("tipo desconhecido")
+	}
+	if m.HandlingDays < 0 {
+		return invalid("prazo negativo")
+	}
+	for _, r := range m.Rates {
+		from, errFrom := NormalizeZip(r.ZipFrom)
+		to, errTo := NormalizeZip(r.ZipTo)
+		switch {
+		case errFrom != nil || errTo != nil || from != r.ZipFrom || to != r.ZipTo:
+			return invalid("CEP da tabela inválido")
+		case from > to:
+			return invalid(fmt.Sprintf("faixa %s a %s invertida", models.FormatZip(from), models.FormatZip(to)))
+		case r.MaxWeightGrams < 0 || r.Price < 0 || r.Days < 0:
+			return invalid("valores negativos na tabela")
+		}
+	}
+	return nil
+}
+
+func (s *StoreService) SetShippingMethodActive(ctx context.Context, idStr string, active bool) error {
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return ErrShippingMethodNotFound
+	}
+	return s.Repo.SetShippingMethodActive(ctx, id, active)
+}
+
+func (s *StoreService) DeleteShippingMethod(ctx context.Context, idStr string) error {
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return ErrShippingMethodNotFound
+	}
+	return s.Repo.DeleteShippingMethod(ctx, id)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestQuoteShipping(t *testing.T) {
+	// Capital de SP: até 1 kg R$ 10, até 5 kg R$ 20; nada acima de 5 kg
+	standard := models.ShippingMethod{
+		ID: primitive.NewObjectID(), Name: "Normal", Kind: models.ShippingStandard, Active: true, HandlingDays: 1,
+		Rates: []models.ShippingRate{
+			{ZipFrom: "01000000", ZipTo: "05999999", MaxWeightGrams: 5000, Price: 2000, Days: 3},
+			{ZipFrom: "01000000", ZipTo: "05999999", MaxWeightGrams: 1000, Price: 1000, Days: 2},
+		},
+	}
+	// Brasil todo, sem limite de peso
+	express := models.ShippingMethod{
+		ID: primitive.NewObjectID(), Name: "Expressa", Kind: models.ShippingExpress, Active: true,
+		Rates: []models.ShippingRate{{ZipFrom: "01000000", ZipTo: "99999999", Price: 4000, Days: 1}},
+	}
+	inactive := models.ShippingMethod{
+		ID: primitive.NewObjectID(), Name: "Desligada", Kind: models.ShippingStandard, Active: false,
+		Rates: []models.ShippingRate{{ZipFrom: "00000000", ZipTo: "99999999", Price: 1, Days: 1}},
+	}
+	pickup := models.ShippingMethod{
+		ID: primitive.NewObjectID(), Name: "Retirada", Kind: models.ShippingPickup, Active: true, PickupAddress: "Rua A, 1",
+	}
+	methods := []models.ShippingMethod{standard, express, inactive, pickup}
+
+	type quote struct {
+		name string
+		cost int64
+		days int
+	}
+	cases := []struct {
+		name   string
+		zip    string
+		weight int
+		want   []quote
+	}{
+		{"início da faixa", "01000000", 500, []quote{{"Retirada", 0, 0}, {"Normal", 1000, 3}, {"Expressa", 4000, 1}}},
+		{"fim da faixa", "05999999", 500, []quote{{"Retirada", 0, 0}, {"Normal", 1000, 3}, {"Expressa", 4000, 1}}},
+		{"logo depois da faixa", "06000000", 500, []quote{{"Retirada", 0, 0}, {"Expressa", 4000, 1}}},
+		{"logo antes da faixa", "00999999", 500, []quote{{"Retirada", 0, 0}}},
+		{"peso no limite da primeira faixa", "01310100", 1000, []quote{{"Retirada", 0, 0}, {"Normal", 1000, 3}, {"Expressa", 4000, 1}}},
+		{"peso na segunda faixa", "01310100", 1001, []quote{{"Retirada", 0, 0}, {"Normal", 2000, 4}, {"Expressa", 4000, 1}}},
+		{"peso acima da última faixa", "01310100", 5001, []quote{{"Retirada", 0, 0}, {"Expressa", 4000, 1}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			quotes := QuoteShipping(methods, tc.zip, tc.weight)
+			var got []quote
+			for _, q := range quotes {
+				got = append(got, quote{q.Name, q.Cost, q.Days})
+				if q.MethodID == inactive.ID {
+					t.Errorf("forma desligada foi cotada")
+				}
+				if q.Zip != tc.zip || q.WeightGrams != tc.weight {
+					t.Errorf("cotação sem CEP/peso: %+v", q)
+				}
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("cotações = %v, quer %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("cotação %d = %v, quer %v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+
+	if quotes := QuoteShipping([]models.ShippingMethod{inactive}, "01310100", 100); len(quotes) != 0 {
+		t.Errorf("só forma desligada deveria dar nenhuma cotação: %+v", quotes)
+	}
+}
+
+func TestNormalizeZip(t *testing.T) {
+	valid := map[string]string{"01310-100": "01310100", "01310100": "01310100", " 01.310-100 ": "01310100"}
+	for in, want := range valid {
+		if got, err := NormalizeZip(in); err != nil || got != want {
+			t.Errorf("NormalizeZip(%q) = %q, %v", in, got, err)
+		}
+	}
+	for _, in := range []string{"", "1310-100", "013101000", "0131O-100"} {
+		if _, err := NormalizeZip(in); err != ErrInvalidZip {
+			t.Errorf("NormalizeZip(%q) deveria falhar, veio %v", in, err)
+		}
+	}
+}
+
+func TestCartWeight(t *testing.T) {
+	boxed := models.Product{ID: primitive.NewObjectID(), Dimensions: models.Dimensions{WeightGrams: 300, LengthCm: 30, WidthCm: 20, HeightCm: 10}}
+	heavy := models.Product{ID: primitive.NewObjectID(), Dimensions: models.Dimensions{WeightGrams: 2000, LengthCm: 10, WidthCm: 10, HeightCm: 10}}
+	items := []models.OrderItem{
+		{ProductID: boxed.ID, Quantity: 2},                // cúbico 1000 g por unidade
+		{ProductID: heavy.ID, Quantity: 1},                // real 2000 g
+		{ProductID: primitive.NewObjectID(), Quantity: 5}, // excluído: não pesa
+	}
+	if got := CartWeight(items, []models.Product{boxed, heavy}); got != 4000 {
+		t.Errorf("CartWeight = %d, quer 4000", got)
+	}
+}
@@ -1,7 +1,11 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log"
+	"slices"
 	"time"
 
 	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
@@ -9,36 +13,74 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Erros de checkout que o cliente pode corrigir (viram 4xx na API)
+var (
+	ErrUnsupportedPaymentMethod = errors.New("forma de pagamento não suportada")
+	ErrMissingCard              = errors.New("dados do cartão ausentes")
+	ErrNoItemsSelected          = errors.New("nenhum item selecionado")
+	ErrOrderNotFound            = errors.New("pedido não encontrado")
+	ErrProductNotFound          = errors.New("produto não encontrado")
+	ErrOrderNotPending          = errors.New("pedido não está aguardando pagamento")
+	ErrVariantRequired          = errors.New("escolha uma variante do produto")
+	ErrVariantNotFound          = errors.New("variante não encontrada")
+	ErrInvalidVariant           = errors.New("variante inválida")
+)
+
+// Tempo padrão que um pedido PIX segura o estoque antes de expirar
+const DefaultReservationWindow = 30 * time.Minute
+
 type StoreService struct {
-	Repo    *repository.StoreRepository
-	Payment *PaymentService
+	Repo    repository.StoreRepository
+	Payment PaymentGateway
+
+	ReservationWindow time.Duration
+	GuestCartTTL      time.Duration // <--- carrinho de visitante parado expira
 }
 
-func NewStoreService(repo *repository.StoreRepository, payment *PaymentService) *StoreService {
+func NewStoreService(repo repository.StoreRepository, payment PaymentGateway) *StoreService {
 	return &StoreService{
-		Repo:    repo,
-		Payment: payment,
+		Repo:              repo,
+		Payment:           payment,
+		ReservationWindow: DefaultReservationWindow,
+		GuestCartTTL:      DefaultGuestCartTTL,
 	}
 }
 
-func (s *StoreService) CreateProduct(name, desc, img string, price int64, stock int, sizes []string) error {
+func (s *StoreService) CreateProduct(ctx context.Context, name, desc, img string, price int64, stock int, sizes []string, variants []models.Variant, categories []string, dims models.Dimensions) error {
+	if err := validateVariants(variants); err != nil {
+		return err
+	}
+	categoryIDs, err := s.productCategories(ctx, categories)
+	if err != nil {
+		return err
+	}
 	product := models.Product{
 		ID:          primitive.NewObjectID(),
 		Name:        name,
 		Description: desc,
 		ImageURL:    img,
 		Price:       price,
-		Stock:       stock,
+		Stock:       variantsStock(variants, stock),
 		Sizes:       sizes,
+		Variants:    variants,
+		CategoryIDs: categoryIDs,
+		Dimensions:  dims,
 		CreatedAt:   time.Now(),
 	}
 	// Assumindo que seu Repo tem CreateProduct (se não, adicione no store_repository)
-	return s.Repo.CreateProduct(product)
+	return duplicateSKU(s.Repo.CreateProduct(ctx, product))
 }
 
-func (s *StoreService) EditProduct(ID primitive.ObjectID, name, desc, img string, price int64, stock int, sizes []string) error {
+func (s *StoreService) EditProduct(ctx context.Context, ID primitive.ObjectID, name, desc, img string, price int64, stock int, sizes []string, variants []models.Variant, categories []string, dims models.Dimensions) error {
+	if err := validateVariants(variants); err != nil {
+		return err
+	}
+	categoryIDs, err := s.productCategories(ctx, categories)
+	if err != nil {
+		return err
+	}
 
-	existingProduct, err := s.Repo.GetProductByID(ID)
+	existingProduct, err := s.Repo.GetProductByID(ctx, ID)
 	if err != nil {
 		return err
 	}
@@ -49,31 +91,87 @@ func (s *StoreService) EditProduct(ID primitive.ObjectID, name, desc, img string
 		Description: desc,
 		ImageURL:    img,
 		Price:       price,
-		Stock:       stock,
+		Stock:       variantsStock(variants, stock),
 		Sizes:       sizes,
+		Variants:    variants,
+		CategoryIDs: categoryIDs,
+		Dimensions:  dims,
 		CreatedAt:   existingProduct.CreatedAt, // Mantém a data original
 		UpdatedAt:   time.Now(),                // Atualiza a data de modificação
 	}
-	return s.Repo.EditProduct(ID, product)
+	return duplicateSKU(s.Repo.EditProduct(ctx, ID, product))
+}
+
+// duplicateSKU mostra o SKU repetido como erro de validação da variante
+func duplicateSKU(err error) error {
+	if errors.Is(err, repository.ErrDuplicateSKU) {
+		return fmt.Errorf("%w: %w", ErrInvalidVariant, err)
+	}
+	return err
+}
+
+// validateVariants exige SKU único, ao menos uma opção e valores não negativos
+func validateVariants(variants []models.Variant) error {
+	seen := map[string]bool{}
+	for _, v := range variants {
+		switch {
+		case v.SKU == "":
+			return fmt.Errorf("%w: SKU vazio", ErrInvalidVariant)
+		case seen[v.SKU]:
+			return fmt.Errorf("%w: SKU %s repetido", ErrInvalidVariant, v.SKU)
+		case len(v.Options) == 0:
+			return fmt.Errorf("%w: %s sem opções", ErrInvalidVariant, v.SKU)
+		case v.Stock < 0 || (v.Price != nil && *v.Price < 0):
+			return fmt.Errorf("%w: %s com estoque ou preço negativo", ErrInvalidVariant, v.SKU)
+		}
+		seen[v.SKU] = true
+	}
+	return nil
+}
+
+// variantsStock: com variantes, o estoque do produto é a soma delas
+func variantsStock(variants []models.Variant, stock int) int {
+	if len(variants) == 0 {
+		return stock
+	}
+	total := 0
+	for _, v := range variants {
+		total += v.Stock
+	}
+	return total
 }
 
-func (s *StoreService) GetShowcase() ([]models.Product, error) {
-	return s.Repo.GetAllProducts()
+func (s *StoreService) GetShowcase(ctx context.Context) ([]models.Product, error) {
+	return s.Repo.GetAllProducts(ctx)
 }
 
-func (s *StoreService) GetProductDetails(idStr string) (*models.Product, error) {
+func (s *StoreService) GetProductDetails(ctx context.Context, idStr string) (*models.Product, error) {
 	objID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
-		return nil, err
+		return nil, ErrProductNotFound
 	}
-	return s.Repo.GetProductByID(objID)
+	product, err := s.Repo.GetProductByID(ctx, objID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrProductNotFound
+	}
+	return product, err
 }
 
-func (s *StoreService) ProcessCartPurchase(userIDStr, customerName, customerEmail, customerAddress, paymentMethod, cardNum, cardCVV string, selectedItems []string) (*models.Order, string, string, error) {
+// ProcessCartPurchase finaliza a compra. Para cartão, recebe só o token gerado
+// pelo provedor (card); o número do cartão nunca chega aqui. couponCode é
+// opcional; as promoções automáticas valem sempre.
+func (s *StoreService) ProcessCartPurchase(ctx context.Context, userIDStr, customerName, customerEmail, customerAddress, paymentMethod string, card *CardToken, selectedItems []string, couponCode, zip, shippingMethodID string) (*models.Order, string, string, error) {
+	if paymentMethod != models.PaymentMethodPix && paymentMethod != models.PaymentMethodCard {
+		return nil, "", "", ErrUnsupportedPaymentMethod
+	}
+	if paymentMethod == models.PaymentMethodCard && card == nil {
+		return nil, "", "", ErrMissingCard
+	}
+
 	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 
 	// 1. Buscar Carrinho
-	user, err := s.Repo.GetUserWithCart(userID)
+	user, err := s.Repo.GetUserWithCart(ctx, userID)
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -83,17 +181,21 @@ func (s *StoreService) ProcessCartPurchase(userIDStr, customerName, customerEmai
 	var total int64 = 0
 
 	for _, item := range user.Cart {
-		shouldBuy := false
-		for _, selID := range selectedItems {
-			if item.ProductID.Hex() == selID {
-				shouldBuy = true
-				break
+		if slices.Contains(selectedItems, item.LineKey()) {
+			product, err := s.Repo.GetProductByID(ctx, item.ProductID)
+			if errors.Is(err, repository.ErrNotFound) || (err == nil && !stillSold(product, item)) {
+				return nil, "", "", fmt.Errorf("produto %s: %w", item.ProductName, ErrCartChanged)
 			}
-		}
-		if shouldBuy {
-			product, err := s.Repo.GetProductByID(item.ProductID)
-			if err != nil || product.Stock < item.Quantity {
-				return nil, "", "", errors.New("produto " + item.ProductName + " sem estoque")
+			if err != nil || product.StockFor(item.SKU) < item.Quantity {
+				return nil, "", "", fmt.Errorf("produto %s: %w", item.ProductName, repository.ErrInsufficientStock)
+			}
+			// O preço mudou depois que o cliente viu o carrinho: grava o novo e
+			// devolve para ele conferir, em vez de cobrar um valor que não viu
+			if current := currentLine(product, item); current.Price != item.Price {
+				if err := s.Repo.RepriceCartItem(ctx, userID, current); err != nil {
+					return nil, "", "", err
+				}
+				return nil, "", "", fmt.Errorf("produto %s: %w", item.ProductName, ErrCartChanged)
 			}
 			itemsToBuy = append(itemsToBuy, item)
 			total += item.Price * int64(item.Quantity)
@@ -101,51 +203,114 @@ func (s *StoreService) ProcessCartPurchase(userIDStr, customerName, customerEmai
 	}
 
 	if len(itemsToBuy) == 0 {
-		return nil, "", "", errors.New("nenhum item selecionado")
+		return nil, "", "", ErrNoItemsSelected
 	}
 
-	// 3. PROCESSAR PAGAMENTO
-	status := "PAGO"
-	var pixCode, qrCodeImg string
+	// Frete da forma escolhida, cotado de novo com o peso de agora
+	delivery, err := s.ChooseShipping(ctx, itemsToBuy, zip, shippingMethodID)
+	if err != nil {
+		return nil, "", "", err
+	}
 
-	if paymentMethod == "pix" {
-		status = "AGUARDANDO_PAGAMENTO"
-		// Gera o PIX
-		code, img, err := s.Payment.GeneratePix(total)
+	// Descontos (promoções automáticas e cupom)
+	pricing, err := s.PriceOrder(ctx, userIDStr, itemsToBuy, couponCode, delivery)
+	if err != nil {
+		return nil, "", "", err
+	}
+	total = pricing.Total
+
+	// 3. PROCESSAR PAGAMENTO
+	// O pagamento acontece fora da transação: o WithTransaction pode repetir o
+	// callback e não queremos cobrar duas vezes. Se a gravação falhar depois,
+	// a cobrança é estornada (compensação).
+	orderID := primitive.NewObjectID()
+	status := models.OrderStatusPaid
+	var pixCode, qrCodeImg, paymentID, cardBrand, cardLast4 string
+	var reservedUntil *time.Time
+
+	if total == 0 {
+		// Pedido de graça (ex: cupom de 100%): não há o que cobrar
+	} else if paymentMethod == models.PaymentMethodPix {
+		// O estoque baixado abaixo fica reservado até o PIX ser pago ou expirar
+		status = models.OrderStatusPending
+		until := time.Now().Add(s.ReservationWindow)
+		reservedUntil = &until
+
+		// Cria a cobrança PIX com o valor e o txid deste pedido
+		charge, err := s.Payment.CreatePixCharge(ctx, PixChargeRequest{TxID: orderID.Hex(), Amount: total})
 		if err != nil {
 			return nil, "", "", err
 		}
-		pixCode = code
-		qrCodeImg = img
+		paymentID = charge.ID
+		pixCode = charge.Payload
+		qrCodeImg = "/pix/" + orderID.Hex() + ".png"
 	} else {
-		// Processa Cartão (usa o método renomeado ou antigo)
-		err = s.Payment.ProcessPaymentCard(cardNum, customerName, cardCVV, total)
+		// Processa Cartão (autoriza + captura)
+		paymentID, err = chargeCard(ctx, s.Payment, CardAuthorization{
+			CardToken: card.Token,
+			Amount:    total,
+		})
 		if err != nil {
 			return nil, "", "", err
 		}
+		cardBrand = string(card.Brand)
+		cardLast4 = card.Last4
 	}
 
-	// 4. Baixar Estoque e Remover do Carrinho
-	for _, item := range itemsToBuy {
-		for i := 0; i < item.Quantity; i++ {
-			s.Repo.DecrementStock(item.ProductID)
-		}
-		s.Repo.RemoveItemFromCart(userID, item.ProductID, item.Size)
-	}
-
-	// 5. Gerar Pedido
 	order := models.Order{
-		ID:              primitive.NewObjectID(),
+		ID:              orderID,
+		UserID:          userID,
 		CustomerName:    customerName,
 		CustomerEmail:   customerEmail,
 		CustomerAddress: customerAddress,
 		Status:          status,
+		PaymentMethod:   paymentMethod,
+		PaymentID:       paymentID,
+		PixPayload:      pixCode,
+		CardBrand:       cardBrand,
+		CardLast4:       cardLast4,
+		ReservedUntil:   reservedUntil,
+		Subtotal:        pricing.Subtotal,
+		Discounts:       pricing.Discounts,
+		FreeShipping:    pricing.FreeShipping,
+		Delivery:        delivery,
 		Total:           total,
 		CreatedAt:       time.Now(),
 		Items:           itemsToBuy,
+		StatusHistory: []models.OrderStatusChange{
+			{To: status, ChangedBy: customerEmail, ChangedAt: time.Now()},
+		},
 	}
 
-	if err := s.Repo.CreateOrder(order); err != nil {
+	// 4. Baixar Estoque, Remover do Carrinho e Gerar Pedido (tudo ou nada)
+	err = s.Repo.RunInTransaction(ctx, func(ctx context.Context) error {
+		for _, item := range itemsToBuy {
+			if err := s.Repo.DecrementStock(ctx, item.ProductID, item.SKU, item.Quantity); err != nil {
+				return fmt.Errorf("produto %s: %w", item.ProductName, err)
+			}
+		}
+		if err := s.Repo.RemoveItemsFromCart(ctx, userID, itemsToBuy); err != nil {
+			return err
+		}
+		if err := s.redeemPromotions(ctx, order); err != nil {
+			return err
+		}
+		return s.Repo.CreateOrder(ctx, order)
+	})
+	if err != nil {
+		// A compensação não pode morrer junto com o request
+		switch {
+		case paymentID == "":
+		case paymentMethod == models.PaymentMethodCard:
+			if refundErr := s.Payment.Refund(context.WithoutCancel(ctx), paymentID, total); refundErr != nil {
+				log.Printf("ERRO CRÍTICO: checkout falhou e o estorno de %s também: %v", paymentID, refundErr)
+			}
+		default:
+			// PIX sem pedido: cancela a cobrança para ninguém pagar um pedido que não existe
+			if voidErr := s.Payment.Void(context.WithoutCancel(ctx), paymentID); voidErr != nil {
+				log.Printf("ERRO CRÍTICO: checkout falhou e o cancelamento do PIX %s também: %v", paymentID, voidErr)
+			}
+		}
 		return nil, "", "", err
 	}
 
@@ -153,26 +318,91 @@ func (s *StoreService) ProcessCartPurchase(userIDStr, customerName, customerEmai
 	return &order, pixCode, qrCodeImg, nil
 }
 
-func (s *StoreService) ConfirmPayment(orderIDStr string) error {
+func (s *StoreService) ConfirmPayment(ctx context.Context, orderIDStr string) error {
 	objID, err := primitive.ObjectIDFromHex(orderIDStr)
 	if err != nil {
 		return err
 	}
-	return s.Repo.UpdateOrderStatus(objID, "PAGO")
+	order, err := s.Repo.GetOrderByID(ctx, objID)
+	if err != nil {
+		return err
+	}
+
+	// Só confirma se ainda estiver aguardando (reserva não expirada)
+	err = s.transitionOrder(ctx, order, models.OrderStatusPaid, "pagamento")
+	if errors.Is(err, repository.ErrOrderStatusChanged) {
+		return errors.New("pedido não está mais aguardando pagamento")
+	}
+	return err
+}
+
+// GetPixQRCode devolve o PNG do QR Code de um pedido PIX do próprio cliente
+func (s *StoreService) GetPixQRCode(ctx context.Context, userIDStr, orderIDStr string) ([]byte, error) {
+	userID, _ := primitive.ObjectIDFromHex(userIDStr)
+	objID, err := primitive.ObjectIDFromHex(orderIDStr)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+
+	order, err := s.Repo.GetOrderByID(ctx, objID)
+	if err != nil || order.UserID != userID || order.PixPayload == "" {
+		return nil, ErrOrderNotFound
+	}
+	if order.Status != models.OrderStatusPending {
+		return nil, ErrOrderNotPending
+	}
+
+	return PixQRCode(order.PixPayload)
+}
+
+// GetUserOrder devolve um pedido só se ele pertencer ao usuário
+func (s *StoreService) GetUserOrder(ctx context.Context, userIDStr, orderIDStr string) (*models.Order, error) {
+	userID, _ := primitive.ObjectIDFromHex(userIDStr)
+	objID, err := primitive.ObjectIDFromHex(orderIDStr)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+
+	order, err := s.Repo.GetOrderByID(ctx, objID)
+	if err != nil || order.UserID != userID {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func (s *StoreService) GetAllOrders(ctx context.Context) ([]models.Order, error) {
+	return s.Repo.GetAllOrders(ctx)
 }
 
-func (s *StoreService) AddProductToCart(userIDStr, productIDStr string, quantity int, size string) error {
+// AddProductToCart: em produto com variantes, sku escolhe a variante (e o
+// size recebido é ignorado: vira o rótulo dela)
+func (s *StoreService) AddProductToCart(ctx context.Context, userIDStr, productIDStr string, quantity int, sku, size string) error {
 
 	// 1. Converter IDs
 	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 
+	item, stock, err := s.cartItem(ctx, productIDStr, quantity, sku, size)
+	if err != nil {
+		return err
+	}
+
+	// 4. Salvar no User: soma à linha que já existe, limitado ao estoque
+	return s.Repo.AddItemToCart(ctx, userID, item, stock)
+}
+
+// cartItem monta a linha do carrinho com os dados atuais do produto (e da
+// variante) e devolve o estoque disponível para ela
+func (s *StoreService) cartItem(ctx context.Context, productIDStr string, quantity int, sku, size string) (models.OrderItem, int, error) {
 	// Convert product ID string to ObjectID
 	productID, _ := primitive.ObjectIDFromHex(productIDStr)
 
 	// 2. Buscar dados atuais do Produto (Preço, Nome, Imagem)
-	product, err := s.Repo.GetProductByID(productID)
+	product, err := s.Repo.GetProductByID(ctx, productID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.OrderItem{}, 0, ErrProductNotFound
+	}
 	if err != nil {
-		return err
+		return models.OrderItem{}, 0, err
 	}
 
 	if quantity <= 0 {
@@ -189,18 +419,37 @@ func (s *StoreService) AddProductToCart(userIDStr, productIDStr string, quantity
 		ImageURL:    product.ImageURL,
 	}
 
-	// 4. Salvar no User
-	return s.Repo.AddItemToCart(userID, item)
+	if product.HasVariants() {
+		if sku == "" {
+			return models.OrderItem{}, 0, ErrVariantRequired
+		}
+		variant, ok := product.Variant(sku)
+		if !ok {
+			return models.OrderItem{}, 0, ErrVariantNotFound
+		}
+		item.SKU = variant.SKU
+		item.Size = variant.Label()
+		item.Price = product.PriceFor(sku)
+		if variant.ImageURL != "" {
+			item.ImageURL = variant.ImageURL
+		}
+	}
+
+	stock := product.StockFor(item.SKU)
+	if stock <= 0 {
+		return models.OrderItem{}, 0, repository.ErrInsufficientStock
+	}
+	return item, stock, nil
 }
 
-func (s *StoreService) RemoveProductFromCart(userIDStr, productIDStr, size string) error {
+func (s *StoreService) RemoveProductFromCart(ctx context.Context, userIDStr, productIDStr, sku, size string) error {
 	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 	productID, _ := primitive.ObjectIDFromHex(productIDStr)
 
-	return s.Repo.RemoveItemFromCart(userID, productID, size)
+	return s.Repo.RemoveItemFromCart(ctx, userID, productID, sku, size)
 }
 
-func (s *StoreService) UpdateCartItemQuantity(userIDStr, productIDStr string, quantity int, size string) error {
+func (s *StoreService) UpdateCartItemQuantity(ctx context.Context, userIDStr, productIDStr string, quantity int, sku, size string) error {
 	if quantity <= 0 {
 		return errors.New("quantidade deve ser maior que zero")
 	}
@@ -208,13 +457,13 @@ func (s *StoreService) UpdateCartItemQuantity(userIDStr, productIDStr string, qu
 	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 	productID, _ := primitive.ObjectIDFromHex(productIDStr)
 
-	return s.Repo.UpdateCartItemQuantity(userID, productID, quantity, size)
+	return s.Repo.UpdateCartItemQuantity(ctx, userID, productID, quantity, sku, size)
 }
 
-func (s *StoreService) GetUserCart(userIDStr string) (*models.User, float64, error) {
+func (s *StoreService) GetUserCart(ctx context.Context, userIDStr string) (*models.User, float64, error) {
 	userID, _ := primitive.ObjectIDFromHex(userIDStr)
 
-	user, err := s.Repo.GetUserWithCart(userID)
+	user, err := s.Repo.GetUserWithCart(ctx, userID)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -229,36 +478,10 @@ func (s *StoreService) GetUserCart(userIDStr string) (*models.User, float64, err
 	return user, float64(total) / 100.0, nil
 }
 
-func (s *StoreService) EnrichCartWithStockInfo(cart []models.OrderItem) ([]models.OrderItemWithStock, error) {
-	var enrichedCart []models.OrderItemWithStock
-
-	for _, item := range cart {
-		product, err := s.Repo.GetProductByID(item.ProductID)
-		if err != nil {
-			return nil, err
-		}
-
-		enrichedItem := models.OrderItemWithStock{
-			ProductID:    item.ProductID,
-			ProductName:  item.ProductName,
-			Price:        item.Price,
-			Quantity:     item.Quantity,
-			Size:         item.Size,
-			ImageURL:     item.ImageURL,
-			Stock:        product.Stock,
-			IsOutOfStock: product.Stock == 0,
-		}
-
-		enrichedCart = append(enrichedCart, enrichedItem)
-	}
-
-	return enrichedCart, nil
-}
-
-func (s *StoreService) DeleteProduct(idStr string) error {
+func (s *StoreService) DeleteProduct(ctx context.Context, idStr string) error {
 	objID, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
 		return err
 	}
-	return s.Repo.DeleteProduct(objID)
+	return s.Repo.DeleteProduct(ctx, objID)
 }
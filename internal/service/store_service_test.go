@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MarcosAndradeV/go-ecommerce/internal/models"
+	"github.com/MarcosAndradeV/go-ecommerce/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// failingOrders faz a transação do checkout falhar depois da cobrança
+type failingOrders struct {
+	repository.StoreRepository
+}
+
+var errOrderConflict = errors.New("conflito ao gravar o pedido")
+
+func (failingOrders) CreateOrder(ctx context.Context, order models.Order) error {
+	return errOrderConflict
+}
+
+// Checkout que falha na transação não pode deixar cobrança em aberto:
+// cartão é estornado e PIX é cancelado
+func TestProcessCartPurchaseCompensatesPayment(t *testing.T) {
+	cases := []struct {
+		method string
+		card   string
+		want   PaymentStatus
+	}{
+		{models.PaymentMethodPix, "", PaymentStatusVoided},
+		{models.PaymentMethodCard, "4111111111111111", PaymentStatusRefunded},
+	}
+	for _, tc := range cases {
+		t.Run(tc.method, func(t *testing.T) {
+			ctx := context.Background()
+			mem := repository.NewMemoryStore()
+			gateway := NewFakePaymentGateway(PixConfig{Key: "loja@exemplo.com", MerchantName: "Loja", MerchantCity: "Sao Paulo"})
+			s := NewStoreService(failingOrders{mem}, gateway)
+
+			user := models.User{ID: primitive.NewObjectID(), Name: "Cliente", Email: "cliente@exemplo.com"}
+			product := models.Product{ID: primitive.NewObjectID(), Name: "Caneca", Price: 3000, Stock: 5}
+			item := models.OrderItem{ProductID: product.ID, ProductName: product.Name, Price: 3000, Quantity: 1}
+			if err := mem.CreateUser(ctx, user); err != nil {
+				t.Fatal(err)
+			}
+			if err := mem.CreateProduct(ctx, product); err != nil {
+				t.Fatal(err)
+			}
+			if err := mem.AddItemToCart(ctx, user.ID, item, 5); err != nil {
+				t.Fatal(err)
+			}
+
+			var card *CardToken
+			if tc.card != "" {
+				var err error
+				card, err = gateway.TokenizeCard(ctx, CardDetails{Number: tc.card, HolderName: "Cliente", CVV: "123", ExpMonth: 12, ExpYear: 2099})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, _, _, err := s.ProcessCartPurchase(ctx, user.ID.Hex(), "Cliente", "cliente@exemplo.com", "Rua A, 1",
+				tc.method, card, []string{item.LineKey()}, "", "", "")
+			if !errors.Is(err, errOrderConflict) {
+				t.Fatalf("esperava o erro da transação, veio %v", err)
+			}
+
+			if len(gateway.payments) != 1 {
+				t.Fatalf("esperava uma cobrança, há %d", len(gateway.payments))
+			}
+			for id, p := range gateway.payments {
+				if p.status != tc.want {
+					t.Errorf("cobrança %s ficou %s, quer %s", id, p.status, tc.want)
+				}
+			}
+		})
+	}
+}